@@ -0,0 +1,201 @@
+// Package apikey generates and verifies static api key of partner integration. Only hmac hash of the key is stored.
+
+package apikey
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kitabisa/perkakas/v2/signature"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	base62       = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	randomLength = 30
+	// checksumLength is length of base62 encoded crc32 checksum, 62^6 > 2^32
+	checksumLength = 6
+)
+
+var (
+	// ErrInvalidKey is returned when api key is malformed, its checksum is wrong or it is unknown
+	ErrInvalidKey = errors.New("apikey: invalid api key")
+	// ErrKeyExpired is returned when api key has expired
+	ErrKeyExpired = errors.New("apikey: api key is expired")
+	// ErrKeyNotFound is returned by store when api key hash is not stored
+	ErrKeyNotFound = errors.New("apikey: api key not found")
+)
+
+// Key is stored api key. Only hmac hash of the api key is stored.
+type Key struct {
+	ID         string    `json:"id"` // public id, safe to be logged and shown to the partner
+	Hash       string    `json:"hash"`
+	Partner    string    `json:"partner"` // partner identity, e.g. client name
+	Name       string    `json:"name"`    // description, e.g. "production server"
+	Scopes     []string  `json:"scopes,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at"` // zero never expires
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+// IsExpired checks whether the key has expired at the time
+func (k Key) IsExpired(at time.Time) bool {
+	return !k.ExpiresAt.IsZero() && !at.Before(k.ExpiresAt)
+}
+
+// Option is api key manager option
+type Option struct {
+	// Store stores api key, e.g. NewMemoryStore, NewRedisStore or NewSQLStore
+	Store Store
+	// Secret is hmac secret of the api key hash. Changing it invalidates all api keys.
+	Secret string
+	// Prefix is api key prefix, so leaked key can be recognized by secret scanner, e.g. "kb_live". Default "kb".
+	Prefix string
+	// LastUsedInterval is minimum interval between last used updates, so not every request writes to the store.
+	// Default 1 minute. Negative disables last used tracking.
+	LastUsedInterval time.Duration
+}
+
+// Manager creates and authenticates api key
+type Manager struct {
+	option Option
+}
+
+// NewManager creates api key manager
+func NewManager(option Option) *Manager {
+	if option.Prefix == "" {
+		option.Prefix = "kb"
+	}
+
+	if option.LastUsedInterval == 0 {
+		option.LastUsedInterval = time.Minute
+	}
+
+	return &Manager{
+		option: option,
+	}
+}
+
+// Create creates api key of the partner. The plain api key is returned only once, give it to the partner.
+// Zero ttl never expires.
+func (m *Manager) Create(partner, name string, scopes []string, ttl time.Duration) (string, *Key, error) {
+	random, err := randomString(randomLength)
+	if err != nil {
+		return "", nil, err
+	}
+
+	body := m.option.Prefix + "_" + random
+	plain := body + checksum(body)
+
+	key := Key{
+		ID:        uuid.New().String(),
+		Hash:      m.Hash(plain),
+		Partner:   partner,
+		Name:      name,
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+	}
+
+	if ttl > 0 {
+		key.ExpiresAt = key.CreatedAt.Add(ttl)
+	}
+
+	if err = m.option.Store.Save(key); err != nil {
+		return "", nil, err
+	}
+
+	return plain, &key, nil
+}
+
+// Authenticate gets valid api key. Malformed key is rejected without store lookup.
+func (m *Manager) Authenticate(plain string) (*Key, error) {
+	if !m.ValidFormat(plain) {
+		return nil, ErrInvalidKey
+	}
+
+	hash := m.Hash(plain)
+	key, err := m.option.Store.Get(hash)
+	if errors.Is(err, ErrKeyNotFound) {
+		return nil, ErrInvalidKey
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if key.IsExpired(now) {
+		return nil, ErrKeyExpired
+	}
+
+	if m.option.LastUsedInterval > 0 && now.Sub(key.LastUsedAt) >= m.option.LastUsedInterval {
+		// failing to track last used must not reject the request
+		if err = m.option.Store.Touch(hash, now); err != nil {
+			log.Error().Err(err).Str("api_key_id", key.ID).Msg("failed to update api key last used")
+		} else {
+			key.LastUsedAt = now
+		}
+	}
+
+	return key, nil
+}
+
+// Revoke deletes api key by its hash, see Key.Hash
+func (m *Manager) Revoke(hash string) error {
+	return m.option.Store.Delete(hash)
+}
+
+// Hash is hmac hash of the plain api key, which is stored
+func (m *Manager) Hash(plain string) string {
+	return signature.GenerateHmac(plain, m.option.Secret)
+}
+
+// ValidFormat checks prefix, length and checksum of the plain api key, e.g. to catch typo
+func (m *Manager) ValidFormat(plain string) bool {
+	prefix := m.option.Prefix + "_"
+	if len(plain) != len(prefix)+randomLength+checksumLength || !strings.HasPrefix(plain, prefix) {
+		return false
+	}
+
+	body := plain[:len(plain)-checksumLength]
+	return checksum(body) == plain[len(body):]
+}
+
+// randomString creates base62 random string without modulo bias
+func randomString(length int) (string, error) {
+	result := make([]byte, 0, length)
+	buf := make([]byte, length)
+
+	for len(result) < length {
+		if _, err := rand.Read(buf); err != nil {
+			return "", fmt.Errorf("apikey: failed to read random: %w", err)
+		}
+
+		for _, b := range buf {
+			// 248 is the largest multiple of 62 below 256
+			if b < 248 && len(result) < length {
+				result = append(result, base62[b%62])
+			}
+		}
+	}
+
+	return string(result), nil
+}
+
+// checksum is fixed length base62 encoded crc32 of the data
+func checksum(data string) string {
+	sum := crc32.ChecksumIEEE([]byte(data))
+
+	result := make([]byte, checksumLength)
+	for i := checksumLength - 1; i >= 0; i-- {
+		result[i] = base62[sum%62]
+		sum /= 62
+	}
+
+	return string(result)
+}
@@ -0,0 +1,106 @@
+package apikey
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateAndAuthenticate(t *testing.T) {
+	store := NewMemoryStore()
+	m := NewManager(Option{Store: store, Secret: "s3cret", Prefix: "kb_live"})
+
+	plain, key, err := m.Create("partner-a", "production", []string{"donation:read"}, 0)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(plain, "kb_live_"))
+	assert.Len(t, plain, len("kb_live_")+randomLength+checksumLength)
+	assert.True(t, key.ExpiresAt.IsZero())
+
+	// only the hash is stored
+	stored, err := store.Get(m.Hash(plain))
+	require.NoError(t, err)
+	assert.NotContains(t, stored.Hash, plain)
+
+	authenticated, err := m.Authenticate(plain)
+	require.NoError(t, err)
+	assert.Equal(t, "partner-a", authenticated.Partner)
+	assert.Equal(t, []string{"donation:read"}, authenticated.Scopes)
+	assert.False(t, authenticated.LastUsedAt.IsZero())
+
+	// other secret can't authenticate the key
+	other := NewManager(Option{Store: store, Secret: "other", Prefix: "kb_live"})
+	_, err = other.Authenticate(plain)
+	assert.Equal(t, ErrInvalidKey, err)
+
+	require.NoError(t, m.Revoke(key.Hash))
+	_, err = m.Authenticate(plain)
+	assert.Equal(t, ErrInvalidKey, err)
+}
+
+func TestValidFormat(t *testing.T) {
+	m := NewManager(Option{Store: NewMemoryStore(), Secret: "s3cret"})
+
+	plain, _, err := m.Create("partner-a", "", nil, 0)
+	require.NoError(t, err)
+	assert.True(t, m.ValidFormat(plain))
+
+	// typo breaks the checksum
+	typo := []byte(plain)
+	if typo[5] == 'a' {
+		typo[5] = 'b'
+	} else {
+		typo[5] = 'a'
+	}
+	assert.False(t, m.ValidFormat(string(typo)))
+	assert.False(t, m.ValidFormat("xx"+plain[2:]))
+	assert.False(t, m.ValidFormat(plain[:len(plain)-1]))
+
+	_, err = m.Authenticate(string(typo))
+	assert.Equal(t, ErrInvalidKey, err)
+}
+
+func TestAuthenticateExpired(t *testing.T) {
+	m := NewManager(Option{Store: NewMemoryStore(), Secret: "s3cret"})
+
+	plain, _, err := m.Create("partner-a", "", nil, 10*time.Millisecond)
+	require.NoError(t, err)
+
+	_, err = m.Authenticate(plain)
+	require.NoError(t, err)
+
+	time.Sleep(20 * time.Millisecond)
+	_, err = m.Authenticate(plain)
+	assert.Equal(t, ErrKeyExpired, err)
+}
+
+func TestLastUsedInterval(t *testing.T) {
+	store := &touchCountingStore{MemoryStore: NewMemoryStore()}
+	m := NewManager(Option{Store: store, Secret: "s3cret"})
+
+	plain, _, err := m.Create("partner-a", "", nil, 0)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err = m.Authenticate(plain)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, store.touched)
+
+	disabled := NewManager(Option{Store: store, Secret: "s3cret", LastUsedInterval: -1})
+	_, err = disabled.Authenticate(plain)
+	require.NoError(t, err)
+	assert.Equal(t, 1, store.touched)
+}
+
+type touchCountingStore struct {
+	*MemoryStore
+	touched int
+}
+
+func (s *touchCountingStore) Touch(hash string, usedAt time.Time) error {
+	s.touched++
+	return s.MemoryStore.Touch(hash, usedAt)
+}
@@ -0,0 +1,118 @@
+package apikey
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QuestionPlaceholder is bind parameter placeholder of MySQL and SQLite
+func QuestionPlaceholder(int) string {
+	return "?"
+}
+
+// DollarPlaceholder is bind parameter placeholder of PostgreSQL
+func DollarPlaceholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+// SQLStoreOption is sql store option
+type SQLStoreOption struct {
+	// Table is api key table name. Default "api_keys".
+	Table string
+	// Placeholder creates nth bind parameter placeholder, starting from 1. Default QuestionPlaceholder.
+	Placeholder func(n int) string
+}
+
+// SQLStore stores api key in sql table. Scopes are stored space separated, see README for the table schema.
+type SQLStore struct {
+	db     *sql.DB
+	option SQLStoreOption
+}
+
+var _ Store = (*SQLStore)(nil)
+
+// NewSQLStore creates sql store
+func NewSQLStore(db *sql.DB, option SQLStoreOption) *SQLStore {
+	if option.Table == "" {
+		option.Table = "api_keys"
+	}
+
+	if option.Placeholder == nil {
+		option.Placeholder = QuestionPlaceholder
+	}
+
+	return &SQLStore{
+		db:     db,
+		option: option,
+	}
+}
+
+func (s *SQLStore) Save(key Key) error {
+	query := fmt.Sprintf(
+		"INSERT INTO %s (hash, id, partner, name, scopes, created_at, expires_at, last_used_at) VALUES (%s)",
+		s.option.Table, s.placeholders(8),
+	)
+
+	_, err := s.db.Exec(query, key.Hash, key.ID, key.Partner, key.Name, strings.Join(key.Scopes, " "), key.CreatedAt,
+		nullTime(key.ExpiresAt), nullTime(key.LastUsedAt))
+	return err
+}
+
+func (s *SQLStore) Get(hash string) (*Key, error) {
+	query := fmt.Sprintf(
+		"SELECT id, partner, name, scopes, created_at, expires_at, last_used_at FROM %s WHERE hash = %s",
+		s.option.Table, s.option.Placeholder(1),
+	)
+
+	var (
+		key                 = Key{Hash: hash}
+		scopes              string
+		expiresAt, lastUsed sql.NullTime
+	)
+
+	err := s.db.QueryRow(query, hash).Scan(&key.ID, &key.Partner, &key.Name, &scopes, &key.CreatedAt, &expiresAt, &lastUsed)
+	if err == sql.ErrNoRows {
+		return nil, ErrKeyNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	key.Scopes = strings.Fields(scopes)
+	key.ExpiresAt = expiresAt.Time
+	key.LastUsedAt = lastUsed.Time
+
+	return &key, nil
+}
+
+func (s *SQLStore) Delete(hash string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE hash = %s", s.option.Table, s.option.Placeholder(1))
+
+	_, err := s.db.Exec(query, hash)
+	return err
+}
+
+func (s *SQLStore) Touch(hash string, usedAt time.Time) error {
+	query := fmt.Sprintf("UPDATE %s SET last_used_at = %s WHERE hash = %s",
+		s.option.Table, s.option.Placeholder(1), s.option.Placeholder(2))
+
+	_, err := s.db.Exec(query, usedAt, hash)
+	return err
+}
+
+func (s *SQLStore) placeholders(count int) string {
+	p := make([]string, count)
+	for i := range p {
+		p[i] = s.option.Placeholder(i + 1)
+	}
+
+	return strings.Join(p, ", ")
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
@@ -0,0 +1,165 @@
+package apikey
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// Store stores api key by its hash
+type Store interface {
+	// Save saves api key
+	Save(key Key) error
+	// Get gets api key by hash. Unknown key returns ErrKeyNotFound.
+	Get(hash string) (*Key, error)
+	// Delete deletes api key by hash
+	Delete(hash string) error
+	// Touch updates last used time of the api key
+	Touch(hash string, usedAt time.Time) error
+}
+
+// MemoryStore is in-memory store, for single instance service and test
+type MemoryStore struct {
+	mu   sync.RWMutex
+	keys map[string]Key
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore creates in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		keys: make(map[string]Key),
+	}
+}
+
+func (s *MemoryStore) Save(key Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[key.Hash] = key
+	return nil
+}
+
+func (s *MemoryStore) Get(hash string) (*Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, ok := s.keys[hash]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+
+	return &key, nil
+}
+
+func (s *MemoryStore) Delete(hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, hash)
+	return nil
+}
+
+func (s *MemoryStore) Touch(hash string, usedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[hash]
+	if !ok {
+		return ErrKeyNotFound
+	}
+
+	key.LastUsedAt = usedAt
+	s.keys[hash] = key
+	return nil
+}
+
+// RedisStore stores api key in redis until it expires. Last used time is stored in separate key, so touching
+// never rewrites the api key.
+type RedisStore struct {
+	client redis.Cmdable
+	prefix string
+}
+
+var _ Store = (*RedisStore)(nil)
+
+// NewRedisStore creates redis store, prefix is key prefix e.g. "partner-service:apikey:"
+func NewRedisStore(client redis.Cmdable, prefix string) *RedisStore {
+	return &RedisStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+func (s *RedisStore) Save(key Key) error {
+	b, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+
+	return s.client.Set(s.keyKey(key.Hash), b, ttl(key)).Err()
+}
+
+func (s *RedisStore) Get(hash string) (*Key, error) {
+	values, err := s.client.MGet(s.keyKey(hash), s.lastUsedKey(hash)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok := values[0].(string)
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+
+	var key Key
+	if err = json.Unmarshal([]byte(data), &key); err != nil {
+		return nil, err
+	}
+
+	if lastUsed, ok := values[1].(string); ok {
+		if unix, err := strconv.ParseInt(lastUsed, 10, 64); err == nil {
+			key.LastUsedAt = time.Unix(unix, 0)
+		}
+	}
+
+	return &key, nil
+}
+
+func (s *RedisStore) Delete(hash string) error {
+	return s.client.Del(s.keyKey(hash), s.lastUsedKey(hash)).Err()
+}
+
+func (s *RedisStore) Touch(hash string, usedAt time.Time) error {
+	key, err := s.Get(hash)
+	if err != nil {
+		return err
+	}
+
+	return s.client.Set(s.lastUsedKey(hash), usedAt.Unix(), ttl(*key)).Err()
+}
+
+func (s *RedisStore) keyKey(hash string) string {
+	return s.prefix + "key:" + hash
+}
+
+func (s *RedisStore) lastUsedKey(hash string) string {
+	return s.prefix + "last_used:" + hash
+}
+
+// ttl is remaining lifetime of the key, zero means no expiration
+func ttl(key Key) time.Duration {
+	if key.ExpiresAt.IsZero() {
+		return 0
+	}
+
+	if remaining := time.Until(key.ExpiresAt); remaining > 0 {
+		return remaining
+	}
+
+	// already expired, keep it shortly so Authenticate returns ErrKeyExpired
+	return time.Second
+}
@@ -0,0 +1,163 @@
+package apikey
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kitabisa/perkakas/v2/internal/redistest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisStore(t *testing.T) {
+	client := redistest.New()
+	testStore(t, NewRedisStore(client, "partner:apikey:"))
+
+	require.NoError(t, NewRedisStore(client, "partner:apikey:").Save(Key{Hash: "hash-2", ExpiresAt: time.Now().Add(time.Hour)}))
+	assert.InDelta(t, time.Hour.Seconds(), client.Expiration("partner:apikey:key:hash-2").Seconds(), 2)
+}
+
+func TestSQLStore(t *testing.T) {
+	db, err := sql.Open("apikey_fake", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	testStore(t, NewSQLStore(db, SQLStoreOption{Placeholder: DollarPlaceholder}))
+	assert.Contains(t, fakeDB.queries, "UPDATE api_keys SET last_used_at = $1 WHERE hash = $2")
+}
+
+func testStore(t *testing.T, store Store) {
+	createdAt := time.Unix(1630000000, 0)
+	key := Key{
+		ID:        "key-1",
+		Hash:      "hash-1",
+		Partner:   "partner-a",
+		Name:      "production",
+		Scopes:    []string{"donation:read", "campaign:read"},
+		CreatedAt: createdAt,
+	}
+	require.NoError(t, store.Save(key))
+
+	saved, err := store.Get("hash-1")
+	require.NoError(t, err)
+	assert.Equal(t, "partner-a", saved.Partner)
+	assert.Equal(t, key.Scopes, saved.Scopes)
+	assert.True(t, saved.ExpiresAt.IsZero())
+	assert.True(t, saved.LastUsedAt.IsZero())
+
+	usedAt := createdAt.Add(time.Hour)
+	require.NoError(t, store.Touch("hash-1", usedAt))
+	saved, err = store.Get("hash-1")
+	require.NoError(t, err)
+	assert.True(t, usedAt.Equal(saved.LastUsedAt))
+
+	_, err = store.Get("hash-unknown")
+	assert.Equal(t, ErrKeyNotFound, err)
+
+	require.NoError(t, store.Delete("hash-1"))
+	_, err = store.Get("hash-1")
+	assert.Equal(t, ErrKeyNotFound, err)
+}
+
+// fakeDB is database/sql driver understanding only the queries of SQLStore
+var fakeDB = &fakeDriver{rows: map[string][]driver.Value{}}
+
+func init() {
+	sql.Register("apikey_fake", fakeDB)
+}
+
+type fakeDriver struct {
+	mu      sync.Mutex
+	rows    map[string][]driver.Value // hash to id, partner, name, scopes, created_at, expires_at, last_used_at
+	queries []string
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) {
+	return d, nil
+}
+
+func (d *fakeDriver) Prepare(query string) (driver.Stmt, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.queries = append(d.queries, query)
+	return &fakeStmt{d: d, query: query}, nil
+}
+
+func (d *fakeDriver) Close() error {
+	return nil
+}
+
+func (d *fakeDriver) Begin() (driver.Tx, error) {
+	return nil, driver.ErrSkip
+}
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(s.query, "INSERT"):
+		s.d.rows[args[0].(string)] = args[1:]
+	case strings.HasPrefix(s.query, "UPDATE"):
+		if row, ok := s.d.rows[args[1].(string)]; ok {
+			row[6] = args[0]
+		}
+	case strings.HasPrefix(s.query, "DELETE"):
+		delete(s.d.rows, args[0].(string))
+	}
+
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+
+	row, ok := s.d.rows[args[0].(string)]
+	if !ok {
+		return &fakeRows{}, nil
+	}
+
+	return &fakeRows{rows: [][]driver.Value{row}}, nil
+}
+
+type fakeRows struct {
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return []string{"id", "partner", "name", "scopes", "created_at", "expires_at", "last_used_at"}
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
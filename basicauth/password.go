@@ -0,0 +1,161 @@
+// Package basicauth provides hashed credential store for basic auth
+
+package basicauth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrUnsupportedHash is returned when password hash is neither bcrypt nor argon2id
+	ErrUnsupportedHash = errors.New("basicauth: unsupported password hash")
+	// ErrInvalidHash is returned when password hash is malformed
+	ErrInvalidHash = errors.New("basicauth: invalid password hash")
+)
+
+const (
+	// maxArgon2Memory is maximum memory accepted from argon2id hash, 1 GiB
+	maxArgon2Memory = 1024 * 1024
+	// maxArgon2Iterations is maximum iterations accepted from argon2id hash
+	maxArgon2Iterations = 100
+)
+
+// Argon2Params is argon2id hashing parameters
+type Argon2Params struct {
+	Memory      uint32 // in KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params is argon2id parameters recommended by OWASP
+var DefaultArgon2Params = Argon2Params{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// HashBcrypt hashes password with bcrypt. Cost below bcrypt.MinCost uses bcrypt.DefaultCost.
+func HashBcrypt(password string, cost int) (string, error) {
+	if cost < bcrypt.MinCost {
+		cost = bcrypt.DefaultCost
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// HashArgon2id hashes password with argon2id, encoded in PHC string format,
+// e.g. $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+func HashArgon2id(password string, params Argon2Params) (string, error) {
+	salt := make([]byte, params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, params.Memory, params.Iterations,
+		params.Parallelism, base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// VerifyPassword compares password with bcrypt or argon2id hash in constant time
+func VerifyPassword(hash, password string) (bool, error) {
+	switch {
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return false, nil
+		}
+
+		if err != nil {
+			return false, fmt.Errorf("%w: %s", ErrInvalidHash, err)
+		}
+
+		return true, nil
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return verifyArgon2id(hash, password)
+	}
+
+	return false, ErrUnsupportedHash
+}
+
+func verifyArgon2id(hash, password string) (bool, error) {
+	params, salt, expected, err := parseArgon2id(hash)
+	if err != nil {
+		return false, err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	return subtle.ConstantTimeCompare(key, expected) == 1, nil
+}
+
+// parseArgon2id parses argon2id PHC string. Parameters which make argon2.IDKey panic or allocate too much memory
+// are rejected with ErrInvalidHash.
+func parseArgon2id(hash string) (params Argon2Params, salt, key []byte, err error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil ||
+		parts[3] != fmt.Sprintf("m=%d,t=%d,p=%d", params.Memory, params.Iterations, params.Parallelism) {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	if params.Iterations < 1 || params.Iterations > maxArgon2Iterations || params.Parallelism < 1 ||
+		params.Memory < 8*uint32(params.Parallelism) || params.Memory > maxArgon2Memory {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil || len(salt) == 0 {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) < 4 {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
+}
+
+// validateHash checks the hash can be verified by VerifyPassword
+func validateHash(hash string) error {
+	switch {
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidHash, err)
+		}
+
+		return nil
+	case strings.HasPrefix(hash, "$argon2id$"):
+		_, _, _, err := parseArgon2id(hash)
+		return err
+	}
+
+	return ErrUnsupportedHash
+}
@@ -0,0 +1,53 @@
+package basicauth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyPassword(t *testing.T) {
+	bcryptHash, err := HashBcrypt("s3cret", 4)
+	require.NoError(t, err)
+
+	argonHash, err := HashArgon2id("s3cret", Argon2Params{Memory: 8 * 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32})
+	require.NoError(t, err)
+	assert.Contains(t, argonHash, "$argon2id$v=19$m=8192,t=1,p=1$")
+
+	for _, hash := range []string{bcryptHash, argonHash} {
+		ok, err := VerifyPassword(hash, "s3cret")
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		ok, err = VerifyPassword(hash, "wrong")
+		require.NoError(t, err)
+		assert.False(t, ok)
+	}
+
+	_, err = VerifyPassword("{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=", "s3cret")
+	assert.Equal(t, ErrUnsupportedHash, err)
+
+	_, err = VerifyPassword("$argon2id$v=19$m=8192", "s3cret")
+	assert.Equal(t, ErrInvalidHash, err)
+}
+
+func TestVerifyPasswordInvalidArgon2Params(t *testing.T) {
+	const saltAndKey = "$c2FsdHNhbHRzYWx0c2FsdA$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U"
+
+	for _, params := range []string{
+		"m=8192,t=0,p=1",       // argon2.IDKey panics
+		"m=8192,t=1,p=0",       // argon2.IDKey panics
+		"m=4294967295,t=1,p=1", // unbounded memory
+		"m=4,t=1,p=1",          // below 8*p
+		"m=8192,t=1,p=1,x=1",
+	} {
+		_, err := VerifyPassword("$argon2id$v=19$"+params+saltAndKey, "s3cret")
+		assert.Equal(t, ErrInvalidHash, err, params)
+	}
+}
+
+func TestDummyHash(t *testing.T) {
+	_, err := VerifyPassword(dummyHash, "s3cret")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,146 @@
+package basicauth
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ErrUserNotFound is returned when username is not in the store
+var ErrUserNotFound = errors.New("basicauth: user not found")
+
+// dummyHash is compared when user is not found, so response time doesn't reveal whether the user exists
+const dummyHash = "$2a$10$rwtCGoicJAUbaaY8AZ8ip.pm/jTDYe4LeZzNWbEKOCmmSKEcesCwK"
+
+// Store provides password hash by username
+type Store interface {
+	PasswordHash(username string) (string, error)
+}
+
+// MemoryStore is in-memory credential store
+type MemoryStore struct {
+	mu     sync.RWMutex
+	hashes map[string]string
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore creates in-memory credential store from username to password hash
+func NewMemoryStore(hashes map[string]string) *MemoryStore {
+	s := &MemoryStore{
+		hashes: make(map[string]string, len(hashes)),
+	}
+
+	for username, hash := range hashes {
+		s.hashes[username] = hash
+	}
+
+	return s
+}
+
+func (s *MemoryStore) PasswordHash(username string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	hash, ok := s.hashes[username]
+	if !ok {
+		return "", ErrUserNotFound
+	}
+
+	return hash, nil
+}
+
+// Set sets password hash of the user
+func (s *MemoryStore) Set(username, hash string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.hashes[username] = hash
+}
+
+// ParseHtpasswd parses htpasswd content, one "username:hash" per line. Empty line and line starting with # is skipped.
+// Only bcrypt and argon2id hash is supported.
+func ParseHtpasswd(r io.Reader) (*MemoryStore, error) {
+	hashes := map[string]string{}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		if err := parseEntry(entry, hashes); err != nil {
+			return nil, fmt.Errorf("%w at line %d", err, line)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return NewMemoryStore(hashes), nil
+}
+
+// LoadHtpasswd loads credential store from htpasswd file, e.g. generated with `htpasswd -B`
+func LoadHtpasswd(path string) (*MemoryStore, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("basicauth: failed to open htpasswd file: %w", err)
+	}
+	defer f.Close()
+
+	return ParseHtpasswd(f)
+}
+
+// LoadEnv loads credential store from environment variable of whitespace separated "username:hash" entries,
+// e.g. BASIC_AUTH_USERS="admin:$2y$10$... partner:$argon2id$v=19$..."
+func LoadEnv(name string) (*MemoryStore, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return nil, fmt.Errorf("basicauth: environment variable %s is not set", name)
+	}
+
+	hashes := map[string]string{}
+	for _, entry := range strings.Fields(value) {
+		if err := parseEntry(entry, hashes); err != nil {
+			return nil, fmt.Errorf("%w in %s", err, name)
+		}
+	}
+
+	return NewMemoryStore(hashes), nil
+}
+
+func parseEntry(entry string, hashes map[string]string) error {
+	parts := strings.SplitN(entry, ":", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return ErrInvalidHash
+	}
+
+	if err := validateHash(parts[1]); err != nil {
+		return fmt.Errorf("%w of user %s", err, parts[0])
+	}
+
+	hashes[parts[0]] = parts[1]
+	return nil
+}
+
+// Authenticate checks username and password against the store. Unknown user still costs a hash comparison,
+// so response time doesn't reveal whether the user exists.
+func Authenticate(store Store, username, password string) (bool, error) {
+	hash, err := store.PasswordHash(username)
+	if errors.Is(err, ErrUserNotFound) {
+		VerifyPassword(dummyHash, password)
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return VerifyPassword(hash, password)
+}
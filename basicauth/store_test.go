@@ -0,0 +1,58 @@
+package basicauth
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHtpasswd(t *testing.T) {
+	hash, err := HashBcrypt("s3cret", 4)
+	require.NoError(t, err)
+
+	content := "# partners\n\nadmin:" + hash + "\n  partner:" + hash + "  \n"
+	store, err := ParseHtpasswd(strings.NewReader(content))
+	require.NoError(t, err)
+
+	ok, err := Authenticate(store, "partner", "s3cret")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = Authenticate(store, "unknown", "s3cret")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = ParseHtpasswd(strings.NewReader("admin:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g="))
+	assert.True(t, errors.Is(err, ErrUnsupportedHash))
+
+	_, err = ParseHtpasswd(strings.NewReader("admin"))
+	assert.True(t, errors.Is(err, ErrInvalidHash))
+
+	// malformed hash is rejected on load instead of failing every login
+	for _, hash := range []string{"$2y$10$short", "$argon2id$v=19$m=8192,t=0,p=1$c2FsdA$a2V5a2V5"} {
+		_, err = ParseHtpasswd(strings.NewReader("admin:" + hash))
+		assert.True(t, errors.Is(err, ErrInvalidHash), hash)
+	}
+}
+
+func TestLoadEnv(t *testing.T) {
+	hash, err := HashBcrypt("s3cret", 4)
+	require.NoError(t, err)
+
+	os.Setenv("TEST_BASIC_AUTH_USERS", "admin:"+hash+" partner:"+hash)
+	defer os.Unsetenv("TEST_BASIC_AUTH_USERS")
+
+	store, err := LoadEnv("TEST_BASIC_AUTH_USERS")
+	require.NoError(t, err)
+
+	ok, err := Authenticate(store, "admin", "s3cret")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	_, err = LoadEnv("TEST_BASIC_AUTH_MISSING")
+	assert.Error(t, err)
+}
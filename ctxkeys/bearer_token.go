@@ -0,0 +1,17 @@
+package ctxkeys
+
+import "context"
+
+// CtxBearerToken context key for raw bearer token of the incoming request
+var CtxBearerToken ContextKey = "Ktbs-Bearer-Token"
+
+// WithBearerToken stores raw bearer token into context
+func WithBearerToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, CtxBearerToken, token)
+}
+
+// BearerTokenFrom gets raw bearer token from context
+func BearerTokenFrom(ctx context.Context) string {
+	token, _ := ctx.Value(CtxBearerToken).(string)
+	return token
+}
@@ -0,0 +1,57 @@
+package ctxkeys
+
+import (
+	"context"
+	"net/http"
+)
+
+// CtxClientInfo context key for client info of the incoming request
+var CtxClientInfo ContextKey = "Ktbs-Client-Info"
+
+// ClientInfo is client metadata sent in kitabisa standard headers
+type ClientInfo struct {
+	ClientName    string // X-Ktbs-Client-Name
+	ClientVersion string // X-Ktbs-Client-Version
+	APIVersion    string // X-Ktbs-Api-Version
+	PlatformName  string // X-Ktbs-Platform-Name
+	Time          string // X-Ktbs-Time
+	UserAgent     string // User-Agent
+}
+
+// ClientInfoFromHeader gets client info from request header
+func ClientInfoFromHeader(header http.Header) ClientInfo {
+	return ClientInfo{
+		ClientName:    header.Get("X-Ktbs-Client-Name"),
+		ClientVersion: header.Get("X-Ktbs-Client-Version"),
+		APIVersion:    header.Get("X-Ktbs-Api-Version"),
+		PlatformName:  header.Get("X-Ktbs-Platform-Name"),
+		Time:          header.Get("X-Ktbs-Time"),
+		UserAgent:     header.Get("User-Agent"),
+	}
+}
+
+// WithClientInfo stores client info into context
+func WithClientInfo(ctx context.Context, info ClientInfo) context.Context {
+	return context.WithValue(ctx, CtxClientInfo, info)
+}
+
+// ClientInfoFrom gets client info from context. It falls back to the deprecated header name keys
+// set by middleware.MapHeaderToContext.
+func ClientInfoFrom(ctx context.Context) (ClientInfo, bool) {
+	if info, ok := ctx.Value(CtxClientInfo).(ClientInfo); ok {
+		return info, true
+	}
+
+	header := http.Header{}
+	for _, key := range []string{"X-Ktbs-Client-Name", "X-Ktbs-Client-Version", "X-Ktbs-Api-Version", "X-Ktbs-Platform-Name", "X-Ktbs-Time", "User-Agent"} {
+		if value, ok := ctx.Value(key).(string); ok {
+			header.Set(key, value)
+		}
+	}
+
+	if len(header) == 0 {
+		return ClientInfo{}, false
+	}
+
+	return ClientInfoFromHeader(header), true
+}
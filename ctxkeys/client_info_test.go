@@ -0,0 +1,24 @@
+package ctxkeys
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientInfoFrom(t *testing.T) {
+	info := ClientInfo{ClientName: "kitabisa-web", PlatformName: "web"}
+
+	got, ok := ClientInfoFrom(WithClientInfo(context.Background(), info))
+	assert.True(t, ok)
+	assert.Equal(t, info, got)
+
+	// deprecated header keys
+	got, ok = ClientInfoFrom(context.WithValue(context.Background(), "X-Ktbs-Client-Name", "kitabisa-web"))
+	assert.True(t, ok)
+	assert.Equal(t, "kitabisa-web", got.ClientName)
+
+	_, ok = ClientInfoFrom(context.Background())
+	assert.False(t, ok)
+}
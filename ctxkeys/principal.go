@@ -0,0 +1,33 @@
+package ctxkeys
+
+import "context"
+
+// CtxPrincipal context key for authenticated principal of the incoming request
+var CtxPrincipal ContextKey = "Ktbs-Principal"
+
+// Authentication method of principal
+const (
+	AuthMethodBasic     = "basic"
+	AuthMethodAPIKey    = "api_key"
+	AuthMethodSignature = "signature"
+	AuthMethodMTLS      = "mtls"
+)
+
+// Principal is authenticated caller which is not a jwt or paseto user, e.g. basic auth user or api key partner
+type Principal struct {
+	ID     string   // e.g. basic auth username, api key partner, signing client name or mtls service identity
+	Method string   // authentication method, e.g. AuthMethodBasic
+	KeyID  string   // id of the credential, e.g. api key id or signing key id
+	Scopes []string // granted scopes, checked by authorization middleware
+}
+
+// WithPrincipal stores authenticated principal into context
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, CtxPrincipal, principal)
+}
+
+// PrincipalFrom gets authenticated principal from context
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(CtxPrincipal).(Principal)
+	return principal, ok
+}
@@ -0,0 +1,19 @@
+package ctxkeys
+
+import "context"
+
+// WithRequestID stores request ID into context under CtxXKtbsRequestID
+func WithRequestID(ctx context.Context, reqID string) context.Context {
+	return context.WithValue(ctx, CtxXKtbsRequestID, reqID)
+}
+
+// RequestIDFrom gets request ID from context. It falls back to the deprecated string key
+// "X-Ktbs-Request-ID" for context that is set by older middleware.
+func RequestIDFrom(ctx context.Context) string {
+	if reqID, ok := ctx.Value(CtxXKtbsRequestID).(string); ok && reqID != "" {
+		return reqID
+	}
+
+	reqID, _ := ctx.Value(CtxXKtbsRequestID.String()).(string)
+	return reqID
+}
@@ -0,0 +1,42 @@
+package ctxkeys
+
+import "context"
+
+var (
+	// CtxJWTClaims context key for claims of verified jwt token
+	CtxJWTClaims ContextKey = "Ktbs-JWT-Claims"
+
+	// CtxPasetoToken context key for verified paseto token
+	CtxPasetoToken ContextKey = "Ktbs-Paseto-Token"
+)
+
+// PasetoToken is verified paseto token with its footer. Token is paseto.JSONToken of o1egl/paseto.
+type PasetoToken struct {
+	Token  interface{}
+	Footer string
+}
+
+// WithUserClaim stores claims of verified jwt token into context, e.g. *jwt.UserClaim of token/jwt package
+// or custom claims of middleware.WithClaims
+func WithUserClaim(ctx context.Context, claims interface{}) context.Context {
+	return context.WithValue(ctx, CtxJWTClaims, claims)
+}
+
+// UserClaimFrom gets claims of verified jwt token from context. Use jwt.UserClaimFrom or jwt.ClaimsFrom of
+// token/jwt package to get it typed.
+func UserClaimFrom(ctx context.Context) (interface{}, bool) {
+	claims := ctx.Value(CtxJWTClaims)
+	return claims, claims != nil
+}
+
+// WithPasetoToken stores verified paseto token and its footer into context
+func WithPasetoToken(ctx context.Context, token interface{}, footer string) context.Context {
+	return context.WithValue(ctx, CtxPasetoToken, PasetoToken{Token: token, Footer: footer})
+}
+
+// PasetoTokenFrom gets verified paseto token and its footer from context. Use paseto.TokenFrom of token/paseto
+// package to get it typed.
+func PasetoTokenFrom(ctx context.Context) (PasetoToken, bool) {
+	token, ok := ctx.Value(CtxPasetoToken).(PasetoToken)
+	return token, ok
+}
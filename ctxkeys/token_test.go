@@ -0,0 +1,32 @@
+package ctxkeys
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testClaims struct {
+	UserID int64
+}
+
+func TestUserClaimFrom(t *testing.T) {
+	claims := &testClaims{UserID: 12345}
+
+	got, ok := UserClaimFrom(WithUserClaim(context.Background(), claims))
+	assert.True(t, ok)
+	assert.Equal(t, claims, got)
+
+	_, ok = UserClaimFrom(context.Background())
+	assert.False(t, ok)
+}
+
+func TestPasetoTokenFrom(t *testing.T) {
+	got, ok := PasetoTokenFrom(WithPasetoToken(context.Background(), "token", "Kitabisa.com"))
+	assert.True(t, ok)
+	assert.Equal(t, PasetoToken{Token: "token", Footer: "Kitabisa.com"}, got)
+
+	_, ok = PasetoTokenFrom(WithUserClaim(context.Background(), &testClaims{}))
+	assert.False(t, ok)
+}
@@ -0,0 +1,76 @@
+package mtls
+
+import (
+	"context"
+	"errors"
+
+	"github.com/kitabisa/perkakas/v2/ctxkeys"
+	"github.com/kitabisa/perkakas/v2/grpcinterceptor/wrapper"
+	"github.com/kitabisa/perkakas/v2/mtls"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Interceptor authenticates service identity from verified client certificate of the grpc connection.
+// Server must use credentials.NewTLS with client certificate required, e.g. mtls.Reloader ServerConfig.
+type Interceptor struct {
+	verifier *mtls.Verifier
+}
+
+// NewInterceptor creates mtls interceptor checking client certificate with the verifier
+func NewInterceptor(verifier *mtls.Verifier) *Interceptor {
+	return &Interceptor{
+		verifier: verifier,
+	}
+}
+
+// UnaryServerInterceptor stores service identity in context, get it with ctxkeys.PrincipalFrom. Call without verified
+// certificate is rejected with Unauthenticated, and certificate not allowed by the policy with PermissionDenied.
+func (i *Interceptor) UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := i.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+// StreamingServerInterceptor stores service identity in stream context, see UnaryServerInterceptor
+func (i *Interceptor) StreamingServerInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := i.authenticate(stream.Context())
+	if err != nil {
+		return err
+	}
+
+	return handler(srv, wrapper.NewServerStreamWrapper(ctx, stream))
+}
+
+func (i *Interceptor) authenticate(ctx context.Context) (context.Context, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, mtls.ErrNoPeerCertificate.Error())
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, mtls.ErrNoPeerCertificate.Error())
+	}
+
+	identity, err := i.verifier.Verify(&tlsInfo.State)
+	if errors.Is(err, mtls.ErrIdentityNotAllowed) {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	return ctxkeys.WithPrincipal(ctx, ctxkeys.Principal{
+		ID:     identity.ID,
+		Method: ctxkeys.AuthMethodMTLS,
+		Scopes: identity.Scopes,
+	}), nil
+}
@@ -0,0 +1,74 @@
+package mtls
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+
+	"github.com/kitabisa/perkakas/v2/ctxkeys"
+	"github.com/kitabisa/perkakas/v2/grpcinterceptor/mocks"
+	"github.com/kitabisa/perkakas/v2/mtls"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func peerContext(commonName string) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: commonName}}}},
+		}},
+	})
+}
+
+func newTestInterceptor(t *testing.T) *Interceptor {
+	verifier, err := mtls.NewVerifier(mtls.Policy{CommonNames: []string{"report-cron"}})
+	require.NoError(t, err)
+
+	return NewInterceptor(verifier)
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := newTestInterceptor(t)
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		principal, ok := ctxkeys.PrincipalFrom(ctx)
+		assert.True(t, ok)
+		return principal.ID, nil
+	}
+
+	resp, err := interceptor.UnaryServerInterceptor(peerContext("report-cron"), nil, mocks.UnaryInfo, handler)
+	require.NoError(t, err)
+	assert.Equal(t, "report-cron", resp)
+
+	_, err = interceptor.UnaryServerInterceptor(peerContext("other-service"), nil, mocks.UnaryInfo, handler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = interceptor.UnaryServerInterceptor(context.Background(), nil, mocks.UnaryInfo, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestStreamingServerInterceptor(t *testing.T) {
+	interceptor := newTestInterceptor(t)
+
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		principal, ok := ctxkeys.PrincipalFrom(stream.Context())
+		assert.True(t, ok)
+		assert.Equal(t, ctxkeys.AuthMethodMTLS, principal.Method)
+		return nil
+	}
+
+	stream := mocks.NewMockServerStream(false)
+	stream.SetContext(peerContext("report-cron"))
+	err := interceptor.StreamingServerInterceptor(nil, stream, mocks.StreamInfo, handler)
+	assert.NoError(t, err)
+
+	err = interceptor.StreamingServerInterceptor(nil, mocks.NewMockServerStream(false), mocks.StreamInfo, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
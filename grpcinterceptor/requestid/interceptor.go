@@ -47,6 +47,20 @@ func StreamingServerInterceptor(srv interface{}, stream grpc.ServerStream, info
 	return instance.StreamingServerInterceptor(srv, stream, info, handler)
 }
 
+// UnaryClientInterceptor calling requestID UnaryClientInterceptor
+// with default interceptor instance
+func UnaryClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	Init()
+	return instance.UnaryClientInterceptor(ctx, method, req, reply, cc, invoker, opts...)
+}
+
+// StreamClientInterceptor calling requestID StreamClientInterceptor
+// with default interceptor instance
+func StreamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	Init()
+	return instance.StreamClientInterceptor(ctx, desc, cc, method, streamer, opts...)
+}
+
 type Options func(*Interceptor)
 
 type Interceptor struct {
@@ -124,6 +138,36 @@ func (i *Interceptor) StreamingServerInterceptor(srv interface{}, stream grpc.Se
 	return handler(srv, newStream)
 }
 
+// UnaryClientInterceptor propagates requestID from context to outgoing grpc metadata
+func (i *Interceptor) UnaryClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	ctx = i.outgoingContext(ctx)
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// StreamClientInterceptor propagates requestID from context to outgoing grpc metadata
+func (i *Interceptor) StreamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	ctx = i.outgoingContext(ctx)
+	return streamer(ctx, desc, cc, method, opts...)
+}
+
+func (i *Interceptor) outgoingContext(ctx context.Context) context.Context {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if len(md[i.metadataKey]) > 0 {
+		return ctx
+	}
+
+	reqID, _ := ctx.Value(i.contextKey).(string)
+	if reqID == "" {
+		reqID = ctxkeys.RequestIDFrom(ctx)
+	}
+
+	if reqID == "" {
+		return ctx
+	}
+
+	return metadata.AppendToOutgoingContext(ctx, i.metadataKey, reqID)
+}
+
 func getRequestID(ctx context.Context, key string) (val string, err error) {
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
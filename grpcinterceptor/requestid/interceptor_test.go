@@ -303,3 +303,47 @@ func TestInstanceStreamingServerInterceptorWithRequestIDAndCustomContextKey(t *t
 	)
 	interceptor.StreamingServerInterceptor(nil, serverStream, mocks.StreamInfo, test)
 }
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	reqID := uuid.NewV4().String()
+
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		assert.Equal(t, []string{reqID}, md[GrpcRequestIDKey])
+		return nil
+	}
+
+	ctx := ctxkeys.WithRequestID(context.Background(), reqID)
+	err := UnaryClientInterceptor(ctx, "TestUnaryClientInterceptor", nil, nil, nil, invoker)
+	assert.NoError(t, err)
+}
+
+func TestUnaryClientInterceptorWithoutRequestID(t *testing.T) {
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		_, ok := metadata.FromOutgoingContext(ctx)
+		assert.False(t, ok)
+		return nil
+	}
+
+	err := UnaryClientInterceptor(context.Background(), "TestUnaryClientInterceptor", nil, nil, nil, invoker)
+	assert.NoError(t, err)
+}
+
+func TestInstanceStreamClientInterceptorWithCustomKey(t *testing.T) {
+	reqID := uuid.NewV4().String()
+
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		assert.Equal(t, []string{reqID}, md["custom-requestid-key"])
+		return nil, nil
+	}
+
+	interceptor := NewInterceptor(
+		WithMetadataKey("custom-requestid-key"),
+		WithContextKey("custom-context-key"),
+	)
+
+	ctx := context.WithValue(context.Background(), ctxkeys.ContextKey("custom-context-key"), reqID)
+	_, err := interceptor.StreamClientInterceptor(ctx, &grpc.StreamDesc{}, nil, "TestStreamClientInterceptor", streamer)
+	assert.NoError(t, err)
+}
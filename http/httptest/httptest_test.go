@@ -0,0 +1,85 @@
+package httptest_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	phttp "github.com/kitabisa/perkakas/v2/http"
+	"github.com/kitabisa/perkakas/v2/http/httptest"
+	"github.com/kitabisa/perkakas/v2/middleware"
+	"github.com/kitabisa/perkakas/v2/structs"
+	"github.com/kitabisa/perkakas/v2/token/jwt"
+)
+
+type person struct {
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
+
+var (
+	secretKey = "secret"
+	signKey   = []byte("abcde")
+)
+
+func newTestHandler() http.Handler {
+	hctx := phttp.NewContextHandler(structs.Meta{Version: "v1.0.0", Status: "stable", APIEnv: "test"})
+
+	handler := phttp.NewHttpHandler(hctx)(func(w http.ResponseWriter, r *http.Request) (interface{}, *string, error) {
+		return person{FirstName: "Kitabisa", LastName: "Dot Com"}, nil, nil
+	})
+
+	return middleware.NewHeaderCheck(hctx, secretKey)(middleware.NewJWT(hctx, signKey)(handler))
+}
+
+func TestSuccess(t *testing.T) {
+	claims := jwt.UserClaim{UserID: 12345}
+	claims.ExpiresAt = time.Now().Add(time.Minute).Unix()
+
+	req := httptest.NewRequest(t, http.MethodGet, "/").
+		WithSignature(secretKey).
+		WithJWT(jwt.NewJWT(signKey), claims).
+		Build()
+
+	res := httptest.Serve(t, newTestHandler(), req)
+	res.AssertSuccess()
+
+	var p person
+	res.DecodeData(&p)
+	if p.FirstName != "Kitabisa" {
+		t.Errorf("unexpected first name %s", p.FirstName)
+	}
+
+	var ps []person
+	res.DecodeData(&ps)
+	if len(ps) != 1 {
+		t.Errorf("unexpected data length %d", len(ps))
+	}
+
+	res.AssertGolden("testdata/success.golden.json")
+}
+
+func TestError(t *testing.T) {
+	req := httptest.NewRequest(t, http.MethodGet, "/").
+		WithSignature("wrong-secret").
+		Build()
+
+	res := httptest.Serve(t, newTestHandler(), req)
+	res.AssertError(structs.ErrInvalidHeaderSignature)
+
+	req = httptest.NewRequest(t, http.MethodGet, "/").
+		WithSignature(secretKey).
+		WithoutHeader("X-Ktbs-Request-ID").
+		Build()
+
+	res = httptest.Serve(t, newTestHandler(), req)
+	res.AssertError(structs.ErrInvalidHeader)
+
+	req = httptest.NewRequest(t, http.MethodGet, "/").
+		WithSignature(secretKey).
+		WithBearerToken("invalid").
+		Build()
+
+	res = httptest.Serve(t, newTestHandler(), req)
+	res.AssertError(structs.ErrUnauthorized)
+}
@@ -0,0 +1,183 @@
+// Package httptest provides request builder and envelope-aware assertion for testing perkakas http handler
+
+package httptest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	libjwt "github.com/golang-jwt/jwt"
+	"github.com/kitabisa/perkakas/v2/signature"
+	libpaseto "github.com/o1egl/paseto"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Default X-Ktbs-* header values filled by NewRequest
+const (
+	DefaultAPIVersion    = "1.0.0"
+	DefaultClientVersion = "1.0.0"
+	DefaultPlatformName  = "android"
+	DefaultClientName    = "perkakas-test"
+)
+
+// JWTCreator creates jwt token, implemented by jwt.JWT and jwt.JWTRSA
+type JWTCreator interface {
+	Create(claims libjwt.Claims) (string, error)
+}
+
+// PasetoEncrypter creates paseto token, implemented by paseto.PasetoSymmetric and paseto.PasetoAsymmetric
+type PasetoEncrypter interface {
+	Encrypt(token libpaseto.JSONToken, footer string) (string, error)
+}
+
+// RequestBuilder builds request with valid X-Ktbs-* headers
+type RequestBuilder struct {
+	t         testing.TB
+	method    string
+	target    string
+	body      io.Reader
+	header    http.Header
+	ctx       context.Context
+	secretKey *string
+	now       time.Time
+}
+
+// NewRequest creates request builder for the given method and target. Target can be path or full url.
+// X-Ktbs-Request-ID, X-Ktbs-Api-Version, X-Ktbs-Client-Version, X-Ktbs-Platform-Name and X-Ktbs-Client-Name
+// headers are pre-filled with valid values.
+func NewRequest(t testing.TB, method, target string) *RequestBuilder {
+	header := make(http.Header)
+	header.Set("X-Ktbs-Request-ID", uuid.NewV4().String())
+	header.Set("X-Ktbs-Api-Version", DefaultAPIVersion)
+	header.Set("X-Ktbs-Client-Version", DefaultClientVersion)
+	header.Set("X-Ktbs-Platform-Name", DefaultPlatformName)
+	header.Set("X-Ktbs-Client-Name", DefaultClientName)
+
+	return &RequestBuilder{
+		t:      t,
+		method: method,
+		target: target,
+		header: header,
+		ctx:    context.Background(),
+	}
+}
+
+// WithHeader sets request header
+func (b *RequestBuilder) WithHeader(key, value string) *RequestBuilder {
+	b.header.Set(key, value)
+	return b
+}
+
+// WithoutHeader removes request header, e.g. to test missing header
+func (b *RequestBuilder) WithoutHeader(key string) *RequestBuilder {
+	b.header.Del(key)
+	return b
+}
+
+// WithClientName sets X-Ktbs-Client-Name header
+func (b *RequestBuilder) WithClientName(clientName string) *RequestBuilder {
+	return b.WithHeader("X-Ktbs-Client-Name", clientName)
+}
+
+// WithSignature signs the request with X-Ktbs-Signature and X-Ktbs-Time headers, as checked by middleware.NewHeaderCheck
+func (b *RequestBuilder) WithSignature(secretKey string) *RequestBuilder {
+	b.secretKey = &secretKey
+	return b
+}
+
+// WithNonce sets X-Ktbs-Nonce header, signed by WithSignature
+func (b *RequestBuilder) WithNonce(nonce string) *RequestBuilder {
+	return b.WithHeader("X-Ktbs-Nonce", nonce)
+}
+
+// WithTime sets the time used for X-Ktbs-Time header. Default is current time.
+func (b *RequestBuilder) WithTime(now time.Time) *RequestBuilder {
+	b.now = now
+	return b
+}
+
+// WithBody sets request body
+func (b *RequestBuilder) WithBody(body io.Reader) *RequestBuilder {
+	b.body = body
+	return b
+}
+
+// WithJSON sets json encoded v as request body
+func (b *RequestBuilder) WithJSON(v interface{}) *RequestBuilder {
+	body, err := json.Marshal(v)
+	if err != nil {
+		b.t.Fatalf("httptest: failed to marshal json body: %s", err)
+	}
+
+	b.header.Set("Content-Type", "application/json")
+	return b.WithBody(bytes.NewReader(body))
+}
+
+// WithContext sets request context
+func (b *RequestBuilder) WithContext(ctx context.Context) *RequestBuilder {
+	b.ctx = ctx
+	return b
+}
+
+// WithBearerToken sets Authorization header with bearer token
+func (b *RequestBuilder) WithBearerToken(token string) *RequestBuilder {
+	return b.WithHeader("Authorization", fmt.Sprintf("Bearer %s", token))
+}
+
+// WithJWT mints jwt token from claims and sets it as bearer token
+func (b *RequestBuilder) WithJWT(creator JWTCreator, claims libjwt.Claims) *RequestBuilder {
+	token, err := creator.Create(claims)
+	if err != nil {
+		b.t.Fatalf("httptest: failed to create jwt token: %s", err)
+	}
+
+	return b.WithBearerToken(token)
+}
+
+// WithPaseto mints paseto token and sets it as bearer token
+func (b *RequestBuilder) WithPaseto(encrypter PasetoEncrypter, token libpaseto.JSONToken, footer string) *RequestBuilder {
+	encToken, err := encrypter.Encrypt(token, footer)
+	if err != nil {
+		b.t.Fatalf("httptest: failed to create paseto token: %s", err)
+	}
+
+	return b.WithBearerToken(encToken)
+}
+
+// Build builds the request. The request can be served directly to handler using Serve,
+// or sent using http.Client when target is full url.
+func (b *RequestBuilder) Build() *http.Request {
+	if b.secretKey != nil {
+		now := b.now
+		if now.IsZero() {
+			now = time.Now()
+		}
+
+		timestamp := strconv.FormatInt(now.Unix(), 10)
+		data := fmt.Sprintf("%s%s%s", b.header.Get("X-Ktbs-Client-Name"), timestamp, b.header.Get("X-Ktbs-Nonce"))
+
+		b.header.Set("X-Ktbs-Time", timestamp)
+		b.header.Set("X-Ktbs-Signature", signature.GenerateHmac(data, *b.secretKey))
+	}
+
+	req := httptest.NewRequest(b.method, b.target, b.body)
+	req = req.WithContext(b.ctx)
+	for k, v := range b.header {
+		req.Header[k] = append([]string(nil), v...)
+	}
+
+	// request created by httptest.NewRequest is server request, it can not be sent using http.Client
+	if req.URL.IsAbs() {
+		req.RequestURI = ""
+	}
+
+	return req
+}
@@ -0,0 +1,175 @@
+package httptest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/kitabisa/perkakas/v2/structs"
+	"github.com/stretchr/testify/assert"
+)
+
+// UpdateGoldenEnv is environment variable to update golden files instead of comparing, e.g. UPDATE_GOLDEN=1 go test ./...
+const UpdateGoldenEnv = "UPDATE_GOLDEN"
+
+const successResponseCode = "000000"
+
+// Response is http response with envelope-aware assertion
+type Response struct {
+	t          testing.TB
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Serve serves the request to handler and returns the response
+func Serve(t testing.TB, handler http.Handler, req *http.Request) *Response {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	return &Response{
+		t:          t,
+		StatusCode: rec.Code,
+		Header:     rec.Header(),
+		Body:       rec.Body.Bytes(),
+	}
+}
+
+// NewResponse reads and closes http response body, e.g. response from httptest.Server
+func NewResponse(t testing.TB, res *http.Response) *Response {
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("httptest: failed to read response body: %s", err)
+	}
+
+	return &Response{
+		t:          t,
+		StatusCode: res.StatusCode,
+		Header:     res.Header,
+		Body:       body,
+	}
+}
+
+// AssertStatus asserts http status code
+func (r *Response) AssertStatus(code int) bool {
+	r.t.Helper()
+	return assert.Equal(r.t, code, r.StatusCode, "http status code. Body: %s", r.Body)
+}
+
+// SuccessResponse decodes body as structs.SuccessResponse
+func (r *Response) SuccessResponse() (resp structs.SuccessResponse) {
+	r.t.Helper()
+	if err := json.Unmarshal(r.Body, &resp); err != nil {
+		r.t.Fatalf("httptest: failed to decode success response: %s. Body: %s", err, r.Body)
+	}
+
+	return
+}
+
+// ErrorResponse decodes body as structs.ErrorResponse
+func (r *Response) ErrorResponse() (resp structs.ErrorResponse) {
+	r.t.Helper()
+	if err := json.Unmarshal(r.Body, &resp); err != nil {
+		r.t.Fatalf("httptest: failed to decode error response: %s. Body: %s", err, r.Body)
+	}
+
+	resp.HttpStatus = r.StatusCode
+	return
+}
+
+// AssertSuccess asserts the response is success response with 000000 response code
+func (r *Response) AssertSuccess() bool {
+	r.t.Helper()
+	if !r.AssertStatus(http.StatusOK) {
+		return false
+	}
+
+	return assert.Equal(r.t, successResponseCode, r.SuccessResponse().ResponseCode, "response code")
+}
+
+// DecodeData decodes data of the success response into v. Since data is always written as array,
+// v can be pointer to slice, or pointer to struct to decode the first element.
+func (r *Response) DecodeData(v interface{}) {
+	r.t.Helper()
+
+	var resp struct {
+		Data json.RawMessage `json:"data"`
+	}
+
+	if err := json.Unmarshal(r.Body, &resp); err != nil {
+		r.t.Fatalf("httptest: failed to decode success response: %s. Body: %s", err, r.Body)
+	}
+
+	data := resp.Data
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && rv.Elem().Kind() != reflect.Slice && rv.Elem().Kind() != reflect.Interface {
+		var arr []json.RawMessage
+		if err := json.Unmarshal(data, &arr); err == nil {
+			if len(arr) == 0 {
+				r.t.Fatalf("httptest: response data is empty")
+			}
+
+			data = arr[0]
+		}
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		r.t.Fatalf("httptest: failed to decode response data: %s. Data: %s", err, data)
+	}
+}
+
+// AssertError asserts the response matches the expected error response http status and response code
+func (r *Response) AssertError(expected *structs.ErrorResponse) bool {
+	r.t.Helper()
+	if !r.AssertStatus(expected.HttpStatus) {
+		return false
+	}
+
+	return r.AssertErrorCode(expected.ResponseCode)
+}
+
+// AssertErrorCode asserts response code of the error response
+func (r *Response) AssertErrorCode(code string) bool {
+	r.t.Helper()
+	return assert.Equal(r.t, code, r.ErrorResponse().ResponseCode, "response code")
+}
+
+// AssertGolden compares response body with golden file. Json body is compared after indented, so the golden file
+// is readable. Set UPDATE_GOLDEN environment variable to write the golden file.
+func (r *Response) AssertGolden(path string) bool {
+	r.t.Helper()
+
+	actual := r.Body
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, r.Body, "", "  "); err == nil {
+		indented.WriteByte('\n')
+		actual = indented.Bytes()
+	}
+
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			r.t.Fatalf("httptest: failed to create golden file directory: %s", err)
+		}
+
+		if err := ioutil.WriteFile(path, actual, 0644); err != nil {
+			r.t.Fatalf("httptest: failed to write golden file: %s", err)
+		}
+
+		return true
+	}
+
+	expected, err := ioutil.ReadFile(path)
+	if err != nil {
+		r.t.Fatalf("httptest: failed to read golden file, run test with %s=1 to create it: %s", UpdateGoldenEnv, err)
+	}
+
+	return assert.Equal(r.t, string(expected), string(actual), "golden file %s", path)
+}
@@ -0,0 +1,322 @@
+// Package openapi generates OpenAPI 3 document from registered perkakas http handler
+
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	phttp "github.com/kitabisa/perkakas/v2/http"
+	"github.com/kitabisa/perkakas/v2/structs"
+)
+
+const openAPIVersion = "3.0.3"
+
+var pathParamPattern = regexp.MustCompile(`{([^}:]+)(:[^}]*)?}`)
+
+// ktbsHeaders is the Kitabisa standard request headers checked by middleware.NewHeaderCheck
+var ktbsHeaders = []struct {
+	name        string
+	description string
+	format      string
+	required    bool
+}{
+	{name: "X-Ktbs-Request-ID", description: "Request ID", format: "uuid", required: true},
+	{name: "X-Ktbs-Api-Version", description: "API version in semver format", required: true},
+	{name: "X-Ktbs-Client-Version", description: "Client version in semver format", required: true},
+	{name: "X-Ktbs-Platform-Name", description: "Client platform name", required: true},
+	{name: "X-Ktbs-Client-Name", description: "Client name", required: true},
+	{name: "X-Ktbs-Signature", description: "HMAC signature of client name, time and nonce"},
+	{name: "X-Ktbs-Time", description: "Request unix timestamp"},
+	{name: "X-Ktbs-Nonce", description: "Unique request nonce, a signature is accepted once"},
+}
+
+// Route is http handler along with its documentation
+type Route struct {
+	Method      string
+	Path        string // chi style path, e.g. /users/{id}
+	Summary     string
+	Description string
+	Tags        []string
+	Handler     phttp.HttpHandler
+	Query       interface{} // struct which fields are the query params
+	Request     interface{} // request body
+	Response    interface{} // data in SuccessResponse
+	Errors      []error     // errors that may be returned, registered in HttpHandlerContext.E
+}
+
+// Registry stores routes and generates OpenAPI document from them
+type Registry struct {
+	info    Info
+	servers []Server
+	mu      sync.RWMutex
+	routes  []Route
+}
+
+// NewRegistry creates route registry
+func NewRegistry(info Info, servers ...Server) *Registry {
+	return &Registry{
+		info:    info,
+		servers: servers,
+	}
+}
+
+// Register adds route into registry. The route can be mounted to router later using Routes().
+func (reg *Registry) Register(route Route) *Registry {
+	route.Method = strings.ToUpper(route.Method)
+
+	reg.mu.Lock()
+	reg.routes = append(reg.routes, route)
+	reg.mu.Unlock()
+
+	return reg
+}
+
+// Routes returns all registered routes
+func (reg *Registry) Routes() []Route {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	return append([]Route(nil), reg.routes...)
+}
+
+// Document generates OpenAPI document from registered routes
+func (reg *Registry) Document() *Document {
+	g := newSchemaGenerator()
+	addEnvelopeSchemas(g)
+
+	doc := &Document{
+		OpenAPI: openAPIVersion,
+		Info:    reg.info,
+		Servers: reg.servers,
+		Paths:   make(map[string]PathItem),
+		Components: Components{
+			Schemas:    g.schemas,
+			Parameters: make(map[string]*Parameter),
+		},
+	}
+
+	var headerRefs []*Parameter
+	for _, h := range ktbsHeaders {
+		doc.Components.Parameters[h.name] = &Parameter{
+			Name:        h.name,
+			In:          "header",
+			Description: h.description,
+			Required:    h.required,
+			Schema:      &Schema{Type: "string", Format: h.format},
+		}
+		headerRefs = append(headerRefs, &Parameter{Ref: "#/components/parameters/" + h.name})
+	}
+
+	for _, route := range reg.Routes() {
+		path := pathParamPattern.ReplaceAllString(route.Path, "{$1}")
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(PathItem)
+		}
+
+		op := &Operation{
+			Summary:     route.Summary,
+			Description: route.Description,
+			OperationID: operationID(route.Method, path),
+			Tags:        route.Tags,
+			Parameters:  append([]*Parameter(nil), headerRefs...),
+			Responses:   make(map[string]Response),
+		}
+
+		for _, m := range pathParamPattern.FindAllStringSubmatch(route.Path, -1) {
+			op.Parameters = append(op.Parameters, &Parameter{
+				Name:     m[1],
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: "string"},
+			})
+		}
+
+		op.Parameters = append(op.Parameters, queryParameters(g, route.Query)...)
+
+		if route.Request != nil {
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content: map[string]MediaType{
+					"application/json": {Schema: g.schemaOf(reflect.TypeOf(route.Request))},
+				},
+			}
+		}
+
+		op.Responses[fmt.Sprint(http.StatusOK)] = successResponse(g, route.Response)
+		for status, resp := range errorResponses(route) {
+			op.Responses[status] = resp
+		}
+
+		doc.Paths[path][strings.ToLower(route.Method)] = op
+	}
+
+	return doc
+}
+
+// ServeHTTP serves OpenAPI document in json format
+func (reg *Registry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	res, err := json.Marshal(reg.Document())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("Failed to marshal openapi document"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(res)
+}
+
+func addEnvelopeSchemas(g *schemaGenerator) {
+	g.schemaOf(reflect.TypeOf(structs.ErrorResponse{}))
+	g.schemaOf(reflect.TypeOf(structs.SuccessResponse{}))
+}
+
+func successResponse(g *schemaGenerator, data interface{}) Response {
+	envelope := &Schema{Ref: schemaRef("SuccessResponse")}
+
+	if data != nil {
+		// CustomWriter always writes data as array
+		t := reflect.TypeOf(data)
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+
+		var items *Schema
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			items = g.schemaOf(t.Elem())
+		} else {
+			items = g.schemaOf(t)
+		}
+
+		envelope = &Schema{
+			AllOf: []*Schema{
+				envelope,
+				{
+					Type: "object",
+					Properties: map[string]*Schema{
+						"data": {Type: "array", Items: items},
+					},
+				},
+			},
+		}
+	}
+
+	return Response{
+		Description: "Success",
+		Content: map[string]MediaType{
+			"application/json": {Schema: envelope},
+		},
+	}
+}
+
+func errorResponses(route Route) map[string]Response {
+	byStatus := make(map[int][]*structs.ErrorResponse)
+	for _, err := range route.Errors {
+		errResp := phttp.LookupError(route.Handler.C.E, err)
+		if errResp == nil && !errors.As(err, &errResp) {
+			errResp = structs.ErrUnknown
+		}
+
+		byStatus[errResp.HttpStatus] = append(byStatus[errResp.HttpStatus], errResp)
+	}
+
+	responses := make(map[string]Response)
+	for status, errResps := range byStatus {
+		sort.Slice(errResps, func(i, j int) bool {
+			return errResps[i].ResponseCode < errResps[j].ResponseCode
+		})
+
+		var codes []string
+		examples := make(map[string]Example)
+		for _, errResp := range errResps {
+			example := *errResp
+			example.Meta = route.Handler.C.M
+
+			codes = append(codes, errResp.ResponseCode)
+			examples[errResp.ResponseCode] = Example{
+				Summary: errResp.ResponseDesc.EN,
+				Value:   example,
+			}
+		}
+
+		responses[fmt.Sprint(status)] = Response{
+			Description: fmt.Sprintf("%s. Response code: %s", http.StatusText(status), strings.Join(codes, ", ")),
+			Content: map[string]MediaType{
+				"application/json": {
+					Schema:   &Schema{Ref: schemaRef("ErrorResponse")},
+					Examples: examples,
+				},
+			},
+		}
+	}
+
+	return responses
+}
+
+func queryParameters(g *schemaGenerator, query interface{}) (params []*Parameter) {
+	if query == nil {
+		return
+	}
+
+	t := reflect.TypeOf(query)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		name := strings.Split(f.Tag.Get("query"), ",")[0]
+		if name == "-" {
+			continue
+		}
+
+		if name == "" {
+			name, _ = fieldName(f)
+		}
+
+		if name == "" || name == "-" {
+			name = f.Name
+		}
+
+		params = append(params, &Parameter{
+			Name:        name,
+			In:          "query",
+			Description: f.Tag.Get("description"),
+			Required:    strings.Contains(f.Tag.Get("valid"), "required"),
+			Schema:      g.schemaOf(f.Type),
+		})
+	}
+
+	return
+}
+
+func operationID(method, path string) string {
+	id := strings.ToLower(method)
+	for _, part := range strings.Split(path, "/") {
+		part = strings.Trim(part, "{}")
+		if part == "" {
+			continue
+		}
+
+		id += strings.ToUpper(part[:1]) + part[1:]
+	}
+
+	return id
+}
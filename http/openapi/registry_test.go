@@ -0,0 +1,127 @@
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	phttp "github.com/kitabisa/perkakas/v2/http"
+	"github.com/kitabisa/perkakas/v2/structs"
+	"github.com/stretchr/testify/assert"
+)
+
+type campaign struct {
+	ID        int64     `json:"id"`
+	Title     string    `json:"title" valid:"required"`
+	Tags      []string  `json:"tags,omitempty"`
+	Owner     *user     `json:"owner"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type user struct {
+	Name    string `json:"name"`
+	private string
+}
+
+type campaignQuery struct {
+	Limit int    `query:"limit"`
+	Order string `json:"order"`
+}
+
+var errCampaignNotFound = errors.New("campaign not found")
+
+var ErrCampaignNotFound = &structs.ErrorResponse{
+	Response: structs.Response{
+		ResponseCode: "10001",
+		ResponseDesc: structs.ResponseDesc{
+			ID: "Campaign tidak ditemukan",
+			EN: "Campaign not found",
+		},
+	},
+	HttpStatus: http.StatusNotFound,
+}
+
+func newTestRegistry() *Registry {
+	hctx := phttp.NewContextHandler(structs.Meta{})
+	hctx.AddError(errCampaignNotFound, ErrCampaignNotFound)
+
+	handler := phttp.NewHttpHandler(hctx)(func(w http.ResponseWriter, r *http.Request) (interface{}, *string, error) {
+		return nil, nil, nil
+	})
+
+	reg := NewRegistry(Info{Title: "Campaign", Version: "1.0.0"})
+	reg.Register(Route{
+		Method:   http.MethodGet,
+		Path:     "/campaigns/{id:[0-9]+}",
+		Handler:  handler,
+		Response: campaign{},
+		Errors:   []error{errCampaignNotFound, structs.ErrUnauthorized},
+	})
+	reg.Register(Route{
+		Method:   http.MethodPost,
+		Path:     "/campaigns",
+		Handler:  handler,
+		Query:    campaignQuery{},
+		Request:  campaign{},
+		Response: []campaign{},
+		Errors:   []error{structs.ErrInvalidHeader, structs.ErrInvalidHeaderSignature},
+	})
+
+	return reg
+}
+
+func TestDocument(t *testing.T) {
+	doc := newTestRegistry().Document()
+
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+	assert.Contains(t, doc.Components.Schemas, "SuccessResponse")
+	assert.Contains(t, doc.Components.Schemas, "ErrorResponse")
+	assert.Contains(t, doc.Components.Parameters, "X-Ktbs-Request-ID")
+
+	schema := doc.Components.Schemas["campaign"]
+	if assert.NotNil(t, schema) {
+		assert.Equal(t, []string{"title"}, schema.Required)
+		assert.Equal(t, "#/components/schemas/user", schema.Properties["owner"].Ref)
+		assert.Equal(t, "date-time", schema.Properties["created_at"].Format)
+		assert.NotContains(t, doc.Components.Schemas["user"].Properties, "private")
+	}
+
+	get := doc.Paths["/campaigns/{id}"]["get"]
+	if assert.NotNil(t, get) {
+		assert.Equal(t, "getCampaignsId", get.OperationID)
+		assert.Contains(t, get.Responses, "200")
+		assert.Contains(t, get.Responses, "401")
+		assert.Contains(t, get.Responses["404"].Description, "10001")
+
+		last := get.Parameters[len(get.Parameters)-1]
+		assert.Equal(t, "id", last.Name)
+		assert.Equal(t, "path", last.In)
+	}
+
+	post := doc.Paths["/campaigns"]["post"]
+	if assert.NotNil(t, post) {
+		assert.NotNil(t, post.RequestBody)
+		assert.Len(t, post.Responses["400"].Content["application/json"].Examples, 2)
+
+		data := post.Responses["200"].Content["application/json"].Schema.AllOf[1].Properties["data"]
+		assert.Equal(t, "#/components/schemas/campaign", data.Items.Ref)
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	reg := newTestRegistry()
+
+	rec := httptest.NewRecorder()
+	reg.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var doc map[string]interface{}
+	err := json.Unmarshal(rec.Body.Bytes(), &doc)
+	assert.NoError(t, err)
+	assert.Equal(t, "3.0.3", doc["openapi"])
+}
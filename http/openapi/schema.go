@@ -0,0 +1,159 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaGenerator generates schema from go type using reflection. Named struct is registered
+// into components schemas and referred using $ref.
+type schemaGenerator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{
+		schemas: make(map[string]*Schema),
+		names:   make(map[reflect.Type]string),
+	}
+}
+
+func (g *schemaGenerator) schemaOf(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		s := g.schemaOf(t.Elem())
+		if s.Ref != "" {
+			return s
+		}
+
+		s.Nullable = true
+		return s
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+
+		return &Schema{Type: "array", Items: g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t == timeType {
+			return &Schema{Type: "string", Format: "date-time"}
+		}
+
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+
+		return &Schema{Ref: g.register(t)}
+	}
+
+	// interface and other kinds can be any value
+	return &Schema{}
+}
+
+func (g *schemaGenerator) register(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return schemaRef(name)
+	}
+
+	name := t.Name()
+	if _, exist := g.schemas[name]; exist {
+		// same struct name from different package
+		pkg := t.PkgPath()
+		name = pkg[strings.LastIndex(pkg, "/")+1:] + "." + name
+	}
+
+	// register before generating properties to support recursive struct
+	g.names[t] = name
+	g.schemas[name] = &Schema{}
+	*g.schemas[name] = *g.structSchema(t)
+
+	return schemaRef(name)
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) *Schema {
+	s := &Schema{
+		Type:       "object",
+		Properties: make(map[string]*Schema),
+	}
+
+	g.addProperties(s, t)
+	return s
+}
+
+func (g *schemaGenerator) addProperties(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		name, omit := fieldName(f)
+		if omit {
+			continue
+		}
+
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		// embedded struct without json name is flattened like encoding/json does
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			g.addProperties(s, ft)
+			continue
+		}
+
+		if f.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		prop := g.schemaOf(f.Type)
+		if desc := f.Tag.Get("description"); desc != "" && prop.Ref == "" {
+			prop.Description = desc
+		}
+
+		s.Properties[name] = prop
+
+		if strings.Contains(f.Tag.Get("valid"), "required") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// fieldName returns json name of the field. omit is true if the field is ignored by encoding/json.
+func fieldName(f reflect.StructField) (name string, omit bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", true
+	}
+
+	return strings.Split(tag, ",")[0], false
+}
+
+func schemaRef(name string) string {
+	return "#/components/schemas/" + name
+}
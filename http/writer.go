@@ -21,6 +21,11 @@ func NewContextHandler(meta structs.Meta) HttpHandlerContext {
 		structs.ErrUnauthorized:           structs.ErrUnauthorized,
 		structs.ErrInvalidHeaderSignature: structs.ErrInvalidHeaderSignature,
 		structs.ErrInvalidHeaderTime:      structs.ErrInvalidHeaderTime,
+		structs.ErrOriginNotAllowed:       structs.ErrOriginNotAllowed,
+		structs.ErrPayloadTooLarge:        structs.ErrPayloadTooLarge,
+		structs.ErrUnsupportedMediaType:   structs.ErrUnsupportedMediaType,
+		structs.ErrForbidden:              structs.ErrForbidden,
+		structs.ErrRequestReplayed:        structs.ErrRequestReplayed,
 	}
 
 	return HttpHandlerContext{
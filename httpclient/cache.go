@@ -0,0 +1,444 @@
+package httpclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	zlog "github.com/rs/zerolog/log"
+)
+
+// CacheStatusHeader is response header set by the cache layer. The value is HIT, REVALIDATED or MISS.
+const CacheStatusHeader = "X-Httpclient-Cache"
+
+const (
+	cacheHit         = "HIT"
+	cacheRevalidated = "REVALIDATED"
+	cacheMiss        = "MISS"
+)
+
+// cacheableStatuses is response status cacheable by default, see RFC 7231 section 6.1
+var cacheableStatuses = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusNotFound:             true,
+	http.StatusGone:                 true,
+}
+
+// CacheConf is response cache configuration
+type CacheConf struct {
+	// Store is cache store, e.g. NewMemoryCacheStore or NewRedisCacheStore
+	Store CacheStore
+	// StaleTTL is how long expired response with ETag or Last-Modified is kept for revalidation. Default 1 hour.
+	StaleTTL time.Duration
+}
+
+type cachedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	// StoredAt is the time the response is generated by the origin, used to calculate its age
+	StoredAt time.Time `json:"stored_at"`
+}
+
+type responseCache struct {
+	store    CacheStore
+	staleTTL time.Duration
+	flight   flightGroup
+}
+
+func newResponseCache(conf CacheConf) *responseCache {
+	if conf.StaleTTL <= 0 {
+		conf.StaleTTL = time.Hour
+	}
+
+	return &responseCache{
+		store:    conf.Store,
+		staleTTL: conf.StaleTTL,
+	}
+}
+
+// do serves GET request from cache, or sends it and stores the response. Concurrent identical requests are collapsed
+// into one. Successful request with unsafe method invalidates the cached response of its url.
+func (c *responseCache) do(req *http.Request, send func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		resp, err := send(req)
+		if err == nil && !isSafeMethod(req.Method) && resp.StatusCode < http.StatusBadRequest {
+			c.invalidate(req)
+		}
+
+		return resp, err
+	}
+
+	// conditional request made by the caller is handled by the caller
+	if req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != "" {
+		return send(req)
+	}
+
+	if parseCacheControl(req.Header).has("no-store") {
+		return send(req)
+	}
+
+	key := c.variantKey(req, c.loadVary(req))
+
+	// requests of different user must not share the response, unless it is stored as shareable response
+	flightKey := key + "\n" + req.Header.Get("Authorization")
+	entry, status, err := c.flight.do(flightKey, func() (*cachedResponse, string, error) {
+		return c.fetch(req, key, send)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entry.response(req, status), nil
+}
+
+func (c *responseCache) fetch(req *http.Request, key string, send func(*http.Request) (*http.Response, error)) (*cachedResponse, string, error) {
+	reqCC := parseCacheControl(req.Header)
+	entry := c.load(key)
+
+	if entry != nil {
+		age := time.Since(entry.StoredAt)
+		maxAge, hasMaxAge := reqCC.duration("max-age")
+		if age < entry.freshness() && !reqCC.has("no-cache") && (!hasMaxAge || age <= maxAge) {
+			return entry, cacheHit, nil
+		}
+
+		// revalidate using clone, so the caller request is not modified
+		etag, lastModified := entry.Header.Get("ETag"), entry.Header.Get("Last-Modified")
+		if etag != "" || lastModified != "" {
+			req = req.Clone(req.Context())
+			if req.Header == nil {
+				req.Header = make(http.Header)
+			}
+		}
+
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+
+		if lastModified != "" {
+			req.Header.Set("If-Modified-Since", lastModified)
+		}
+	}
+
+	resp, err := send(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		for _, h := range []string{"Cache-Control", "Date", "ETag", "Expires", "Last-Modified", "Vary"} {
+			if v := resp.Header.Get(h); v != "" {
+				entry.Header.Set(h, v)
+			}
+		}
+
+		entry.StoredAt = responseTime(resp.Header)
+		c.save(req, entry)
+
+		return entry, cacheRevalidated, nil
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	entry = &cachedResponse{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+		StoredAt:   responseTime(resp.Header),
+	}
+
+	if isCacheable(req, resp) {
+		c.save(req, entry)
+	}
+
+	return entry, cacheMiss, nil
+}
+
+func (c *responseCache) load(key string) *cachedResponse {
+	value, found, err := c.store.Get(key)
+	if err != nil {
+		zlog.Warn().Err(err).Str("key", key).Msg("http client failed to get cached response")
+		return nil
+	}
+
+	if !found {
+		return nil
+	}
+
+	entry := new(cachedResponse)
+	if err = json.Unmarshal(value, entry); err != nil {
+		zlog.Warn().Err(err).Str("key", key).Msg("http client failed to decode cached response")
+		return nil
+	}
+
+	return entry
+}
+
+func (c *responseCache) save(req *http.Request, entry *cachedResponse) {
+	vary := varyHeaders(entry.Header)
+	if len(vary) == 1 && vary[0] == "*" {
+		return
+	}
+
+	ttl := entry.freshness() - time.Since(entry.StoredAt)
+	if entry.Header.Get("ETag") != "" || entry.Header.Get("Last-Modified") != "" {
+		if ttl < 0 {
+			ttl = 0
+		}
+
+		ttl += c.staleTTL
+	}
+
+	if ttl <= 0 {
+		return
+	}
+
+	value, err := json.Marshal(entry)
+	if err != nil {
+		zlog.Warn().Err(err).Msg("http client failed to encode cached response")
+		return
+	}
+
+	key := c.variantKey(req, vary)
+	if err = c.store.Set(varyKey(req), []byte(strings.Join(vary, ",")), ttl); err == nil {
+		err = c.store.Set(key, value, ttl)
+	}
+
+	if err != nil {
+		zlog.Warn().Err(err).Str("key", key).Msg("http client failed to store cached response")
+	}
+}
+
+// invalidate removes cached response of the request url
+func (c *responseCache) invalidate(req *http.Request) {
+	keys := []string{varyKey(req), baseKey(req), c.variantKey(req, c.loadVary(req))}
+	for _, key := range keys {
+		if err := c.store.Delete(key); err != nil {
+			zlog.Warn().Err(err).Str("key", key).Msg("http client failed to invalidate cached response")
+		}
+	}
+}
+
+// loadVary returns the Vary headers of the cached response of the request url
+func (c *responseCache) loadVary(req *http.Request) []string {
+	value, found, err := c.store.Get(varyKey(req))
+	if err != nil || !found || len(value) == 0 {
+		return nil
+	}
+
+	return strings.Split(string(value), ",")
+}
+
+// variantKey returns cache key of the request, including value of its Vary headers
+func (c *responseCache) variantKey(req *http.Request, vary []string) string {
+	key := baseKey(req)
+	for _, h := range vary {
+		key += fmt.Sprintf("\n%s:%s", h, strings.Join(req.Header[h], ","))
+	}
+
+	return key
+}
+
+func baseKey(req *http.Request) string {
+	return "httpclient:" + req.URL.String()
+}
+
+func varyKey(req *http.Request) string {
+	return "httpclient:vary:" + req.URL.String()
+}
+
+// varyHeaders returns sorted canonical header names of the Vary header
+func varyHeaders(header http.Header) []string {
+	var vary []string
+	for _, v := range header["Vary"] {
+		for _, h := range strings.Split(v, ",") {
+			h = strings.TrimSpace(h)
+			if h == "*" {
+				return []string{"*"}
+			}
+
+			if h != "" {
+				vary = append(vary, http.CanonicalHeaderKey(h))
+			}
+		}
+	}
+
+	sort.Strings(vary)
+	return vary
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+
+	return false
+}
+
+// isCacheable checks whether the response can be stored. Response of request with Authorization header is stored
+// only when it is explicitly allowed, see RFC 7234 section 3.2.
+func isCacheable(req *http.Request, resp *http.Response) bool {
+	if !cacheableStatuses[resp.StatusCode] {
+		return false
+	}
+
+	reqCC := parseCacheControl(req.Header)
+	respCC := parseCacheControl(resp.Header)
+	if reqCC.has("no-store") || respCC.has("no-store") {
+		return false
+	}
+
+	if req.Header.Get("Authorization") != "" && !respCC.has("public") && !respCC.has("s-maxage") && !respCC.has("must-revalidate") {
+		return false
+	}
+
+	return true
+}
+
+// responseTime returns the time the response is generated, using Age header if any
+func responseTime(header http.Header) time.Time {
+	now := time.Now()
+	if age, err := strconv.Atoi(header.Get("Age")); err == nil && age > 0 {
+		return now.Add(-time.Duration(age) * time.Second)
+	}
+
+	return now
+}
+
+// freshness returns freshness lifetime of the response from Cache-Control max-age or Expires header
+func (r *cachedResponse) freshness() time.Duration {
+	cc := parseCacheControl(r.Header)
+	if cc.has("no-cache") {
+		return 0
+	}
+
+	if maxAge, ok := cc.duration("max-age"); ok {
+		return maxAge
+	}
+
+	expires, err := http.ParseTime(r.Header.Get("Expires"))
+	if err != nil {
+		return 0
+	}
+
+	date, err := http.ParseTime(r.Header.Get("Date"))
+	if err != nil {
+		date = r.StoredAt
+	}
+
+	return expires.Sub(date)
+}
+
+func (r *cachedResponse) response(req *http.Request, status string) *http.Response {
+	header := r.Header.Clone()
+	header.Set(CacheStatusHeader, status)
+
+	if status != cacheMiss {
+		header.Set("Age", strconv.Itoa(int(time.Since(r.StoredAt).Seconds())))
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+type cacheControl map[string]string
+
+func parseCacheControl(header http.Header) cacheControl {
+	cc := make(cacheControl)
+	for _, v := range header["Cache-Control"] {
+		for _, directive := range strings.Split(v, ",") {
+			directive = strings.TrimSpace(directive)
+			if directive == "" {
+				continue
+			}
+
+			name, value := directive, ""
+			if i := strings.Index(directive, "="); i >= 0 {
+				name, value = directive[:i], strings.Trim(directive[i+1:], `"`)
+			}
+
+			cc[strings.ToLower(name)] = value
+		}
+	}
+
+	return cc
+}
+
+func (cc cacheControl) has(directive string) bool {
+	_, ok := cc[directive]
+	return ok
+}
+
+func (cc cacheControl) duration(directive string) (time.Duration, bool) {
+	seconds, err := strconv.Atoi(cc[directive])
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+
+	return time.Duration(seconds) * time.Second, true
+}
+
+// flightGroup collapses concurrent calls with the same key into one
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	wg     sync.WaitGroup
+	entry  *cachedResponse
+	status string
+	err    error
+}
+
+func (g *flightGroup) do(key string, fn func() (*cachedResponse, string, error)) (*cachedResponse, string, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		call.wg.Wait()
+		return call.entry, call.status, call.err
+	}
+
+	call := new(flightCall)
+	call.wg.Add(1)
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	call.entry, call.status, call.err = fn()
+	call.wg.Done()
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+
+	return call.entry, call.status, call.err
+}
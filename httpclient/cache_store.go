@@ -0,0 +1,153 @@
+package httpclient
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// CacheStore stores cached response. It must be safe for concurrent use.
+type CacheStore interface {
+	// Get returns the value of key. Found is false when the key does not exist or expired.
+	Get(key string) (value []byte, found bool, err error)
+	// Set stores value of key with ttl
+	Set(key string, value []byte, ttl time.Duration) error
+	// Delete removes key
+	Delete(key string) error
+}
+
+// MemoryCacheStore is in-memory LRU cache store
+type MemoryCacheStore struct {
+	maxEntries int
+
+	mu      sync.Mutex
+	ll      *list.List
+	entries map[string]*list.Element
+}
+
+type memoryCacheEntry struct {
+	key       string
+	value     []byte
+	expiredAt time.Time
+}
+
+var _ CacheStore = (*MemoryCacheStore)(nil)
+
+// NewMemoryCacheStore creates in-memory LRU cache store. The least recently used entry is evicted
+// when the number of entries exceeds maxEntries. maxEntries less than or equal 0 means no limit.
+func NewMemoryCacheStore(maxEntries int) *MemoryCacheStore {
+	return &MemoryCacheStore{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+func (s *MemoryCacheStore) Get(key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	entry := el.Value.(*memoryCacheEntry)
+	if time.Now().After(entry.expiredAt) {
+		s.removeElement(el)
+		return nil, false, nil
+	}
+
+	s.ll.MoveToFront(el)
+	return entry.value, true, nil
+}
+
+func (s *MemoryCacheStore) Set(key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiredAt := time.Now().Add(ttl)
+	if el, ok := s.entries[key]; ok {
+		entry := el.Value.(*memoryCacheEntry)
+		entry.value = value
+		entry.expiredAt = expiredAt
+		s.ll.MoveToFront(el)
+		return nil
+	}
+
+	s.entries[key] = s.ll.PushFront(&memoryCacheEntry{
+		key:       key,
+		value:     value,
+		expiredAt: expiredAt,
+	})
+
+	if s.maxEntries > 0 && s.ll.Len() > s.maxEntries {
+		s.removeElement(s.ll.Back())
+	}
+
+	return nil
+}
+
+func (s *MemoryCacheStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.entries[key]; ok {
+		s.removeElement(el)
+	}
+
+	return nil
+}
+
+// Len returns number of entries, including the expired one which is not evicted yet
+func (s *MemoryCacheStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.ll.Len()
+}
+
+// removeElement must be called with lock held
+func (s *MemoryCacheStore) removeElement(el *list.Element) {
+	s.ll.Remove(el)
+	delete(s.entries, el.Value.(*memoryCacheEntry).key)
+}
+
+// RedisCacheStore is redis cache store, so the cache can be shared between instances
+type RedisCacheStore struct {
+	client redis.Cmdable
+	prefix string
+}
+
+var _ CacheStore = (*RedisCacheStore)(nil)
+
+// NewRedisCacheStore creates redis cache store. Prefix is prepended to every key, e.g. "campaign-service:httpcache:".
+func NewRedisCacheStore(client redis.Cmdable, prefix string) *RedisCacheStore {
+	return &RedisCacheStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+func (s *RedisCacheStore) Get(key string) ([]byte, bool, error) {
+	value, err := s.client.Get(s.prefix + key).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	return value, true, nil
+}
+
+func (s *RedisCacheStore) Set(key string, value []byte, ttl time.Duration) error {
+	return s.client.Set(s.prefix+key, value, ttl).Err()
+}
+
+func (s *RedisCacheStore) Delete(key string) error {
+	return s.client.Del(s.prefix + key).Err()
+}
@@ -0,0 +1,241 @@
+package httpclient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kitabisa/perkakas/v2/internal/redistest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCacheTestClient() *HttpClient {
+	conf := getDefaultHttpClientConf()
+	conf.Cache = &CacheConf{Store: NewMemoryCacheStore(100)}
+
+	return NewHttpClient(conf)
+}
+
+func getBody(t *testing.T, h *HttpClient, url string, header http.Header) (*http.Response, string) {
+	resp, err := h.Get(url, header)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return resp, string(body)
+}
+
+func TestCacheMaxAge(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Write([]byte("config"))
+	}))
+	defer srv.Close()
+
+	h := newCacheTestClient()
+
+	resp, body := getBody(t, h, srv.URL, nil)
+	assert.Equal(t, "config", body)
+	assert.Equal(t, cacheMiss, resp.Header.Get(CacheStatusHeader))
+
+	resp, body = getBody(t, h, srv.URL, nil)
+	assert.Equal(t, "config", body)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, cacheHit, resp.Header.Get(CacheStatusHeader))
+	assert.Equal(t, int32(1), hits)
+
+	// request no-cache forces revalidation, response without validator is fetched again
+	header := http.Header{}
+	header.Set("Cache-Control", "no-cache")
+	getBody(t, h, srv.URL, header)
+	assert.Equal(t, int32(2), hits)
+}
+
+func TestCacheNoStore(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "no-store, max-age=60")
+	}))
+	defer srv.Close()
+
+	h := newCacheTestClient()
+	getBody(t, h, srv.URL, nil)
+	getBody(t, h, srv.URL, nil)
+	assert.Equal(t, int32(2), hits)
+}
+
+func TestCacheETagRevalidation(t *testing.T) {
+	var hits, notModified int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("ETag", `"v1"`)
+
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Write([]byte("reference"))
+	}))
+	defer srv.Close()
+
+	h := newCacheTestClient()
+	getBody(t, h, srv.URL, nil)
+
+	resp, body := getBody(t, h, srv.URL, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "reference", body)
+	assert.Equal(t, cacheRevalidated, resp.Header.Get(CacheStatusHeader))
+	assert.Equal(t, int32(2), hits)
+	assert.Equal(t, int32(1), notModified)
+}
+
+func TestCacheVary(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "Accept-Language")
+		w.Write([]byte(r.Header.Get("Accept-Language")))
+	}))
+	defer srv.Close()
+
+	h := newCacheTestClient()
+	id := http.Header{"Accept-Language": {"id"}}
+	en := http.Header{"Accept-Language": {"en"}}
+
+	_, body := getBody(t, h, srv.URL, id)
+	assert.Equal(t, "id", body)
+
+	_, body = getBody(t, h, srv.URL, en)
+	assert.Equal(t, "en", body)
+
+	resp, body := getBody(t, h, srv.URL, en)
+	assert.Equal(t, "en", body)
+	assert.Equal(t, cacheHit, resp.Header.Get(CacheStatusHeader))
+	assert.Equal(t, int32(2), hits)
+}
+
+func TestCacheAuthorization(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", r.URL.Query().Get("cc"))
+	}))
+	defer srv.Close()
+
+	h := newCacheTestClient()
+	header := http.Header{"Authorization": {"Bearer token"}}
+
+	getBody(t, h, srv.URL+"?cc=max-age=60", header)
+	getBody(t, h, srv.URL+"?cc=max-age=60", header)
+	assert.Equal(t, int32(2), hits)
+
+	getBody(t, h, srv.URL+"?cc=public,max-age=60", header)
+	getBody(t, h, srv.URL+"?cc=public,max-age=60", header)
+	assert.Equal(t, int32(3), hits)
+}
+
+func TestCacheInvalidate(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			atomic.AddInt32(&hits, 1)
+			w.Header().Set("Cache-Control", "max-age=60")
+		}
+	}))
+	defer srv.Close()
+
+	h := newCacheTestClient()
+	getBody(t, h, srv.URL, nil)
+	getBody(t, h, srv.URL, nil)
+	assert.Equal(t, int32(1), hits)
+
+	resp, err := h.Put(srv.URL, nil, nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	getBody(t, h, srv.URL, nil)
+	assert.Equal(t, int32(2), hits)
+}
+
+func TestCacheSingleFlight(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("slow"))
+	}))
+	defer srv.Close()
+
+	h := newCacheTestClient()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, body := getBody(t, h, srv.URL, nil)
+			assert.Equal(t, "slow", body)
+		}()
+	}
+
+	wg.Wait()
+	assert.Equal(t, int32(1), hits)
+}
+
+func TestMemoryCacheStore(t *testing.T) {
+	s := NewMemoryCacheStore(2)
+
+	require.NoError(t, s.Set("a", []byte("1"), time.Minute))
+	require.NoError(t, s.Set("b", []byte("2"), time.Minute))
+
+	// a becomes the most recently used, so b is evicted
+	_, found, _ := s.Get("a")
+	assert.True(t, found)
+
+	require.NoError(t, s.Set("c", []byte("3"), time.Minute))
+	_, found, _ = s.Get("b")
+	assert.False(t, found)
+	assert.Equal(t, 2, s.Len())
+
+	require.NoError(t, s.Set("d", []byte("4"), -time.Second))
+	_, found, _ = s.Get("d")
+	assert.False(t, found)
+
+	require.NoError(t, s.Delete("a"))
+	_, found, _ = s.Get("a")
+	assert.False(t, found)
+}
+
+func TestRedisCacheStore(t *testing.T) {
+	client := redistest.New()
+	s := NewRedisCacheStore(client, "svc:")
+
+	require.NoError(t, s.Set("a", []byte("1"), time.Minute))
+	stored, _ := client.Value("svc:a")
+	assert.Equal(t, "1", stored)
+	assert.Equal(t, time.Minute, client.Expiration("svc:a"))
+
+	value, found, err := s.Get("a")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("1"), value)
+
+	require.NoError(t, s.Delete("a"))
+	_, found, err = s.Get("a")
+	require.NoError(t, err)
+	assert.False(t, found)
+}
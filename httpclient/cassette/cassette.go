@@ -0,0 +1,429 @@
+// Package cassette provides record/replay transport for httpclient. Real interactions are recorded into cassette file
+// on the first run, then replayed offline afterwards.
+
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/gojektech/heimdall"
+)
+
+// RecordEnv is environment variable to re-record every cassette, e.g. RECORD_CASSETTE=1 go test ./...
+const RecordEnv = "RECORD_CASSETTE"
+
+// Redacted replaces redacted header, body field and query param value
+const Redacted = "[REDACTED]"
+
+// ErrInteractionNotFound is returned in replay mode when no recorded interaction matches the request
+var ErrInteractionNotFound = errors.New("cassette: interaction not found")
+
+// Mode is recorder mode
+type Mode int
+
+const (
+	// ModeAuto replays when the cassette file exists, or records when it does not exist or RECORD_CASSETTE is set
+	ModeAuto Mode = iota
+	// ModeRecord always sends real request and records it, overwriting the cassette file
+	ModeRecord
+	// ModeReplay always replays from cassette file, request is never sent
+	ModeReplay
+)
+
+// DefaultRedactHeaders is header redacted by default
+var DefaultRedactHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Api-Key",
+	"X-Ktbs-Signature",
+}
+
+// Request is recorded request
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body"`
+}
+
+// Response is recorded response
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+}
+
+// Interaction is recorded request and its response
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Cassette is the content of cassette file
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Matcher checks whether the request matches the recorded request. Both are redacted.
+type Matcher func(req Request, recorded Request) bool
+
+// MatchMethod matches request method
+func MatchMethod(req Request, recorded Request) bool {
+	return req.Method == recorded.Method
+}
+
+// MatchURL matches request url, including query params
+func MatchURL(req Request, recorded Request) bool {
+	return req.URL == recorded.URL
+}
+
+// MatchBody matches request body. Json body is compared semantically, so key order doesn't matter.
+func MatchBody(req Request, recorded Request) bool {
+	if req.Body == recorded.Body {
+		return true
+	}
+
+	var a, b interface{}
+	if json.Unmarshal([]byte(req.Body), &a) != nil || json.Unmarshal([]byte(recorded.Body), &b) != nil {
+		return false
+	}
+
+	aj, _ := json.Marshal(a)
+	bj, _ := json.Marshal(b)
+	return bytes.Equal(aj, bj)
+}
+
+// MatchHeaders creates matcher matching the given request headers
+func MatchHeaders(names ...string) Matcher {
+	return func(req Request, recorded Request) bool {
+		for _, name := range names {
+			if req.Header.Get(name) != recorded.Header.Get(name) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// Match combines matchers, request matches when all of them match
+func Match(matchers ...Matcher) Matcher {
+	return func(req Request, recorded Request) bool {
+		for _, m := range matchers {
+			if !m(req, recorded) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// DefaultMatcher matches method, url and body
+var DefaultMatcher = Match(MatchMethod, MatchURL, MatchBody)
+
+// Conf is recorder configuration
+type Conf struct {
+	// Path is cassette file path, e.g. testdata/cassettes/get_campaign.json
+	Path string
+	Mode Mode
+	// Doer sends the real request when recording. Default is http.Client.
+	Doer heimdall.Doer
+	// Matcher matches request with recorded request. Default is DefaultMatcher.
+	Matcher Matcher
+	// RedactHeaders is request and response header to be redacted. Default is DefaultRedactHeaders.
+	RedactHeaders []string
+	// RedactFields is json body field, form field and query param to be redacted, e.g. password or token.
+	// Field name is case insensitive, json field is redacted in any depth.
+	RedactFields []string
+}
+
+// Recorder is heimdall.Doer recording or replaying interactions. Call Stop after the test to write the cassette file.
+type Recorder struct {
+	conf      Conf
+	recording bool
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+var _ heimdall.Doer = (*Recorder)(nil)
+
+// NewRecorder creates recorder. Use it as custom client of httpclient:
+//
+//	rec, err := cassette.NewRecorder(cassette.Conf{Path: "testdata/cassettes/get_campaign.json"})
+//	defer rec.Stop()
+//
+//	client := httpclient.NewHttpWithCustomClient(conf, rec)
+func NewRecorder(conf Conf) (*Recorder, error) {
+	if conf.Doer == nil {
+		conf.Doer = &http.Client{}
+	}
+
+	if conf.Matcher == nil {
+		conf.Matcher = DefaultMatcher
+	}
+
+	if conf.RedactHeaders == nil {
+		conf.RedactHeaders = DefaultRedactHeaders
+	}
+
+	r := &Recorder{conf: conf}
+
+	switch conf.Mode {
+	case ModeRecord:
+		r.recording = true
+	case ModeReplay:
+	default:
+		_, err := os.Stat(conf.Path)
+		r.recording = os.Getenv(RecordEnv) != "" || os.IsNotExist(err)
+	}
+
+	if r.recording {
+		return r, nil
+	}
+
+	b, err := ioutil.ReadFile(conf.Path)
+	if err != nil {
+		return nil, fmt.Errorf("cassette: failed to read cassette file: %w", err)
+	}
+
+	if err = json.Unmarshal(b, &r.cassette); err != nil {
+		return nil, fmt.Errorf("cassette: failed to decode cassette file: %w", err)
+	}
+
+	r.used = make([]bool, len(r.cassette.Interactions))
+	return r, nil
+}
+
+// IsRecording reports whether the recorder sends real request
+func (r *Recorder) IsRecording() bool {
+	return r.recording
+}
+
+// Do records or replays the request
+func (r *Recorder) Do(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	recReq := r.redactRequest(Request{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Header: req.Header.Clone(),
+		Body:   string(body),
+	})
+
+	if r.recording {
+		return r.record(req, recReq)
+	}
+
+	return r.replay(req, recReq)
+}
+
+func (r *Recorder) record(req *http.Request, recReq Request) (*http.Response, error) {
+	resp, err := r.conf.Doer.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	recResp := Response{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		Body:       string(body),
+	}
+	r.redactHeader(recResp.Header)
+	recResp.Body = r.redactBody(recResp.Header.Get("Content-Type"), recResp.Body)
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{Request: recReq, Response: recResp})
+	r.mu.Unlock()
+
+	return resp, nil
+}
+
+// replay returns response of the first unused matching interaction, so the same request can be replayed
+// with different response in order
+func (r *Recorder) replay(req *http.Request, recReq Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, interaction := range r.cassette.Interactions {
+		if r.used[i] || !r.conf.Matcher(recReq, interaction.Request) {
+			continue
+		}
+
+		r.used[i] = true
+		resp := interaction.Response
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode)),
+			StatusCode:    resp.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        resp.Header.Clone(),
+			Body:          ioutil.NopCloser(strings.NewReader(resp.Body)),
+			ContentLength: int64(len(resp.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s %s", ErrInteractionNotFound, recReq.Method, recReq.URL)
+}
+
+// Stop writes the cassette file when recording
+func (r *Recorder) Stop() error {
+	if !r.recording {
+		return nil
+	}
+
+	r.mu.Lock()
+	b, err := json.MarshalIndent(r.cassette, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("cassette: failed to encode cassette: %w", err)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(r.conf.Path), 0755); err != nil {
+		return fmt.Errorf("cassette: failed to create cassette directory: %w", err)
+	}
+
+	if err = ioutil.WriteFile(r.conf.Path, append(b, '\n'), 0644); err != nil {
+		return fmt.Errorf("cassette: failed to write cassette file: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Recorder) redactRequest(req Request) Request {
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+
+	r.redactHeader(req.Header)
+	req.Body = r.redactBody(req.Header.Get("Content-Type"), req.Body)
+
+	if u, err := url.Parse(req.URL); err == nil && u.RawQuery != "" {
+		query := u.Query()
+		if r.redactValues(query) {
+			u.RawQuery = query.Encode()
+			req.URL = u.String()
+		}
+	}
+
+	return req
+}
+
+func (r *Recorder) redactHeader(header http.Header) {
+	for _, name := range r.conf.RedactHeaders {
+		if _, ok := header[http.CanonicalHeaderKey(name)]; ok {
+			header.Set(name, Redacted)
+		}
+	}
+}
+
+func (r *Recorder) redactBody(contentType, body string) string {
+	if len(r.conf.RedactFields) == 0 || body == "" {
+		return body
+	}
+
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		values, err := url.ParseQuery(body)
+		if err == nil && r.redactValues(values) {
+			return values.Encode()
+		}
+
+		return body
+	}
+
+	var v interface{}
+	if err := json.Unmarshal([]byte(body), &v); err != nil {
+		return body
+	}
+
+	if !r.redactJSON(v) {
+		return body
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+
+	return string(b)
+}
+
+func (r *Recorder) redactValues(values url.Values) (redacted bool) {
+	for key := range values {
+		if r.isRedactField(key) {
+			values.Set(key, Redacted)
+			redacted = true
+		}
+	}
+
+	return
+}
+
+func (r *Recorder) redactJSON(v interface{}) (redacted bool) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for key, child := range val {
+			if r.isRedactField(key) {
+				val[key] = Redacted
+				redacted = true
+				continue
+			}
+
+			if r.redactJSON(child) {
+				redacted = true
+			}
+		}
+	case []interface{}:
+		for _, child := range val {
+			if r.redactJSON(child) {
+				redacted = true
+			}
+		}
+	}
+
+	return
+}
+
+func (r *Recorder) isRedactField(name string) bool {
+	for _, field := range r.conf.RedactFields {
+		if strings.EqualFold(field, name) {
+			return true
+		}
+	}
+
+	return false
+}
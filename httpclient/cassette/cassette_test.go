@@ -0,0 +1,126 @@
+package cassette_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kitabisa/perkakas/v2/httpclient"
+	"github.com/kitabisa/perkakas/v2/httpclient/cassette"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "cassette")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	return dir
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return string(body)
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	path := filepath.Join(tempDir(t), "cassettes", "login.json")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.JSONEq(t, `{"username":"budi","password":"rahasia"}`, string(body))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=abc")
+		w.Write([]byte(`{"user":{"id":1,"token":"secret-token"}}`))
+	}))
+
+	conf := cassette.Conf{
+		Path:         path,
+		RedactFields: []string{"password", "token"},
+	}
+
+	// first run records the real interaction
+	rec, err := cassette.NewRecorder(conf)
+	require.NoError(t, err)
+	assert.True(t, rec.IsRecording())
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set("Authorization", "Bearer real-token")
+
+	client := httpclient.NewHttpWithCustomClient(nil, rec)
+	resp, err := client.Post(srv.URL+"/login", strings.NewReader(`{"username":"budi","password":"rahasia"}`), header)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"user":{"id":1,"token":"secret-token"}}`, readBody(t, resp))
+	require.NoError(t, rec.Stop())
+	srv.Close()
+
+	file, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(file), "rahasia")
+	assert.NotContains(t, string(file), "secret-token")
+	assert.NotContains(t, string(file), "real-token")
+	assert.NotContains(t, string(file), "session=abc")
+
+	// second run replays offline, the server is already closed
+	rec, err = cassette.NewRecorder(conf)
+	require.NoError(t, err)
+	assert.False(t, rec.IsRecording())
+
+	client = httpclient.NewHttpWithCustomClient(nil, rec)
+	resp, err = client.Post(srv.URL+"/login", strings.NewReader(`{"password":"other","username":"budi"}`), header)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, `{"user":{"id":1,"token":"[REDACTED]"}}`, readBody(t, resp))
+}
+
+func TestReplayNotFound(t *testing.T) {
+	path := filepath.Join(tempDir(t), "empty.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"interactions":[]}`), 0644))
+
+	rec, err := cassette.NewRecorder(cassette.Conf{Path: path, Mode: cassette.ModeReplay})
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest(http.MethodGet, "http://campaign-service/campaigns", nil)
+	_, err = rec.Do(req)
+	assert.True(t, errors.Is(err, cassette.ErrInteractionNotFound))
+}
+
+func TestReplayInOrderWithMatcher(t *testing.T) {
+	path := filepath.Join(tempDir(t), "ordered.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"interactions":[
+		{"request":{"method":"GET","url":"http://svc/a","header":{"X-Tenant":["1"]}},"response":{"status_code":200,"body":"first"}},
+		{"request":{"method":"GET","url":"http://svc/a","header":{"X-Tenant":["1"]}},"response":{"status_code":200,"body":"second"}},
+		{"request":{"method":"GET","url":"http://svc/a","header":{"X-Tenant":["2"]}},"response":{"status_code":200,"body":"tenant-2"}}
+	]}`), 0644))
+
+	rec, err := cassette.NewRecorder(cassette.Conf{
+		Path:    path,
+		Matcher: cassette.Match(cassette.MatchMethod, cassette.MatchURL, cassette.MatchHeaders("X-Tenant")),
+	})
+	require.NoError(t, err)
+
+	do := func(tenant string) string {
+		req, _ := http.NewRequest(http.MethodGet, "http://svc/a", nil)
+		req.Header.Set("X-Tenant", tenant)
+
+		resp, err := rec.Do(req)
+		require.NoError(t, err)
+		return readBody(t, resp)
+	}
+
+	assert.Equal(t, "tenant-2", do("2"))
+	assert.Equal(t, "first", do("1"))
+	assert.Equal(t, "second", do("1"))
+}
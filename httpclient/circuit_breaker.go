@@ -0,0 +1,254 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned when request is rejected because the circuit breaker of the host is open.
+// Use errors.Is(err, ErrCircuitOpen) to check it.
+var ErrCircuitOpen = errors.New("httpclient: circuit breaker is open")
+
+// CircuitOpenError is the error returned when the circuit breaker of the host is open
+type CircuitOpenError struct {
+	Host string
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("%s for host %s", ErrCircuitOpen.Error(), e.Host)
+}
+
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// IsCircuitOpen checks whether err is caused by open circuit breaker
+func IsCircuitOpen(err error) bool {
+	return errors.Is(err, ErrCircuitOpen)
+}
+
+type CircuitState int
+
+const (
+	StateClosed CircuitState = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half_open"
+	}
+
+	return "unknown"
+}
+
+// CircuitBreakerConf is per host circuit breaker configuration
+type CircuitBreakerConf struct {
+	// FailureThreshold is number of consecutive failures to open the circuit. Default 5.
+	FailureThreshold int
+	// OpenTimeout is duration of open circuit before it becomes half-open. Default 30s.
+	OpenTimeout time.Duration
+	// HalfOpenMaxRequests is number of probe requests allowed in half-open state.
+	// The circuit is closed when all of them succeed. Default 1.
+	HalfOpenMaxRequests int
+	// IsFailure determines whether the request is failed. Default is error or 5xx response.
+	IsFailure func(resp *http.Response, err error) bool
+	// Fallback is called when the circuit is open or the request returns error
+	Fallback func(req *http.Request, err error) (*http.Response, error)
+	// OnStateChange is called when the circuit state of a host changes
+	OnStateChange func(host string, from, to CircuitState)
+}
+
+func (c *CircuitBreakerConf) setDefault() {
+	if c.FailureThreshold <= 0 {
+		c.FailureThreshold = 5
+	}
+
+	if c.OpenTimeout <= 0 {
+		c.OpenTimeout = 30 * time.Second
+	}
+
+	if c.HalfOpenMaxRequests <= 0 {
+		c.HalfOpenMaxRequests = 1
+	}
+
+	if c.IsFailure == nil {
+		c.IsFailure = isFailure
+	}
+}
+
+func isFailure(resp *http.Response, err error) bool {
+	return err != nil || resp == nil || resp.StatusCode >= http.StatusInternalServerError
+}
+
+type circuitBreaker struct {
+	host          string
+	conf          CircuitBreakerConf
+	onStateChange func(host string, from, to CircuitState)
+
+	mu               sync.Mutex
+	state            CircuitState
+	failures         int
+	openedAt         time.Time
+	halfOpenInFlight int
+	halfOpenSuccess  int
+	transitions      [][2]CircuitState
+}
+
+// allow reports whether request can be sent. Open circuit becomes half-open after OpenTimeout.
+func (cb *circuitBreaker) allow() bool {
+	cb.mu.Lock()
+	allowed := true
+
+	switch cb.state {
+	case StateOpen:
+		if time.Since(cb.openedAt) < cb.conf.OpenTimeout {
+			allowed = false
+			break
+		}
+
+		cb.setState(StateHalfOpen)
+		cb.halfOpenInFlight++
+	case StateHalfOpen:
+		if cb.halfOpenInFlight >= cb.conf.HalfOpenMaxRequests {
+			allowed = false
+			break
+		}
+
+		cb.halfOpenInFlight++
+	}
+
+	cb.unlockAndNotify()
+	return allowed
+}
+
+func (cb *circuitBreaker) record(success bool) {
+	cb.mu.Lock()
+
+	switch cb.state {
+	case StateClosed:
+		if success {
+			cb.failures = 0
+			break
+		}
+
+		cb.failures++
+		if cb.failures >= cb.conf.FailureThreshold {
+			cb.setState(StateOpen)
+		}
+	case StateHalfOpen:
+		if !success {
+			cb.setState(StateOpen)
+			break
+		}
+
+		cb.halfOpenSuccess++
+		if cb.halfOpenSuccess >= cb.conf.HalfOpenMaxRequests {
+			cb.setState(StateClosed)
+		}
+	}
+
+	cb.unlockAndNotify()
+}
+
+func (cb *circuitBreaker) currentState() CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	return cb.state
+}
+
+// setState must be called with lock held
+func (cb *circuitBreaker) setState(state CircuitState) {
+	cb.transitions = append(cb.transitions, [2]CircuitState{cb.state, state})
+	cb.state = state
+	cb.failures = 0
+	cb.halfOpenInFlight = 0
+	cb.halfOpenSuccess = 0
+
+	if state == StateOpen {
+		cb.openedAt = time.Now()
+	}
+}
+
+// unlockAndNotify releases the lock, then calls OnStateChange outside the lock
+// so the callback can safely use the client
+func (cb *circuitBreaker) unlockAndNotify() {
+	transitions := cb.transitions
+	cb.transitions = nil
+	cb.mu.Unlock()
+
+	if cb.onStateChange == nil {
+		return
+	}
+
+	for _, t := range transitions {
+		cb.onStateChange(cb.host, t[0], t[1])
+	}
+}
+
+// circuitBreakers holds circuit breaker for each host
+type circuitBreakers struct {
+	conf          CircuitBreakerConf
+	onStateChange func(host string, from, to CircuitState)
+
+	mu       sync.Mutex
+	breakers map[string]*circuitBreaker
+}
+
+func newCircuitBreakers(conf CircuitBreakerConf, onStateChange func(host string, from, to CircuitState)) *circuitBreakers {
+	conf.setDefault()
+
+	return &circuitBreakers{
+		conf:          conf,
+		onStateChange: onStateChange,
+		breakers:      make(map[string]*circuitBreaker),
+	}
+}
+
+func (c *circuitBreakers) get(host string) *circuitBreaker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cb, ok := c.breakers[host]
+	if !ok {
+		cb = &circuitBreaker{
+			host:          host,
+			conf:          c.conf,
+			onStateChange: c.onStateChange,
+		}
+		c.breakers[host] = cb
+	}
+
+	return cb
+}
+
+func (c *circuitBreakers) do(req *http.Request, do func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	cb := c.get(req.URL.Host)
+	if !cb.allow() {
+		err := &CircuitOpenError{Host: req.URL.Host}
+		if c.conf.Fallback != nil {
+			return c.conf.Fallback(req, err)
+		}
+
+		return nil, err
+	}
+
+	resp, err := do(req)
+	cb.record(!c.conf.IsFailure(resp, err))
+
+	if err != nil && c.conf.Fallback != nil {
+		return c.conf.Fallback(req, err)
+	}
+
+	return resp, err
+}
@@ -0,0 +1,101 @@
+package httpclient
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func newCircuitBreakerTestClient(cbConf *CircuitBreakerConf) *HttpClient {
+	conf := getDefaultHttpClientConf()
+	conf.RetryCount = 0
+	conf.CircuitBreaker = cbConf
+
+	return NewHttpClient(conf)
+}
+
+func TestCircuitBreakerOpen(t *testing.T) {
+	defer gock.Off()
+
+	var transitions []CircuitState
+	client := newCircuitBreakerTestClient(&CircuitBreakerConf{
+		FailureThreshold: 2,
+		OpenTimeout:      50 * time.Millisecond,
+		OnStateChange: func(host string, from, to CircuitState) {
+			assert.Equal(t, "cb-test.com", host)
+			transitions = append(transitions, to)
+		},
+	})
+
+	gock.New("http://cb-test.com").Get("/").Times(2).Reply(http.StatusServiceUnavailable)
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get("http://cb-test.com/", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	}
+
+	assert.Equal(t, StateOpen, client.CircuitState("cb-test.com"))
+
+	_, err := client.Get("http://cb-test.com/", nil)
+	assert.True(t, IsCircuitOpen(err))
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+
+	var cbErr *CircuitOpenError
+	if assert.True(t, errors.As(err, &cbErr)) {
+		assert.Equal(t, "cb-test.com", cbErr.Host)
+	}
+
+	// other host is not affected
+	assert.Equal(t, StateClosed, client.CircuitState("other-host.com"))
+
+	// half-open probe succeeds and closes the circuit
+	time.Sleep(60 * time.Millisecond)
+	gock.New("http://cb-test.com").Get("/").Reply(http.StatusOK)
+
+	resp, err := client.Get("http://cb-test.com/", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, StateClosed, client.CircuitState("cb-test.com"))
+	assert.Equal(t, []CircuitState{StateOpen, StateHalfOpen, StateClosed}, transitions)
+}
+
+func TestCircuitBreakerHalfOpenFailure(t *testing.T) {
+	defer gock.Off()
+
+	client := newCircuitBreakerTestClient(&CircuitBreakerConf{
+		FailureThreshold: 1,
+		OpenTimeout:      20 * time.Millisecond,
+	})
+
+	gock.New("http://cb-test.com").Get("/").Times(2).Reply(http.StatusInternalServerError)
+
+	client.Get("http://cb-test.com/", nil)
+	assert.Equal(t, StateOpen, client.CircuitState("cb-test.com"))
+
+	time.Sleep(30 * time.Millisecond)
+	client.Get("http://cb-test.com/", nil)
+	assert.Equal(t, StateOpen, client.CircuitState("cb-test.com"))
+}
+
+func TestCircuitBreakerFallback(t *testing.T) {
+	defer gock.Off()
+
+	client := newCircuitBreakerTestClient(&CircuitBreakerConf{
+		FailureThreshold: 1,
+		Fallback: func(req *http.Request, err error) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Request: req}, nil
+		},
+	})
+
+	gock.New("http://cb-test.com").Get("/").Reply(http.StatusInternalServerError)
+	client.Get("http://cb-test.com/", nil)
+
+	resp, err := client.Get("http://cb-test.com/", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
@@ -1,21 +1,60 @@
 package httpclient
 
 import (
+	"fmt"
+	"io"
+	"net/http"
 	"time"
 
+	"github.com/DataDog/datadog-go/statsd"
 	"github.com/gojektech/heimdall"
 	"github.com/gojektech/heimdall/httpclient"
+	zlog "github.com/rs/zerolog/log"
 )
 
+// HttpClient is http client with retrier and optional circuit breaker. Use its Get, Post, Put, Patch, Delete
+// and Do method. Client is the underlying heimdall client, calling it directly bypasses the circuit breaker
+// and retries every error or 5xx response using constant backoff.
 type HttpClient struct {
 	Client *httpclient.Client
+
+	retrier     *retrier
+	breakers    *circuitBreakers
+	cache       *responseCache
+	metric      *statsd.Client
+	serviceName string
 }
 
+var _ heimdall.Client = (*HttpClient)(nil)
+
 type HttpClientConf struct {
 	BackoffInterval       time.Duration
 	MaximumJitterInterval time.Duration
 	Timeout               time.Duration
 	RetryCount            int
+
+	// Backoff is backoff policy between retries, e.g. NewExponentialBackoff or NewDecorrelatedJitterBackoff.
+	// Default is constant backoff using BackoffInterval and MaximumJitterInterval.
+	Backoff Backoff
+	// RetryableStatuses is response status to be retried. Default is DefaultRetryableStatuses.
+	// Only idempotent method, or request with Idempotency-Key header, is retried.
+	RetryableStatuses []int
+	// MaximumRetryAfter is maximum Retry-After response header to be waited. The response is returned without retry
+	// when Retry-After is longer. Zero means no maximum.
+	MaximumRetryAfter time.Duration
+
+	// CircuitBreaker enables per host circuit breaker. Nil means disabled.
+	CircuitBreaker *CircuitBreakerConf
+
+	// Cache enables response cache honoring Cache-Control, ETag and Vary. Nil means disabled.
+	Cache *CacheConf
+
+	// Signer signs every request, e.g. &signature.SignerV2{Key: key}. Nil means disabled.
+	Signer RequestSigner
+
+	// Metric is statsd client to send http client metrics, e.g. circuit breaker state
+	Metric      *statsd.Client
+	ServiceName string
 }
 
 func NewHttpClient(conf *HttpClientConf) *HttpClient {
@@ -25,18 +64,7 @@ func NewHttpClient(conf *HttpClientConf) *HttpClient {
 		conf = getDefaultHttpClientConf()
 	}
 
-	backoff := heimdall.NewConstantBackoff(conf.BackoffInterval, conf.MaximumJitterInterval)
-	retrier := heimdall.NewRetrier(backoff)
-
-	newClient := httpclient.NewClient(
-		httpclient.WithHTTPTimeout(conf.Timeout),
-		httpclient.WithRetrier(retrier),
-		httpclient.WithRetryCount(conf.RetryCount),
-	)
-
-	return &HttpClient{
-		Client: newClient,
-	}
+	return newHttpClient(conf, &http.Client{Timeout: conf.Timeout})
 }
 
 func NewHttpWithCustomClient(conf *HttpClientConf, doer heimdall.Doer) *HttpClient {
@@ -46,18 +74,144 @@ func NewHttpWithCustomClient(conf *HttpClientConf, doer heimdall.Doer) *HttpClie
 		conf = getDefaultHttpClientConf()
 	}
 
+	return newHttpClient(conf, doer)
+}
+
+func newHttpClient(conf *HttpClientConf, doer heimdall.Doer) *HttpClient {
 	backoff := heimdall.NewConstantBackoff(conf.BackoffInterval, conf.MaximumJitterInterval)
 	retrier := heimdall.NewRetrier(backoff)
 
+	if conf.Signer != nil {
+		doer = &signerDoer{signer: conf.Signer, doer: doer}
+	}
+
+	reqIDDoer := &requestIDDoer{doer: doer}
+
 	newClient := httpclient.NewClient(
 		httpclient.WithHTTPTimeout(conf.Timeout),
 		httpclient.WithRetrier(retrier),
 		httpclient.WithRetryCount(conf.RetryCount),
-		httpclient.WithHTTPClient(doer),
+		httpclient.WithHTTPClient(reqIDDoer),
 	)
 
-	return &HttpClient{
-		Client: newClient,
+	h := &HttpClient{
+		Client:      newClient,
+		retrier:     newRetrier(conf, reqIDDoer),
+		metric:      conf.Metric,
+		serviceName: conf.ServiceName,
+	}
+
+	if conf.CircuitBreaker != nil {
+		h.breakers = newCircuitBreakers(*conf.CircuitBreaker, h.onCircuitStateChange)
+	}
+
+	if conf.Cache != nil && conf.Cache.Store != nil {
+		h.cache = newResponseCache(*conf.Cache)
+	}
+
+	return h
+}
+
+// Do makes http request. Request is retried on error or retryable status when the method is idempotent,
+// or Idempotency-Key header is set. When circuit breaker is enabled and the circuit of the host is open,
+// it returns CircuitOpenError without sending the request.
+// Each request is traced as child span of the span in request context, and its metric is sent when Metric is set.
+// When cache is enabled, fresh cached response is returned without sending the request.
+func (h *HttpClient) Do(req *http.Request) (*http.Response, error) {
+	if h.cache == nil {
+		return h.send(req)
+	}
+
+	return h.cache.do(req, h.send)
+}
+
+func (h *HttpClient) send(req *http.Request) (resp *http.Response, err error) {
+	route := routeTemplate(req)
+	span := startSpan(req, route)
+	start := time.Now()
+
+	var retries int
+	do := func(req *http.Request) (resp *http.Response, err error) {
+		resp, retries, err = h.retrier.do(req)
+		return
+	}
+
+	if h.breakers == nil {
+		resp, err = do(req)
+	} else {
+		resp, err = h.breakers.do(req, do)
+	}
+
+	finishSpan(span, resp, retries, err)
+	h.sendMetric(req, route, resp, err, time.Since(start))
+
+	return resp, err
+}
+
+// Get makes http GET request
+func (h *HttpClient) Get(url string, headers http.Header) (*http.Response, error) {
+	return h.newRequest(http.MethodGet, url, nil, headers)
+}
+
+// Post makes http POST request
+func (h *HttpClient) Post(url string, body io.Reader, headers http.Header) (*http.Response, error) {
+	return h.newRequest(http.MethodPost, url, body, headers)
+}
+
+// Put makes http PUT request
+func (h *HttpClient) Put(url string, body io.Reader, headers http.Header) (*http.Response, error) {
+	return h.newRequest(http.MethodPut, url, body, headers)
+}
+
+// Patch makes http PATCH request
+func (h *HttpClient) Patch(url string, body io.Reader, headers http.Header) (*http.Response, error) {
+	return h.newRequest(http.MethodPatch, url, body, headers)
+}
+
+// Delete makes http DELETE request
+func (h *HttpClient) Delete(url string, headers http.Header) (*http.Response, error) {
+	return h.newRequest(http.MethodDelete, url, nil, headers)
+}
+
+// CircuitState returns circuit breaker state of the host. It always returns StateClosed if circuit breaker is disabled.
+func (h *HttpClient) CircuitState(host string) CircuitState {
+	if h.breakers == nil {
+		return StateClosed
+	}
+
+	return h.breakers.get(host).currentState()
+}
+
+func (h *HttpClient) newRequest(method, url string, body io.Reader, headers http.Header) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("%s - request creation failed: %w", method, err)
+	}
+
+	req.Header = headers
+	return h.Do(req)
+}
+
+func (h *HttpClient) onCircuitStateChange(host string, from, to CircuitState) {
+	zlog.Warn().
+		Str("host", host).
+		Str("from", from.String()).
+		Str("to", to.String()).
+		Msg("http client circuit breaker state changed")
+
+	if h.metric != nil {
+		tag := []string{
+			fmt.Sprintf("service_name:%s", h.serviceName),
+			fmt.Sprintf("host:%s", host),
+			fmt.Sprintf("state:%s", to.String()),
+		}
+
+		h.metric.Incr("CIRCUIT_BREAKER_STATE_CHANGE", tag, 1)
+		h.metric.Gauge("CIRCUIT_BREAKER_STATE", float64(to), tag[:2], 1)
+	}
+
+	if onStateChange := h.breakers.conf.OnStateChange; onStateChange != nil {
+		onStateChange(host, from, to)
 	}
 }
 
@@ -1,6 +1,7 @@
 package httpclient
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -8,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/gojektech/heimdall/httpclient"
+	"github.com/kitabisa/perkakas/v2/ctxkeys"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 
@@ -15,7 +17,7 @@ import (
 )
 
 type httpClientResponse struct {
-	Test string "json:`test`"
+	Test string `json:"test"`
 }
 
 type testCustomHttp struct {
@@ -101,6 +103,28 @@ func (suite *LogTestSuite) TestGetRequestWithCustomClient() {
 	assert.Equal(suite.T(), "a1234-abcd", result.Test, "Result is not same")
 }
 
+func (suite *LogTestSuite) TestRequestIDPropagation() {
+	defer gock.Off()
+
+	reqID := "6f1b1c9e-4b0e-4e4a-9d2c-9b7f3c1d2e4f"
+	gock.New(suite.host).
+		Get(suite.endpoint).
+		MatchHeader("X-Ktbs-Request-ID", reqID).
+		Reply(200).
+		JSON(suite.mockResponse)
+
+	req, err := http.NewRequest(http.MethodGet, suite.url, nil)
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+
+	ctx := ctxkeys.WithRequestID(context.Background(), reqID)
+	resp, err := suite.HttpClient.Do(req.WithContext(ctx))
+	assert.Nil(suite.T(), err, "Nil expected")
+	assert.Equal(suite.T(), http.StatusOK, resp.StatusCode)
+	assert.Equal(suite.T(), true, gock.IsDone(), "Must be equal")
+}
+
 func TestSuite(t *testing.T) {
 	suite.Run(t, new(LogTestSuite))
 }
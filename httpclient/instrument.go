@@ -0,0 +1,109 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/kitabisa/perkakas/v2/ctxkeys"
+	phttp "github.com/kitabisa/perkakas/v2/http"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	otLog "github.com/opentracing/opentracing-go/log"
+)
+
+type routeTemplateKey struct{}
+
+// WithRouteTemplate sets route template of the outbound request, e.g. /campaigns/{id}, used as span name and metric endpoint tag.
+// Default is the request path with its params replaced, as HttpHandler does.
+func WithRouteTemplate(ctx context.Context, route string) context.Context {
+	return context.WithValue(ctx, routeTemplateKey{}, route)
+}
+
+func routeTemplate(req *http.Request) string {
+	if route, ok := req.Context().Value(routeTemplateKey{}).(string); ok && route != "" {
+		return route
+	}
+
+	return phttp.PathPattern(req.URL.Path)
+}
+
+// startSpan starts client span as child of span in the request context, and injects the span context into request header
+func startSpan(req *http.Request, route string) opentracing.Span {
+	span, _ := opentracing.StartSpanFromContext(req.Context(), fmt.Sprintf("HTTP %s %s", req.Method, route))
+	ext.SpanKindRPCClient.Set(span)
+	ext.HTTPMethod.Set(span, req.Method)
+	ext.HTTPUrl.Set(span, req.URL.String())
+	ext.PeerHostname.Set(span, req.URL.Host)
+	span.SetTag("http.route", route)
+
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+
+	_ = span.Tracer().Inject(span.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(req.Header))
+	return span
+}
+
+func finishSpan(span opentracing.Span, resp *http.Response, retries int, err error) {
+	span.SetTag("retry_count", retries)
+
+	if err != nil {
+		ext.Error.Set(span, true)
+		span.LogFields(otLog.Error(err))
+	} else if resp != nil {
+		ext.HTTPStatusCode.Set(span, uint16(resp.StatusCode))
+		if resp.StatusCode >= http.StatusInternalServerError {
+			ext.Error.Set(span, true)
+		}
+	}
+
+	span.Finish()
+}
+
+// sendMetric sends outbound request metric per downstream host
+func (h *HttpClient) sendMetric(req *http.Request, route string, resp *http.Response, err error, elapsed time.Duration) {
+	if h.metric == nil {
+		return
+	}
+
+	requestID := req.Header.Get(ctxkeys.CtxXKtbsRequestID.String())
+	if requestID == "" {
+		requestID = ctxkeys.RequestIDFrom(req.Context())
+	}
+
+	responseTimeTag := []string{
+		fmt.Sprintf("service_name:%s", h.serviceName),
+		fmt.Sprintf("method:%s", req.Method),
+		fmt.Sprintf("host:%s", req.URL.Host),
+		fmt.Sprintf("endpoint:%s", route),
+		fmt.Sprintf("request_id:%s", requestID),
+	}
+
+	h.metric.Count("HTTP_CLIENT_RESPONSE_TIME", elapsed.Milliseconds(), responseTimeTag, 1)
+
+	var statusCode int
+	if resp != nil {
+		statusCode = resp.StatusCode
+	}
+
+	tag := append(responseTimeTag, fmt.Sprintf("http_status:%d", statusCode))
+
+	if err == nil && statusCode < http.StatusBadRequest {
+		h.metric.Incr("HTTP_CLIENT_SUCCESS", tag, 1)
+		return
+	}
+
+	var status string
+	switch {
+	case err != nil:
+		status = "REQUEST_ERROR"
+	case statusCode < http.StatusInternalServerError:
+		status = "CLIENT_ERROR"
+	default:
+		status = "SERVER_ERROR"
+	}
+
+	h.metric.Incr("HTTP_CLIENT_ERROR", append(tag, fmt.Sprintf("status:%s", status)), 1)
+}
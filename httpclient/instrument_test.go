@@ -0,0 +1,106 @@
+package httpclient
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-go/statsd"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracing(t *testing.T) {
+	tracer := mocktracer.New()
+	opentracing.SetGlobalTracer(tracer)
+	defer opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+
+	var attempts int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		assert.NotEmpty(t, r.Header.Get("Mockpfx-Ids-Traceid"))
+
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	conf := getDefaultHttpClientConf()
+	conf.RetryCount = 1
+	h := NewHttpClient(conf)
+
+	parent := tracer.StartSpan("parent")
+	ctx := opentracing.ContextWithSpan(context.Background(), parent)
+	ctx = WithRouteTemplate(ctx, "/campaigns/{id}")
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/campaigns/123", nil)
+	resp, err := h.Do(req.WithContext(ctx))
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	spans := tracer.FinishedSpans()
+	require.Len(t, spans, 1)
+
+	span := spans[0]
+	assert.Equal(t, "HTTP GET /campaigns/{id}", span.OperationName)
+	assert.Equal(t, parent.Context().(mocktracer.MockSpanContext).SpanID, span.ParentID)
+	assert.Equal(t, "GET", span.Tag("http.method"))
+	assert.Equal(t, strings.TrimPrefix(srv.URL, "http://"), span.Tag("peer.hostname"))
+	assert.Equal(t, "/campaigns/{id}", span.Tag("http.route"))
+	assert.Equal(t, uint16(http.StatusOK), span.Tag("http.status_code"))
+	assert.Equal(t, 1, span.Tag("retry_count"))
+	assert.Nil(t, span.Tag("error"))
+}
+
+func TestMetric(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	metric, err := statsd.New(conn.LocalAddr().String())
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	conf := getDefaultHttpClientConf()
+	conf.Metric = metric
+	conf.ServiceName = "test-service"
+	h := NewHttpClient(conf)
+
+	resp, err := h.Get(srv.URL+"/campaigns/1234567890123", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.NoError(t, metric.Close())
+
+	buf := make([]byte, 4096)
+	var packets []string
+	for {
+		conn.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			break
+		}
+
+		packets = append(packets, string(buf[:n]))
+	}
+
+	received := strings.Join(packets, "\n")
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	assert.Contains(t, received, "HTTP_CLIENT_RESPONSE_TIME:")
+	assert.Contains(t, received, "HTTP_CLIENT_ERROR:1|c|#service_name:test-service,method:GET,host:"+host+",endpoint:/campaigns/PARAM")
+	assert.Contains(t, received, "http_status:404,status:CLIENT_ERROR")
+}
@@ -0,0 +1,28 @@
+package httpclient
+
+import (
+	"net/http"
+
+	"github.com/gojektech/heimdall"
+	"github.com/kitabisa/perkakas/v2/ctxkeys"
+)
+
+// requestIDDoer propagates request ID from request context into X-Ktbs-Request-ID header,
+// unless the header is already set by the caller
+type requestIDDoer struct {
+	doer heimdall.Doer
+}
+
+func (d *requestIDDoer) Do(req *http.Request) (*http.Response, error) {
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+
+	if req.Header.Get(ctxkeys.CtxXKtbsRequestID.String()) == "" {
+		if reqID := ctxkeys.RequestIDFrom(req.Context()); reqID != "" {
+			req.Header.Set(ctxkeys.CtxXKtbsRequestID.String(), reqID)
+		}
+	}
+
+	return d.doer.Do(req)
+}
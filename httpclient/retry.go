@@ -0,0 +1,283 @@
+package httpclient
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gojektech/heimdall"
+	"github.com/kitabisa/perkakas/v2/ctxkeys"
+	zlog "github.com/rs/zerolog/log"
+)
+
+// IdempotencyKeyHeader is the header marking non idempotent request, e.g. POST, safe to be retried
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// DefaultRetryableStatuses is http status retried by default
+var DefaultRetryableStatuses = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// Backoff returns wait duration before the next retry. Retry starts from 1, previous is the previous wait duration
+// (0 for the first retry). It must be safe for concurrent use.
+type Backoff interface {
+	Next(retry int, previous time.Duration) time.Duration
+}
+
+// ConstantBackoff waits Interval plus random jitter up to MaximumJitter
+type ConstantBackoff struct {
+	Interval      time.Duration
+	MaximumJitter time.Duration
+}
+
+// NewConstantBackoff creates constant backoff
+func NewConstantBackoff(interval, maximumJitter time.Duration) *ConstantBackoff {
+	return &ConstantBackoff{
+		Interval:      interval,
+		MaximumJitter: maximumJitter,
+	}
+}
+
+func (b *ConstantBackoff) Next(retry int, previous time.Duration) time.Duration {
+	return b.Interval + jitter(b.MaximumJitter)
+}
+
+// ExponentialBackoff waits Initial * Factor^(retry-1), capped at Maximum, plus random jitter up to MaximumJitter
+type ExponentialBackoff struct {
+	Initial       time.Duration
+	Maximum       time.Duration
+	Factor        float64
+	MaximumJitter time.Duration
+}
+
+// NewExponentialBackoff creates exponential backoff. Factor less than or equal 1 is set to 2.
+func NewExponentialBackoff(initial, maximum time.Duration, factor float64, maximumJitter time.Duration) *ExponentialBackoff {
+	if factor <= 1 {
+		factor = 2
+	}
+
+	return &ExponentialBackoff{
+		Initial:       initial,
+		Maximum:       maximum,
+		Factor:        factor,
+		MaximumJitter: maximumJitter,
+	}
+}
+
+func (b *ExponentialBackoff) Next(retry int, previous time.Duration) time.Duration {
+	wait := float64(b.Initial) * math.Pow(b.Factor, float64(retry-1))
+	if b.Maximum > 0 && wait > float64(b.Maximum) {
+		wait = float64(b.Maximum)
+	}
+
+	return time.Duration(wait) + jitter(b.MaximumJitter)
+}
+
+// DecorrelatedJitterBackoff waits random duration between Base and 3 times the previous wait, capped at Maximum.
+// See https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/
+type DecorrelatedJitterBackoff struct {
+	Base    time.Duration
+	Maximum time.Duration
+}
+
+// NewDecorrelatedJitterBackoff creates decorrelated jitter backoff
+func NewDecorrelatedJitterBackoff(base, maximum time.Duration) *DecorrelatedJitterBackoff {
+	return &DecorrelatedJitterBackoff{
+		Base:    base,
+		Maximum: maximum,
+	}
+}
+
+func (b *DecorrelatedJitterBackoff) Next(retry int, previous time.Duration) time.Duration {
+	if previous < b.Base {
+		previous = b.Base
+	}
+
+	wait := b.Base + jitter(previous*3-b.Base)
+	if b.Maximum > 0 && wait > b.Maximum {
+		wait = b.Maximum
+	}
+
+	return wait
+}
+
+var (
+	randMu sync.Mutex
+	rnd    = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// jitter returns random duration in [0, max)
+func jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+
+	randMu.Lock()
+	defer randMu.Unlock()
+
+	return time.Duration(rnd.Int63n(int64(max)))
+}
+
+// retrier retries request based on method, idempotency key and response status
+type retrier struct {
+	doer              heimdall.Doer
+	retryCount        int
+	backoff           Backoff
+	retryableStatuses map[int]bool
+	maxRetryAfter     time.Duration
+}
+
+func newRetrier(conf *HttpClientConf, doer heimdall.Doer) *retrier {
+	backoff := conf.Backoff
+	if backoff == nil {
+		backoff = NewConstantBackoff(conf.BackoffInterval, conf.MaximumJitterInterval)
+	}
+
+	statuses := conf.RetryableStatuses
+	if statuses == nil {
+		statuses = DefaultRetryableStatuses
+	}
+
+	retryableStatuses := make(map[int]bool, len(statuses))
+	for _, s := range statuses {
+		retryableStatuses[s] = true
+	}
+
+	return &retrier{
+		doer:              doer,
+		retryCount:        conf.RetryCount,
+		backoff:           backoff,
+		retryableStatuses: retryableStatuses,
+		maxRetryAfter:     conf.MaximumRetryAfter,
+	}
+}
+
+// isRetryable checks whether request is safe to be sent more than once
+func isRetryable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	return req.Header.Get(IdempotencyKeyHeader) != ""
+}
+
+// parseRetryAfter parses Retry-After header in seconds or http date format
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	t, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+
+	wait := t.Sub(now)
+	if wait < 0 {
+		wait = 0
+	}
+
+	return wait, true
+}
+
+// do sends the request and returns the response of the last attempt with number of retries made
+func (r *retrier) do(req *http.Request) (*http.Response, int, error) {
+	if r.retryCount <= 0 || !isRetryable(req) {
+		resp, err := r.doer.Do(req)
+		return resp, 0, err
+	}
+
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	var wait time.Duration
+	for retry := 0; ; retry++ {
+		if body != nil {
+			req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+
+		resp, err := r.doer.Do(req)
+		if retry >= r.retryCount || req.Context().Err() != nil {
+			return resp, retry, err
+		}
+
+		var retryAfter time.Duration
+		var hasRetryAfter bool
+		if err == nil {
+			if !r.retryableStatuses[resp.StatusCode] {
+				return resp, retry, nil
+			}
+
+			retryAfter, hasRetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+			if hasRetryAfter && r.maxRetryAfter > 0 && retryAfter > r.maxRetryAfter {
+				return resp, retry, nil
+			}
+		}
+
+		wait = r.backoff.Next(retry+1, wait)
+		if hasRetryAfter {
+			wait = retryAfter
+		}
+
+		r.logRetry(req, resp, err, retry+1, wait)
+
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, retry, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (r *retrier) logRetry(req *http.Request, resp *http.Response, err error, retry int, wait time.Duration) {
+	reqID := req.Header.Get(ctxkeys.CtxXKtbsRequestID.String())
+	if reqID == "" {
+		reqID = ctxkeys.RequestIDFrom(req.Context())
+	}
+
+	event := zlog.Warn().
+		Str(ctxkeys.CtxXKtbsRequestID.String(), reqID).
+		Str("method", req.Method).
+		Str("host", req.URL.Host).
+		Str("path", req.URL.Path).
+		Int("retry", retry).
+		Dur("wait", wait)
+
+	if err != nil {
+		event = event.Err(err)
+	} else {
+		event = event.Int("status", resp.StatusCode)
+	}
+
+	event.Msg("http client retrying request")
+}
@@ -0,0 +1,186 @@
+package httpclient
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRetryTestClient(statuses []int) *HttpClient {
+	conf := getDefaultHttpClientConf()
+	conf.RetryCount = 2
+	conf.Backoff = NewExponentialBackoff(time.Millisecond, 5*time.Millisecond, 2, 0)
+	conf.RetryableStatuses = statuses
+
+	return NewHttpClient(conf)
+}
+
+// newFlakyServer replies status for the first failures requests, then 200
+func newFlakyServer(t *testing.T, failures int32, status int, attempts *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Method == http.MethodPost {
+			assert.Equal(t, "payload", string(body))
+		}
+
+		if atomic.AddInt32(attempts, 1) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func TestRetryIdempotentMethod(t *testing.T) {
+	var attempts int32
+	srv := newFlakyServer(t, 2, http.StatusServiceUnavailable, &attempts)
+	defer srv.Close()
+
+	resp, err := newRetryTestClient(nil).Get(srv.URL, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(3), attempts)
+}
+
+func TestRetryExhausted(t *testing.T) {
+	var attempts int32
+	srv := newFlakyServer(t, 5, http.StatusBadGateway, &attempts)
+	defer srv.Close()
+
+	resp, err := newRetryTestClient(nil).Get(srv.URL, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	assert.Equal(t, int32(3), attempts)
+}
+
+func TestRetryNonIdempotentMethod(t *testing.T) {
+	var attempts int32
+	srv := newFlakyServer(t, 1, http.StatusServiceUnavailable, &attempts)
+	defer srv.Close()
+
+	h := newRetryTestClient(nil)
+
+	resp, err := h.Post(srv.URL, strings.NewReader("payload"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, int32(1), attempts)
+
+	atomic.StoreInt32(&attempts, 0)
+	header := http.Header{}
+	header.Set(IdempotencyKeyHeader, "key-1")
+
+	resp, err = h.Post(srv.URL, strings.NewReader("payload"), header)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(2), attempts)
+}
+
+func TestRetryableStatuses(t *testing.T) {
+	var attempts int32
+	srv := newFlakyServer(t, 1, http.StatusInternalServerError, &attempts)
+	defer srv.Close()
+
+	resp, err := newRetryTestClient(nil).Get(srv.URL, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	assert.Equal(t, int32(1), attempts)
+
+	atomic.StoreInt32(&attempts, 0)
+	resp, err = newRetryTestClient([]int{http.StatusInternalServerError}).Get(srv.URL, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(2), attempts)
+}
+
+func TestRetryAfter(t *testing.T) {
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.Header().Set("Retry-After", r.URL.Query().Get("after"))
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	conf := getDefaultHttpClientConf()
+	conf.RetryCount = 1
+	conf.MaximumRetryAfter = 2 * time.Second
+	h := NewHttpClient(conf)
+
+	start := time.Now()
+	resp, err := h.Get(srv.URL+"?after=1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(time.Second))
+
+	atomic.StoreInt32(&attempts, 0)
+	resp, err = h.Get(srv.URL+"?after=120", nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, int32(1), attempts)
+}
+
+func TestRetryContextCanceled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	conf := getDefaultHttpClientConf()
+	conf.RetryCount = 3
+	conf.Backoff = NewConstantBackoff(time.Minute, 0)
+	h := NewHttpClient(conf)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	_, err := h.Do(req.WithContext(ctx))
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	wait, ok := parseRetryAfter("3", now)
+	assert.True(t, ok)
+	assert.Equal(t, 3*time.Second, wait)
+
+	wait, ok = parseRetryAfter(now.Add(10*time.Second).Format(http.TimeFormat), now)
+	assert.True(t, ok)
+	assert.Equal(t, 10*time.Second, wait)
+
+	_, ok = parseRetryAfter("", now)
+	assert.False(t, ok)
+
+	_, ok = parseRetryAfter("soon", now)
+	assert.False(t, ok)
+}
+
+func TestBackoff(t *testing.T) {
+	exp := NewExponentialBackoff(10*time.Millisecond, 50*time.Millisecond, 2, 0)
+	assert.Equal(t, 10*time.Millisecond, exp.Next(1, 0))
+	assert.Equal(t, 20*time.Millisecond, exp.Next(2, 0))
+	assert.Equal(t, 40*time.Millisecond, exp.Next(3, 0))
+	assert.Equal(t, 50*time.Millisecond, exp.Next(4, 0))
+
+	dj := NewDecorrelatedJitterBackoff(10*time.Millisecond, 100*time.Millisecond)
+	var wait time.Duration
+	for i := 1; i <= 20; i++ {
+		wait = dj.Next(i, wait)
+		assert.GreaterOrEqual(t, int64(wait), int64(10*time.Millisecond))
+		assert.LessOrEqual(t, int64(wait), int64(100*time.Millisecond))
+	}
+}
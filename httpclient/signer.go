@@ -0,0 +1,37 @@
+package httpclient
+
+import (
+	"net/http"
+
+	"github.com/gojektech/heimdall"
+)
+
+// RequestSigner signs outgoing request, implemented by signature.SignerV2
+type RequestSigner interface {
+	Sign(req *http.Request) error
+}
+
+// signerDoer signs every attempt of the request, so retried request has fresh time and nonce
+type signerDoer struct {
+	signer RequestSigner
+	doer   heimdall.Doer
+}
+
+func (d *signerDoer) Do(req *http.Request) (*http.Response, error) {
+	if err := d.signer.Sign(req); err != nil {
+		return nil, err
+	}
+
+	return d.doer.Do(req)
+}
+
+// WithSigner returns copy of the client which signs every attempt of the request with signer, before the request is
+// signed by HttpClientConf.Signer. Circuit breaker and cache are shared with the original client.
+func (h *HttpClient) WithSigner(signer RequestSigner) *HttpClient {
+	retrier := *h.retrier
+	retrier.doer = &signerDoer{signer: signer, doer: retrier.doer}
+
+	c := *h
+	c.retrier = &retrier
+	return &c
+}
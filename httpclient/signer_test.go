@@ -0,0 +1,53 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kitabisa/perkakas/v2/nonce"
+	"github.com/kitabisa/perkakas/v2/signature"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigner(t *testing.T) {
+	key := signature.Key{ID: "donation-2021-09", ClientName: "donation-service", Secret: "secret"}
+	verifier := signature.NewVerifierV2(signature.VerifierV2Option{
+		Keys:       signature.NewMemoryKeyStore(key),
+		NonceStore: nonce.NewMemoryStore(),
+	})
+
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := verifier.Verify(r)
+		assert.NoError(t, err)
+
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	conf := getDefaultHttpClientConf()
+	conf.RetryCount = 1
+	conf.Backoff = NewExponentialBackoff(time.Millisecond, time.Millisecond, 2, 0)
+	conf.Signer = &signature.SignerV2{Key: key, Nonce: true}
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set(IdempotencyKeyHeader, "donation-1")
+
+	// retried request is signed again with new nonce
+	resp, err := NewHttpClient(conf).Post(srv.URL+"/donations?campaign_id=1", strings.NewReader(`{"amount":10000}`), header)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(2), attempts)
+}
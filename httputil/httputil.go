@@ -3,6 +3,7 @@ package httputil
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"regexp"
@@ -33,6 +34,33 @@ func ReadRequestBody(req *http.Request) (bodyString string) {
 	return
 }
 
+// ReadRequestBodyLimit reads request body up to limit bytes, so a huge body is never fully loaded into memory.
+// The body is restored to its original state. complete is false if the body is larger than limit, and bodyString will be empty.
+func ReadRequestBodyLimit(req *http.Request, limit int64) (bodyString string, complete bool) {
+	if req.Body == nil {
+		return "", true
+	}
+
+	bodyBytes, _ := ioutil.ReadAll(io.LimitReader(req.Body, limit+1))
+
+	// Restore the io.ReadCloser, prepending the bytes that already read
+	req.Body = readCloser{
+		Reader: io.MultiReader(bytes.NewReader(bodyBytes), req.Body),
+		Closer: req.Body,
+	}
+
+	if int64(len(bodyBytes)) > limit {
+		return "", false
+	}
+
+	return string(bodyBytes), true
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
 // ExcludeSensitiveHeader exclude sensitive header. Currently, sensitive header only Authorization
 func ExcludeSensitiveHeader(header http.Header) (h http.Header) {
 	h = make(http.Header)
@@ -1,9 +1,12 @@
 package httputil
 
 import (
-	"github.com/stretchr/testify/assert"
+	"io/ioutil"
 	"net/http"
+	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestExcludeSensitiveRequestBody(t *testing.T) {
@@ -49,3 +52,19 @@ func TestIsServerError(t *testing.T) {
 	failCode := http.StatusOK
 	assert.False(t, IsServerError(failCode))
 }
+
+func TestReadRequestBodyLimit(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "/", strings.NewReader("0123456789"))
+	body, complete := ReadRequestBodyLimit(req, 10)
+	assert.True(t, complete)
+	assert.Equal(t, "0123456789", body)
+
+	req, _ = http.NewRequest(http.MethodPost, "/", strings.NewReader("0123456789"))
+	body, complete = ReadRequestBodyLimit(req, 5)
+	assert.False(t, complete)
+	assert.Empty(t, body)
+
+	// body must be restored
+	restored, _ := ioutil.ReadAll(req.Body)
+	assert.Equal(t, "0123456789", string(restored))
+}
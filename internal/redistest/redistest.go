@@ -0,0 +1,300 @@
+// Package redistest provides in-memory fake of redis.Cmdable for tests of redis backed stores
+
+package redistest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// Client is in-memory fake of redis.Cmdable. It implements Get, Set, SetNX, MGet, Del, Expire, ZAdd,
+// ZRemRangeByScore and ZRangeByScoreWithScores, calling other command panics. Key doesn't expire,
+// its ttl is recorded to be asserted instead.
+type Client struct {
+	redis.Cmdable
+
+	mu    sync.Mutex
+	data  map[string]string
+	sets  map[string]map[string]float64
+	ttl   map[string]time.Duration
+	calls map[string]int
+	err   error
+}
+
+// New creates empty fake client
+func New() *Client {
+	return &Client{
+		data:  make(map[string]string),
+		sets:  make(map[string]map[string]float64),
+		ttl:   make(map[string]time.Duration),
+		calls: make(map[string]int),
+	}
+}
+
+// Value gets string value of the key
+func (c *Client) Value(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	v, ok := c.data[key]
+	return v, ok
+}
+
+// Expiration gets the last ttl set to the key
+func (c *Client) Expiration(key string) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.ttl[key]
+}
+
+// Calls counts calls of the command, e.g. "mget"
+func (c *Client) Calls(cmd string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.calls[cmd]
+}
+
+// SetError makes every command fails with the error, nil resets it
+func (c *Client) SetError(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.err = err
+}
+
+// call counts the command and returns the configured error. It must be called with the lock held.
+func (c *Client) call(cmd string) error {
+	c.calls[cmd]++
+	return c.err
+}
+
+func (c *Client) Get(key string) *redis.StringCmd {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("get"); err != nil {
+		return redis.NewStringResult("", err)
+	}
+
+	v, ok := c.data[key]
+	if !ok {
+		return redis.NewStringResult("", redis.Nil)
+	}
+
+	return redis.NewStringResult(v, nil)
+}
+
+func (c *Client) Set(key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("set"); err != nil {
+		return redis.NewStatusResult("", err)
+	}
+
+	c.set(key, value, expiration)
+	return redis.NewStatusResult("OK", nil)
+}
+
+func (c *Client) SetNX(key string, value interface{}, expiration time.Duration) *redis.BoolCmd {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("setnx"); err != nil {
+		return redis.NewBoolResult(false, err)
+	}
+
+	if _, ok := c.data[key]; ok {
+		return redis.NewBoolResult(false, nil)
+	}
+
+	c.set(key, value, expiration)
+	return redis.NewBoolResult(true, nil)
+}
+
+func (c *Client) set(key string, value interface{}, expiration time.Duration) {
+	switch v := value.(type) {
+	case []byte:
+		c.data[key] = string(v)
+	case string:
+		c.data[key] = v
+	default:
+		c.data[key] = fmt.Sprint(v)
+	}
+
+	c.ttl[key] = expiration
+}
+
+func (c *Client) MGet(keys ...string) *redis.SliceCmd {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("mget"); err != nil {
+		return redis.NewSliceResult(nil, err)
+	}
+
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		if v, ok := c.data[key]; ok {
+			values[i] = v
+		}
+	}
+
+	return redis.NewSliceResult(values, nil)
+}
+
+func (c *Client) Del(keys ...string) *redis.IntCmd {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("del"); err != nil {
+		return redis.NewIntResult(0, err)
+	}
+
+	var n int64
+	for _, key := range keys {
+		_, isString := c.data[key]
+		_, isSet := c.sets[key]
+		if isString || isSet {
+			n++
+		}
+
+		delete(c.data, key)
+		delete(c.sets, key)
+		delete(c.ttl, key)
+	}
+
+	return redis.NewIntResult(n, nil)
+}
+
+func (c *Client) Expire(key string, expiration time.Duration) *redis.BoolCmd {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("expire"); err != nil {
+		return redis.NewBoolResult(false, err)
+	}
+
+	_, isString := c.data[key]
+	_, isSet := c.sets[key]
+	if !isString && !isSet {
+		return redis.NewBoolResult(false, nil)
+	}
+
+	c.ttl[key] = expiration
+	return redis.NewBoolResult(true, nil)
+}
+
+func (c *Client) ZAdd(key string, members ...redis.Z) *redis.IntCmd {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("zadd"); err != nil {
+		return redis.NewIntResult(0, err)
+	}
+
+	set, ok := c.sets[key]
+	if !ok {
+		set = make(map[string]float64)
+		c.sets[key] = set
+	}
+
+	var added int64
+	for _, m := range members {
+		member := fmt.Sprint(m.Member)
+		if _, ok := set[member]; !ok {
+			added++
+		}
+
+		set[member] = m.Score
+	}
+
+	return redis.NewIntResult(added, nil)
+}
+
+func (c *Client) ZRemRangeByScore(key, min, max string) *redis.IntCmd {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("zremrangebyscore"); err != nil {
+		return redis.NewIntResult(0, err)
+	}
+
+	inRange, err := scoreRange(min, max)
+	if err != nil {
+		return redis.NewIntResult(0, err)
+	}
+
+	var n int64
+	for member, score := range c.sets[key] {
+		if inRange(score) {
+			delete(c.sets[key], member)
+			n++
+		}
+	}
+
+	return redis.NewIntResult(n, nil)
+}
+
+// ZRangeByScoreWithScores ignores offset and count of the range
+func (c *Client) ZRangeByScoreWithScores(key string, opt redis.ZRangeBy) *redis.ZSliceCmd {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call("zrangebyscore"); err != nil {
+		return redis.NewZSliceCmdResult(nil, err)
+	}
+
+	inRange, err := scoreRange(opt.Min, opt.Max)
+	if err != nil {
+		return redis.NewZSliceCmdResult(nil, err)
+	}
+
+	var result []redis.Z
+	for member, score := range c.sets[key] {
+		if inRange(score) {
+			result = append(result, redis.Z{Score: score, Member: member})
+		}
+	}
+
+	return redis.NewZSliceCmdResult(result, nil)
+}
+
+// scoreRange parses redis score range, e.g. "-inf" and "(100"
+func scoreRange(min, max string) (func(score float64) bool, error) {
+	minScore, minExclusive, err := parseScore(min)
+	if err != nil {
+		return nil, err
+	}
+
+	maxScore, maxExclusive, err := parseScore(max)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(score float64) bool {
+		if score < minScore || (minExclusive && score == minScore) {
+			return false
+		}
+
+		return score < maxScore || (!maxExclusive && score == maxScore)
+	}, nil
+}
+
+func parseScore(s string) (score float64, exclusive bool, err error) {
+	if strings.HasPrefix(s, "(") {
+		exclusive = true
+		s = s[1:]
+	}
+
+	// ParseFloat parses "-inf" and "+inf" too
+	score, err = strconv.ParseFloat(s, 64)
+	return score, exclusive, err
+}
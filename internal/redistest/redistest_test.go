@@ -0,0 +1,58 @@
+package redistest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	c := New()
+
+	first, err := c.SetNX("a", []byte("1"), time.Minute).Result()
+	require.NoError(t, err)
+	assert.True(t, first)
+
+	first, err = c.SetNX("a", "2", time.Minute).Result()
+	require.NoError(t, err)
+	assert.False(t, first)
+
+	value, err := c.Get("a").Result()
+	require.NoError(t, err)
+	assert.Equal(t, "1", value)
+	assert.Equal(t, time.Minute, c.Expiration("a"))
+
+	values, err := c.MGet("a", "b").Result()
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"1", nil}, values)
+
+	require.NoError(t, c.Del("a").Err())
+	assert.Equal(t, redis.Nil, c.Get("a").Err())
+	assert.Equal(t, 2, c.Calls("get"))
+
+	c.SetError(errors.New("connection refused"))
+	assert.Error(t, c.Set("a", "1", 0).Err())
+	c.SetError(nil)
+	assert.NoError(t, c.Set("a", "1", 0).Err())
+}
+
+func TestClientSortedSet(t *testing.T) {
+	c := New()
+	require.NoError(t, c.ZAdd("s", redis.Z{Score: 1, Member: "a"}, redis.Z{Score: 2, Member: "b"}, redis.Z{Score: 3, Member: "c"}).Err())
+
+	removed, err := c.ZRemRangeByScore("s", "-inf", "1").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+
+	members, err := c.ZRangeByScoreWithScores("s", redis.ZRangeBy{Min: "(2", Max: "+inf"}).Result()
+	require.NoError(t, err)
+	assert.Equal(t, []redis.Z{{Score: 3, Member: "c"}}, members)
+
+	assert.True(t, c.Expire("s", time.Hour).Val())
+	assert.False(t, c.Expire("unknown", time.Hour).Val())
+	assert.Equal(t, time.Hour, c.Expiration("s"))
+}
@@ -86,7 +86,7 @@ func (l *Logger) NewChildLogger() (logger *Logger) {
 func (l *Logger) SetRequest(req interface{}) {
 	switch v := req.(type) {
 	case *http.Request:
-		token, ok := v.Context().Value("token").(*jwt.UserClaim)
+		token, ok := jwt.UserClaimFrom(v.Context())
 		if ok {
 			l.fields.Store(FieldUserID, token.UserID)
 		}
@@ -11,13 +11,8 @@ import (
 // GetSublogger get zerolog sublogger
 // TODO: TO BE DEPRECATED after v2.14.6
 func GetSublogger(ctx context.Context, ctxName string) zerolog.Logger {
-	reqID := ""
-	if ctx.Value(ctxkeys.CtxXKtbsRequestID.String()) != nil {
-		reqID = ctx.Value(ctxkeys.CtxXKtbsRequestID.String()).(string)
-	}
-
 	return log.With().
-		Str(ctxkeys.CtxXKtbsRequestID.String(), reqID).
+		Str(ctxkeys.CtxXKtbsRequestID.String(), ctxkeys.RequestIDFrom(ctx)).
 		Str("label", ctxName).
 		Logger()
 }
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/kitabisa/perkakas/v2/apikey"
+	"github.com/kitabisa/perkakas/v2/ctxkeys"
+	phttp "github.com/kitabisa/perkakas/v2/http"
+	"github.com/kitabisa/perkakas/v2/log"
+	"github.com/kitabisa/perkakas/v2/structs"
+)
+
+// APIKeyAuthenticator authenticates plain api key, implemented by apikey.Manager
+type APIKeyAuthenticator interface {
+	Authenticate(plain string) (*apikey.Key, error)
+}
+
+// APIKeyOption is optional configuration of api key middleware
+type APIKeyOption func(*apiKeyOption)
+
+type apiKeyOption struct {
+	header string
+	query  string
+}
+
+// WithAPIKeyHeader reads api key from the header. Default "X-Api-Key".
+func WithAPIKeyHeader(name string) APIKeyOption {
+	return func(o *apiKeyOption) {
+		o.header = name
+	}
+}
+
+// WithAPIKeyQuery reads api key from the query param when the header is empty. Disabled by default,
+// since url is often logged, only use it for partner who can't set header e.g. webhook.
+func WithAPIKeyQuery(param string) APIKeyOption {
+	return func(o *apiKeyOption) {
+		o.query = param
+	}
+}
+
+// NewAPIKey authenticates partner api key. Partner identity and scopes of the key are stored in context,
+// get them with ctxkeys.PrincipalFrom.
+func NewAPIKey(hctx phttp.HttpHandlerContext, authenticator APIKeyAuthenticator, opts ...APIKeyOption) func(next http.Handler) http.Handler {
+	opt := &apiKeyOption{
+		header: "X-Api-Key",
+	}
+
+	for _, o := range opts {
+		o(opt)
+	}
+
+	writer := phttp.CustomWriter{
+		C: hctx,
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			log := log.GetSublogger(ctx, "Middleware.APIKey")
+
+			plain := r.Header.Get(opt.header)
+			if plain == "" && opt.query != "" {
+				plain = r.URL.Query().Get(opt.query)
+			}
+
+			if plain == "" {
+				log.Error().Msg("api key is empty")
+				writer.WriteError(w, structs.ErrUnauthorized)
+				return
+			}
+
+			key, err := authenticator.Authenticate(plain)
+			if err != nil {
+				log.Error().Msg(err.Error())
+
+				// store failure is not the partner's fault
+				switch {
+				case errors.Is(err, apikey.ErrInvalidKey), errors.Is(err, apikey.ErrKeyExpired):
+					writer.WriteError(w, structs.ErrUnauthorized)
+				default:
+					writer.WriteError(w, structs.ErrUnknown)
+				}
+				return
+			}
+
+			ctx = ctxkeys.WithPrincipal(ctx, ctxkeys.Principal{
+				ID:     key.Partner,
+				Method: ctxkeys.AuthMethodAPIKey,
+				KeyID:  key.ID,
+				Scopes: key.Scopes,
+			})
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
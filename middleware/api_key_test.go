@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kitabisa/perkakas/v2/apikey"
+	"github.com/kitabisa/perkakas/v2/ctxkeys"
+	phttp "github.com/kitabisa/perkakas/v2/http"
+	"github.com/kitabisa/perkakas/v2/structs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKey(t *testing.T) {
+	manager := apikey.NewManager(apikey.Option{Store: apikey.NewMemoryStore(), Secret: "s3cret"})
+	plain, key, err := manager.Create("partner-a", "production", []string{"donation:read"}, 0)
+	require.NoError(t, err)
+
+	hctx := phttp.NewContextHandler(structs.Meta{})
+
+	var principal ctxkeys.Principal
+	handler := NewAPIKey(hctx, manager, WithAPIKeyQuery("api_key"))(
+		RequireScopes(hctx, "donation:read")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, _ = ctxkeys.PrincipalFrom(r.Context())
+		})),
+	)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Api-Key", plain)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, ctxkeys.Principal{
+		ID:     "partner-a",
+		Method: ctxkeys.AuthMethodAPIKey,
+		KeyID:  key.ID,
+		Scopes: []string{"donation:read"},
+	}, principal)
+
+	r = httptest.NewRequest(http.MethodGet, "/?api_key="+plain, nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	for _, target := range []string{"/", "/?api_key=kb_invalid"} {
+		r = httptest.NewRequest(http.MethodGet, target, nil)
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+
+	// key without the scope is forbidden
+	other, _, err := manager.Create("partner-b", "", nil, 0)
+	require.NoError(t, err)
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Api-Key", other)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+// failingAPIKeyStore fails every lookup, e.g. redis is down
+type failingAPIKeyStore struct {
+	apikey.Store
+}
+
+func (failingAPIKeyStore) Get(hash string) (*apikey.Key, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestAPIKeyStoreError(t *testing.T) {
+	manager := apikey.NewManager(apikey.Option{Store: apikey.NewMemoryStore(), Secret: "s3cret"})
+	plain, _, err := manager.Create("partner-a", "", nil, time.Millisecond)
+	require.NoError(t, err)
+
+	hctx := phttp.NewContextHandler(structs.Meta{})
+	serve := func(manager *apikey.Manager) int {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-Api-Key", plain)
+		w := httptest.NewRecorder()
+		NewAPIKey(hctx, manager)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(w, r)
+		return w.Code
+	}
+
+	// expired key
+	time.Sleep(5 * time.Millisecond)
+	assert.Equal(t, http.StatusUnauthorized, serve(manager))
+
+	failing := apikey.NewManager(apikey.Option{Store: failingAPIKeyStore{}, Secret: "s3cret"})
+	assert.Equal(t, http.StatusInternalServerError, serve(failing))
+}
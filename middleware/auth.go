@@ -6,6 +6,8 @@ import (
 	"reflect"
 	"strings"
 
+	"github.com/kitabisa/perkakas/v2/basicauth"
+	"github.com/kitabisa/perkakas/v2/ctxkeys"
 	phttp "github.com/kitabisa/perkakas/v2/http"
 	"github.com/kitabisa/perkakas/v2/structs"
 	"github.com/kitabisa/perkakas/v2/token/jwt"
@@ -13,16 +15,30 @@ import (
 )
 
 type AuthOption struct {
-	SignKey  []byte // jwt sign key
-	Username string // Basic Auth username
-	Password string // Basuc Auth password
+	SignKey    []byte        // jwt sign key
+	KeySource  jwt.KeySource // jwt key source, e.g. JWKS. When set, SignKey is ignored.
+	JWTOptions []JWTOption   // jwt verifier, algorithm allowlist, claim validation and custom claims
+	Username   string        // Basic Auth username. Basic auth is rejected when Username or Password is empty.
+	Password   string        // Basuc Auth password
+	// BasicAuthStore is credential store of several basic auth users. When set, Username and Password are ignored.
+	BasicAuthStore basicauth.Store
 }
 
 // Middleware authentication supports jwt or basic auth
 func NewAuthentication(hctx phttp.HttpHandlerContext, authOption AuthOption) func(next http.Handler) http.Handler {
-	jwtt := jwt.NewJWT(authOption.SignKey)
-	definedUsername := authOption.Username
-	definedPassword := authOption.Password
+	jwtOpts := authOption.JWTOptions
+	if authOption.KeySource != nil {
+		jwtOpts = append([]JWTOption{WithKeySource(authOption.KeySource)}, jwtOpts...)
+	}
+
+	jwtAuth := newJWTAuthenticator(authOption.SignKey, jwtOpts...)
+	authenticate := credentialAuthenticator(authOption.Username, authOption.Password)
+
+	if authOption.BasicAuthStore != nil {
+		authenticate = func(username, password string) (bool, error) {
+			return basicauth.Authenticate(authOption.BasicAuthStore, username, password)
+		}
+	}
 	writer := phttp.CustomWriter{
 		C: hctx,
 	}
@@ -32,7 +48,7 @@ func NewAuthentication(hctx phttp.HttpHandlerContext, authOption AuthOption) fun
 			ctx := r.Context()
 			auth := strings.ToLower(r.Header.Get("Authorization"))
 			if strings.HasPrefix(auth, "basic") {
-				ok, err := basicAuth(r, definedUsername, definedPassword)
+				ok, err := basicAuth(r, authenticate)
 				if err != nil {
 					log.Error().Msg(err.Error())
 					writer.WriteError(w, structs.ErrUnauthorized)
@@ -42,17 +58,22 @@ func NewAuthentication(hctx phttp.HttpHandlerContext, authOption AuthOption) fun
 				if !ok {
 					log.Error().Msg("Failed login using basic auth")
 					writer.WriteError(w, structs.ErrUnauthorized)
+					return
 				}
+
+				ctx = withBasicAuthPrincipal(ctx, r)
 			} else if strings.HasPrefix(auth, "bearer") {
-				claims, err := bearerAuth(r, jwtt)
+				claims, err := bearerAuth(r, jwtAuth)
 				if err != nil {
 					log.Error().Msg(err.Error())
 					writer.WriteError(w, structs.ErrUnauthorized)
 					return
 				}
 
+				ctx = jwt.WithClaims(ctx, claims)
 				ctx = setClaimContext(ctx, claims)
 				ctx = context.WithValue(ctx, "token", claims) // compatibility with existing logic in all our services
+				ctx = ctxkeys.WithBearerToken(ctx, bearerToken(r))
 			} else {
 				log.Error().Msg("invalid authentication type")
 				writer.WriteError(w, structs.ErrUnauthorized)
@@ -64,9 +85,20 @@ func NewAuthentication(hctx phttp.HttpHandlerContext, authOption AuthOption) fun
 	}
 }
 
-func setClaimContext(ctx context.Context, claims *jwt.UserClaim) context.Context {
-	e := reflect.ValueOf(claims).Elem()
+// setClaimContext stores each field of claims struct in context, e.g. "UserID".
+// Deprecated, kept for compatibility with existing logic in all our services. Use jwt.UserClaimFrom instead.
+func setClaimContext(ctx context.Context, claims interface{}) context.Context {
+	e := reflect.ValueOf(claims)
+	if e.Kind() != reflect.Ptr || e.Elem().Kind() != reflect.Struct {
+		return ctx
+	}
+
+	e = e.Elem()
 	for i := 0; i < e.NumField(); i++ {
+		if !e.Field(i).CanInterface() {
+			continue
+		}
+
 		name := e.Type().Field(i).Name
 		value := e.Field(i).Interface()
 
@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/kitabisa/perkakas/v2/ctxkeys"
+	phttp "github.com/kitabisa/perkakas/v2/http"
+	"github.com/kitabisa/perkakas/v2/structs"
+	"github.com/kitabisa/perkakas/v2/token/jwt"
+	"github.com/kitabisa/perkakas/v2/token/paseto"
+	"github.com/rs/zerolog/log"
+)
+
+// ScopeSeparator separates hierarchical scope segments, e.g. campaign:donation:read
+const ScopeSeparator = ":"
+
+// Policy is resource level authorization hook, e.g. checking the user owns the requested campaign.
+// Scopes is the scopes granted to the token. Return false to reject the request with ErrForbidden,
+// or error to reject it with the error.
+type Policy func(r *http.Request, scopes []string) (allowed bool, err error)
+
+// RequireScopes allows request whose token is granted all of the scopes. It must be used after NewJWT,
+// NewAuthentication, NewPaseto, NewIntrospection, NewAPIKey or NewMTLS middleware. Request without token is rejected
+// with ErrUnauthorized, and request without the scopes is rejected with ErrForbidden.
+func RequireScopes(hctx phttp.HttpHandlerContext, scopes ...string) func(next http.Handler) http.Handler {
+	return RequirePolicy(hctx, func(r *http.Request, granted []string) (bool, error) {
+		for _, required := range scopes {
+			if !HasScope(granted, required) {
+				return false, nil
+			}
+		}
+
+		return true, nil
+	})
+}
+
+// RequireAnyScope allows request whose token is granted at least one of the scopes
+func RequireAnyScope(hctx phttp.HttpHandlerContext, scopes ...string) func(next http.Handler) http.Handler {
+	return RequirePolicy(hctx, func(r *http.Request, granted []string) (bool, error) {
+		for _, required := range scopes {
+			if HasScope(granted, required) {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	})
+}
+
+// RequirePolicy allows request allowed by the policy
+func RequirePolicy(hctx phttp.HttpHandlerContext, policy Policy) func(next http.Handler) http.Handler {
+	writer := phttp.CustomWriter{
+		C: hctx,
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			granted, ok := scopesFromContext(r.Context())
+			if !ok {
+				log.Error().Msg("authorization: no token in context")
+				writer.WriteError(w, structs.ErrUnauthorized)
+				return
+			}
+
+			allowed, err := policy(r, granted)
+			if err != nil {
+				writer.WriteError(w, err)
+				return
+			}
+
+			if !allowed {
+				log.Error().Strs("scopes", granted).Str("path", r.URL.Path).Msg("authorization: forbidden")
+				writer.WriteError(w, structs.ErrForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// HasScope checks whether any of the granted scopes satisfies the required scope
+func HasScope(granted []string, required string) bool {
+	for _, g := range granted {
+		if ScopeMatches(g, required) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ScopeMatches checks whether granted scope satisfies required scope. Scope is hierarchical, separated by colon:
+//   - "*" grants every scope
+//   - "campaign" grants "campaign", "campaign:read" and "campaign:donation:write"
+//   - "campaign:*:read" grants "campaign:donation:read" and "campaign:update:read", "*" matches one segment
+//   - "campaign:*" grants every scope under "campaign"
+func ScopeMatches(granted, required string) bool {
+	if granted == "" || required == "" {
+		return false
+	}
+
+	if granted == "*" || granted == required {
+		return true
+	}
+
+	g := strings.Split(granted, ScopeSeparator)
+	r := strings.Split(required, ScopeSeparator)
+	if len(g) > len(r) {
+		return false
+	}
+
+	for i := range g {
+		if g[i] != "*" && g[i] != r[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// scopedClaims is implemented by *jwt.UserClaim, or custom claims granting scopes
+type scopedClaims interface {
+	GetScopes() []string
+}
+
+var _ scopedClaims = (*jwt.UserClaim)(nil)
+
+// scopesFromContext gets granted scopes from jwt or paseto token, or principal stored in context
+func scopesFromContext(ctx context.Context) ([]string, bool) {
+	if claims, ok := jwt.ClaimsFrom(ctx); ok {
+		if scoped, ok := claims.(scopedClaims); ok {
+			return scoped.GetScopes(), true
+		}
+
+		// custom claims without scopes
+		return nil, true
+	}
+
+	if token, _, ok := paseto.TokenFrom(ctx); ok {
+		return paseto.Scopes(token), true
+	}
+
+	if principal, ok := ctxkeys.PrincipalFrom(ctx); ok {
+		return principal.Scopes, true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	phttp "github.com/kitabisa/perkakas/v2/http"
+	"github.com/kitabisa/perkakas/v2/structs"
+	"github.com/kitabisa/perkakas/v2/token/jwt"
+	libpaseto "github.com/o1egl/paseto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScopeMatches(t *testing.T) {
+	cases := []struct {
+		granted  string
+		required string
+		expected bool
+	}{
+		{"campaign:read", "campaign:read", true},
+		{"*", "campaign:read", true},
+		{"campaign", "campaign:read", true},
+		{"campaign", "campaign:donation:write", true},
+		{"campaign:*", "campaign:donation:write", true},
+		{"campaign:*:read", "campaign:donation:read", true},
+		{"campaign:*:read", "campaign:donation:write", false},
+		{"campaign:read", "campaign", false},
+		{"campaign:read", "campaign:write", false},
+		{"campaigns", "campaign:read", false},
+		{"", "campaign:read", false},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.expected, ScopeMatches(c.granted, c.required), "%s -> %s", c.granted, c.required)
+	}
+}
+
+func serveWithToken(handler http.Handler, token interface{}) int {
+	req := httptest.NewRequest(http.MethodGet, "/campaigns/1", nil)
+	if token != nil {
+		req = req.WithContext(context.WithValue(req.Context(), "token", token))
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestRequireScopes(t *testing.T) {
+	hctx := phttp.NewContextHandler(structs.Meta{})
+	all := RequireScopes(hctx, "campaign:read", "donation:read")(testHandler)
+	anyScope := RequireAnyScope(hctx, "campaign:read", "donation:read")(testHandler)
+
+	jwtToken := &jwt.UserClaim{Scopes: []string{"campaign"}}
+	assert.Equal(t, http.StatusForbidden, serveWithToken(all, jwtToken))
+	assert.Equal(t, http.StatusOK, serveWithToken(anyScope, jwtToken))
+
+	jwtToken = &jwt.UserClaim{Scopes: []string{"campaign", "donation:*"}}
+	assert.Equal(t, http.StatusOK, serveWithToken(all, jwtToken))
+
+	pasetoToken := libpaseto.JSONToken{}
+	pasetoToken.Set("scopes", "campaign:read donation:read")
+	assert.Equal(t, http.StatusOK, serveWithToken(all, pasetoToken))
+
+	pasetoToken = libpaseto.JSONToken{}
+	pasetoToken.Set("scopes", `["user:read"]`)
+	assert.Equal(t, http.StatusForbidden, serveWithToken(anyScope, pasetoToken))
+
+	assert.Equal(t, http.StatusUnauthorized, serveWithToken(all, nil))
+}
+
+func TestRequirePolicy(t *testing.T) {
+	hctx := phttp.NewContextHandler(structs.Meta{})
+	errPolicy := errors.New("policy error")
+	hctx.AddErrorMap(map[error]*structs.ErrorResponse{
+		errPolicy: {Response: structs.Response{ResponseCode: "99999"}, HttpStatus: http.StatusConflict},
+	})
+
+	ownCampaign := RequirePolicy(hctx, func(r *http.Request, scopes []string) (bool, error) {
+		claims := r.Context().Value("token").(*jwt.UserClaim)
+		if claims.UserID == 0 {
+			return false, errPolicy
+		}
+
+		return HasScope(scopes, "campaign:write") && claims.UserID == 1, nil
+	})(testHandler)
+
+	assert.Equal(t, http.StatusOK, serveWithToken(ownCampaign, &jwt.UserClaim{UserID: 1, Scopes: []string{"campaign"}}))
+	assert.Equal(t, http.StatusForbidden, serveWithToken(ownCampaign, &jwt.UserClaim{UserID: 2, Scopes: []string{"campaign"}}))
+	assert.Equal(t, http.StatusConflict, serveWithToken(ownCampaign, &jwt.UserClaim{Scopes: []string{"campaign"}}))
+}
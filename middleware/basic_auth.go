@@ -1,15 +1,72 @@
 package middleware
 
 import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
 	"errors"
+	"fmt"
 	"net/http"
 
+	"github.com/kitabisa/perkakas/v2/basicauth"
+	"github.com/kitabisa/perkakas/v2/ctxkeys"
 	phttp "github.com/kitabisa/perkakas/v2/http"
 	"github.com/kitabisa/perkakas/v2/log"
 	"github.com/kitabisa/perkakas/v2/structs"
 )
 
-func NewBasicAuth(hctx phttp.HttpHandlerContext, definedUsername, definedPassword string) func(next http.Handler) http.Handler {
+// BasicAuthOption is optional configuration of basic auth middleware
+type BasicAuthOption func(*basicAuthOption)
+
+type basicAuthOption struct {
+	realm string
+}
+
+// WithRealm sends WWW-Authenticate header with the realm on failed login, so browser prompts for credential
+func WithRealm(realm string) BasicAuthOption {
+	return func(o *basicAuthOption) {
+		o.realm = realm
+	}
+}
+
+// basicAuthenticator checks username and password of basic auth
+type basicAuthenticator func(username, password string) (bool, error)
+
+// NewBasicAuth checks basic auth against single username and password. Every request is rejected when the username
+// or password is empty.
+func NewBasicAuth(hctx phttp.HttpHandlerContext, definedUsername, definedPassword string, opts ...BasicAuthOption) func(next http.Handler) http.Handler {
+	return newBasicAuth(hctx, credentialAuthenticator(definedUsername, definedPassword), opts...)
+}
+
+// credentialAuthenticator compares with single username and password. It rejects every login when the username or
+// password is not configured, e.g. jwt only AuthOption, so empty basic auth credential is not accepted.
+func credentialAuthenticator(definedUsername, definedPassword string) basicAuthenticator {
+	return func(username, password string) (bool, error) {
+		if definedUsername == "" || definedPassword == "" {
+			return false, errors.New("basic auth credential is not configured")
+		}
+
+		// compare both, so response time doesn't reveal valid username
+		usernameMatch := secureCompare(username, definedUsername)
+		passwordMatch := secureCompare(password, definedPassword)
+		return usernameMatch && passwordMatch, nil
+	}
+}
+
+// NewBasicAuthStore checks basic auth against credential store of several users with bcrypt or argon2id hashed
+// password, see basicauth package. Username is stored in context, get it with ctxkeys.PrincipalFrom.
+func NewBasicAuthStore(hctx phttp.HttpHandlerContext, store basicauth.Store, opts ...BasicAuthOption) func(next http.Handler) http.Handler {
+	return newBasicAuth(hctx, func(username, password string) (bool, error) {
+		return basicauth.Authenticate(store, username, password)
+	}, opts...)
+}
+
+func newBasicAuth(hctx phttp.HttpHandlerContext, authenticate basicAuthenticator, opts ...BasicAuthOption) func(next http.Handler) http.Handler {
+	opt := &basicAuthOption{}
+	for _, o := range opts {
+		o(opt)
+	}
+
 	writer := phttp.CustomWriter{
 		C: hctx,
 	}
@@ -20,29 +77,49 @@ func NewBasicAuth(hctx phttp.HttpHandlerContext, definedUsername, definedPasswor
 			ctxName := "Middleware.BasicAuth"
 			log := log.GetSublogger(ctx, ctxName)
 
-			ok, err := basicAuth(r, definedUsername, definedPassword)
+			ok, err := basicAuth(r, authenticate)
 			if err != nil {
 				log.Error().Msg(err.Error())
-				writer.WriteError(w, structs.ErrUnauthorized)
+				writeBasicAuthError(w, writer, opt.realm)
 				return
 			}
 
 			if !ok {
 				log.Error().Msg("Failed login using basic auth")
-				writer.WriteError(w, structs.ErrUnauthorized)
+				writeBasicAuthError(w, writer, opt.realm)
 				return
 			}
 
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(w, r.WithContext(withBasicAuthPrincipal(ctx, r)))
 		})
 	}
 }
 
-func basicAuth(r *http.Request, definedUsername, definedPassword string) (bool, error) {
+func writeBasicAuthError(w http.ResponseWriter, writer phttp.CustomWriter, realm string) {
+	if realm != "" {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=%q, charset=\"UTF-8\"", realm))
+	}
+
+	writer.WriteError(w, structs.ErrUnauthorized)
+}
+
+func withBasicAuthPrincipal(ctx context.Context, r *http.Request) context.Context {
+	username, _, _ := r.BasicAuth()
+	return ctxkeys.WithPrincipal(ctx, ctxkeys.Principal{ID: username, Method: ctxkeys.AuthMethodBasic})
+}
+
+func basicAuth(r *http.Request, authenticate basicAuthenticator) (bool, error) {
 	username, password, ok := r.BasicAuth()
 	if !ok {
 		return false, errors.New("failed to parse basic auth string")
 	}
 
-	return username == definedUsername && password == definedPassword, nil
+	return authenticate(username, password)
+}
+
+// secureCompare compares in constant time regardless of the length
+func secureCompare(given, defined string) bool {
+	g := sha256.Sum256([]byte(given))
+	d := sha256.Sum256([]byte(defined))
+	return subtle.ConstantTimeCompare(g[:], d[:]) == 1
 }
@@ -4,9 +4,15 @@ import (
 	"encoding/base64"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/kitabisa/perkakas/v2/basicauth"
+	"github.com/kitabisa/perkakas/v2/ctxkeys"
+	phttp "github.com/kitabisa/perkakas/v2/http"
+	"github.com/kitabisa/perkakas/v2/structs"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestBasicAuth(t *testing.T) {
@@ -16,7 +22,57 @@ func TestBasicAuth(t *testing.T) {
 	val := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", user, pass)))
 
 	r.Header.Add("Authorization", fmt.Sprintf("Basic %s", val))
-	ok, err := basicAuth(r, user, pass)
+	ok, err := basicAuth(r, func(username, password string) (bool, error) {
+		usernameMatch := secureCompare(username, user)
+		passwordMatch := secureCompare(password, pass)
+		return usernameMatch && passwordMatch, nil
+	})
 	assert.Equal(t, true, ok)
 	assert.Nil(t, err)
 }
+
+func TestBasicAuthStore(t *testing.T) {
+	hash, err := basicauth.HashBcrypt("s3cret", 4)
+	require.NoError(t, err)
+
+	store := basicauth.NewMemoryStore(map[string]string{"partner": hash})
+	hctx := phttp.NewContextHandler(structs.Meta{})
+
+	var principal ctxkeys.Principal
+	handler := NewBasicAuthStore(hctx, store, WithRealm("partner api"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ = ctxkeys.PrincipalFrom(r.Context())
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.SetBasicAuth("partner", "s3cret")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, ctxkeys.Principal{ID: "partner", Method: ctxkeys.AuthMethodBasic}, principal)
+
+	for _, cred := range [][2]string{{"partner", "wrong"}, {"unknown", "s3cret"}} {
+		r = httptest.NewRequest(http.MethodGet, "/", nil)
+		r.SetBasicAuth(cred[0], cred[1])
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, `Basic realm="partner api", charset="UTF-8"`, w.Header().Get("WWW-Authenticate"))
+	}
+}
+
+func TestBasicAuthNotConfigured(t *testing.T) {
+	hctx := phttp.NewContextHandler(structs.Meta{})
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	// jwt only configuration must not accept empty basic auth credential
+	for _, handler := range []http.Handler{
+		NewBasicAuth(hctx, "", "")(next),
+		NewAuthentication(hctx, AuthOption{SignKey: []byte("secret")})(next),
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Authorization", "Basic Og==")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+}
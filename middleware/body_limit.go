@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	phttp "github.com/kitabisa/perkakas/v2/http"
+	"github.com/kitabisa/perkakas/v2/structs"
+)
+
+// BodyLimitOption is request body limit configuration. To limit per route, create the middleware for each route,
+// e.g. using chi router.With().
+type BodyLimitOption struct {
+	// MaxBytes is default max body size in bytes. Zero means no limit.
+	MaxBytes int64
+	// MaxBytesByContentType overrides MaxBytes for certain content type, e.g. "multipart/form-data"
+	MaxBytesByContentType map[string]int64
+	// AllowedContentTypes is list of allowed content type. Empty means all content type are allowed.
+	AllowedContentTypes []string
+}
+
+// NewBodyLimit creates middleware that enforces request body size and content type.
+// Request with known Content-Length above the limit is rejected with ErrPayloadTooLarge, and the body is wrapped
+// with http.MaxBytesReader so chunked body can not be read beyond the limit. When the handler reads beyond the limit,
+// ErrPayloadTooLarge is written and the response of the handler is discarded.
+// Request with disallowed content type is rejected with ErrUnsupportedMediaType.
+func NewBodyLimit(hctx phttp.HttpHandlerContext, opt BodyLimitOption) func(next http.Handler) http.Handler {
+	allowedContentTypes := make(map[string]bool)
+	for _, ct := range opt.AllowedContentTypes {
+		allowedContentTypes[strings.ToLower(ct)] = true
+	}
+
+	maxBytesByContentType := make(map[string]int64)
+	for ct, max := range opt.MaxBytesByContentType {
+		maxBytesByContentType[strings.ToLower(ct)] = max
+	}
+
+	writer := phttp.CustomWriter{
+		C: hctx,
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !hasBody(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			contentType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if err != nil {
+				contentType = ""
+			}
+
+			if len(allowedContentTypes) > 0 && !allowedContentTypes[contentType] {
+				writer.WriteError(w, structs.ErrUnsupportedMediaType)
+				return
+			}
+
+			maxBytes := opt.MaxBytes
+			if max, ok := maxBytesByContentType[contentType]; ok {
+				maxBytes = max
+			}
+
+			if maxBytes > 0 {
+				if r.ContentLength > maxBytes {
+					writer.WriteError(w, structs.ErrPayloadTooLarge)
+					return
+				}
+
+				lw := &bodyLimitWriter{ResponseWriter: w, writer: writer}
+				r.Body = &limitedBody{ReadCloser: http.MaxBytesReader(w, r.Body, maxBytes), w: lw, max: maxBytes}
+				w = lw
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func hasBody(r *http.Request) bool {
+	return r.Body != nil && r.Body != http.NoBody && r.ContentLength != 0
+}
+
+// limitedBody rejects the request with ErrPayloadTooLarge when http.MaxBytesReader stops at the limit
+type limitedBody struct {
+	io.ReadCloser
+	w    *bodyLimitWriter
+	max  int64
+	read int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	if err != nil && err != io.EOF && b.read >= b.max {
+		b.w.reject()
+	}
+
+	return n, err
+}
+
+// bodyLimitWriter writes ErrPayloadTooLarge once the body is read beyond the limit, and discards the response
+// written by the handler afterwards, e.g. error of the failed read
+type bodyLimitWriter struct {
+	http.ResponseWriter
+	writer      phttp.CustomWriter
+	rejected    bool
+	wroteHeader bool
+}
+
+func (w *bodyLimitWriter) reject() {
+	if w.rejected {
+		return
+	}
+
+	w.rejected = true
+	if !w.wroteHeader {
+		w.writer.WriteError(w.ResponseWriter, structs.ErrPayloadTooLarge)
+	}
+}
+
+func (w *bodyLimitWriter) WriteHeader(statusCode int) {
+	if w.rejected {
+		return
+	}
+
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *bodyLimitWriter) Write(b []byte) (int, error) {
+	if w.rejected {
+		return len(b), nil
+	}
+
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyLimitWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok && !w.rejected {
+		f.Flush()
+	}
+}
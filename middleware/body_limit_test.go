@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	phttp "github.com/kitabisa/perkakas/v2/http"
+	"github.com/kitabisa/perkakas/v2/structs"
+	"github.com/stretchr/testify/assert"
+)
+
+var testReadBodyHandler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	_, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+})
+
+func newTestBodyLimit() http.Handler {
+	hctx := phttp.NewContextHandler(structs.Meta{})
+	bodyLimit := NewBodyLimit(hctx, BodyLimitOption{
+		MaxBytes: 10,
+		MaxBytesByContentType: map[string]int64{
+			"text/plain": 20,
+		},
+		AllowedContentTypes: []string{"application/json", "text/plain"},
+	})
+
+	return bodyLimit(testReadBodyHandler)
+}
+
+func TestBodyLimit(t *testing.T) {
+	handler := newTestBodyLimit()
+
+	cases := []struct {
+		contentType  string
+		body         string
+		expectedCode int
+	}{
+		{contentType: "application/json", body: `{"a":"b"}`, expectedCode: http.StatusOK},
+		{contentType: "application/json; charset=utf-8", body: `{"a":"bcdefghij"}`, expectedCode: http.StatusRequestEntityTooLarge},
+		{contentType: "text/plain", body: "0123456789012345", expectedCode: http.StatusOK},
+		{contentType: "application/xml", body: "<a></a>", expectedCode: http.StatusUnsupportedMediaType},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(c.body))
+		req.Header.Set("Content-Type", c.contentType)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, c.expectedCode, rec.Code, c.contentType)
+
+		if c.expectedCode != http.StatusOK {
+			var resp structs.ErrorResponse
+			err := json.Unmarshal(rec.Body.Bytes(), &resp)
+			assert.NoError(t, err)
+			assert.NotEmpty(t, resp.ResponseCode)
+		}
+	}
+}
+
+func TestBodyLimitUnknownLength(t *testing.T) {
+	handler := newTestBodyLimit()
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a":"bcdefghij"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.ContentLength = -1
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+}
+
+func TestBodyLimitChunkedEnvelope(t *testing.T) {
+	hctx := phttp.NewContextHandler(structs.Meta{})
+
+	// handler returns the read error, body limit writes ErrPayloadTooLarge instead
+	handler := NewBodyLimit(hctx, BodyLimitOption{MaxBytes: 10})(
+		phttp.NewHttpHandler(hctx)(func(w http.ResponseWriter, r *http.Request) (interface{}, *string, error) {
+			var body map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				return nil, nil, err
+			}
+
+			return body, nil, nil
+		}),
+	)
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a":"bcdefghijklmnopqrstuvwxyz"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.ContentLength = -1
+	req.TransferEncoding = []string{"chunked"}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+	var resp structs.ErrorResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, structs.ErrPayloadTooLarge.ResponseCode, resp.ResponseCode)
+
+	// body within the limit is not affected
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a":"b"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.ContentLength = -1
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestBodyLimitWithoutBody(t *testing.T) {
+	handler := newTestBodyLimit()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
@@ -0,0 +1,227 @@
+package middleware
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	phttp "github.com/kitabisa/perkakas/v2/http"
+	"github.com/kitabisa/perkakas/v2/structs"
+)
+
+// KtbsHeaders is the list of Kitabisa standard request headers checked by NewHeaderCheck
+var KtbsHeaders = []string{
+	"X-Ktbs-Request-ID",
+	"X-Ktbs-Api-Version",
+	"X-Ktbs-Client-Version",
+	"X-Ktbs-Platform-Name",
+	"X-Ktbs-Client-Name",
+	"X-Ktbs-Signature",
+	"X-Ktbs-Time",
+	"X-Ktbs-Nonce",
+}
+
+// CORSOption is CORS middleware configuration
+type CORSOption struct {
+	// AllowedOrigins is list of allowed origin. Origin can be exact match (https://kitabisa.com),
+	// wildcard subdomain (https://*.kitabisa.com) or "*" to allow any origin.
+	AllowedOrigins []string
+	// AllowedOriginPatterns is list of regular expression to match the whole origin, as sent by the browser.
+	// Pattern is anchored, so `https://.*\.kitabisa\.com` doesn't match https://evil.kitabisa.com.attacker.io.
+	AllowedOriginPatterns []string
+	// AllowedMethods default to GET, POST, PUT, PATCH, DELETE and HEAD
+	AllowedMethods []string
+	// AllowedHeaders default to Authorization, Content-Type and all X-Ktbs-* headers
+	AllowedHeaders []string
+	// ExposedHeaders is list of response header that can be read by browser
+	ExposedHeaders []string
+	// AllowCredentials allows cookies and Authorization header to be sent by browser
+	AllowCredentials bool
+	// MaxAge is duration of preflight response cached by browser
+	MaxAge time.Duration
+}
+
+type cors struct {
+	allowAll         bool
+	origins          map[string]bool
+	wildcardOrigins  [][2]string
+	originPatterns   []*regexp.Regexp
+	methods          map[string]bool
+	methodsValue     string
+	headers          map[string]bool
+	headersValue     string
+	exposedValue     string
+	allowCredentials bool
+	maxAge           string
+}
+
+// NewCORS creates CORS middleware. Preflight request from disallowed origin, method or header
+// is rejected with ErrOriginNotAllowed.
+func NewCORS(hctx phttp.HttpHandlerContext, opt CORSOption) func(next http.Handler) http.Handler {
+	c := newCORS(opt)
+	writer := phttp.CustomWriter{
+		C: hctx,
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+			w.Header().Add("Vary", "Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if preflight {
+				w.Header().Add("Vary", "Access-Control-Request-Method")
+				w.Header().Add("Vary", "Access-Control-Request-Headers")
+
+				if !c.isOriginAllowed(origin) ||
+					!c.isMethodAllowed(r.Header.Get("Access-Control-Request-Method")) ||
+					!c.isHeadersAllowed(r.Header.Get("Access-Control-Request-Headers")) {
+					writer.WriteError(w, structs.ErrOriginNotAllowed)
+					return
+				}
+
+				c.setOriginHeader(w, origin)
+				w.Header().Set("Access-Control-Allow-Methods", c.methodsValue)
+				w.Header().Set("Access-Control-Allow-Headers", c.headersValue)
+				if c.maxAge != "" {
+					w.Header().Set("Access-Control-Max-Age", c.maxAge)
+				}
+
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			// let the browser block the response for disallowed origin
+			if c.isOriginAllowed(origin) {
+				c.setOriginHeader(w, origin)
+				if c.exposedValue != "" {
+					w.Header().Set("Access-Control-Expose-Headers", c.exposedValue)
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func newCORS(opt CORSOption) *cors {
+	c := &cors{
+		origins:          make(map[string]bool),
+		methods:          make(map[string]bool),
+		headers:          make(map[string]bool),
+		allowCredentials: opt.AllowCredentials,
+	}
+
+	for _, origin := range opt.AllowedOrigins {
+		origin = strings.ToLower(origin)
+		if origin == "*" {
+			c.allowAll = true
+		} else if i := strings.Index(origin, "*"); i >= 0 {
+			c.wildcardOrigins = append(c.wildcardOrigins, [2]string{origin[:i], origin[i+1:]})
+		} else {
+			c.origins[origin] = true
+		}
+	}
+
+	for _, pattern := range opt.AllowedOriginPatterns {
+		c.originPatterns = append(c.originPatterns, regexp.MustCompile("^(?:"+pattern+")$"))
+	}
+
+	allowedMethods := opt.AllowedMethods
+	if len(allowedMethods) == 0 {
+		allowedMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodHead}
+	}
+
+	var methods []string
+	for _, m := range allowedMethods {
+		m = strings.ToUpper(m)
+		c.methods[m] = true
+		methods = append(methods, m)
+	}
+	c.methodsValue = strings.Join(methods, ", ")
+
+	allowedHeaders := opt.AllowedHeaders
+	if len(allowedHeaders) == 0 {
+		allowedHeaders = append([]string{"Authorization", "Content-Type"}, KtbsHeaders...)
+	}
+
+	var headers []string
+	for _, h := range allowedHeaders {
+		h = http.CanonicalHeaderKey(h)
+		c.headers[h] = true
+		headers = append(headers, h)
+	}
+	c.headersValue = strings.Join(headers, ", ")
+
+	c.exposedValue = strings.Join(opt.ExposedHeaders, ", ")
+
+	if opt.MaxAge > 0 {
+		c.maxAge = strconv.FormatInt(int64(opt.MaxAge/time.Second), 10)
+	}
+
+	return c
+}
+
+func (c *cors) isOriginAllowed(origin string) bool {
+	if c.allowAll {
+		return true
+	}
+
+	lower := strings.ToLower(origin)
+	if c.origins[lower] {
+		return true
+	}
+
+	for _, w := range c.wildcardOrigins {
+		if len(lower) > len(w[0])+len(w[1]) && strings.HasPrefix(lower, w[0]) && strings.HasSuffix(lower, w[1]) {
+			return true
+		}
+	}
+
+	for _, p := range c.originPatterns {
+		if p.MatchString(origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *cors) isMethodAllowed(method string) bool {
+	return c.methods[strings.ToUpper(method)]
+}
+
+func (c *cors) isHeadersAllowed(requestHeaders string) bool {
+	for _, h := range strings.Split(requestHeaders, ",") {
+		h = strings.TrimSpace(h)
+		if h == "" {
+			continue
+		}
+
+		if !c.headers[http.CanonicalHeaderKey(h)] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c *cors) setOriginHeader(w http.ResponseWriter, origin string) {
+	// wildcard origin is not allowed by browser when credentials are included
+	if c.allowAll && !c.allowCredentials {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	}
+
+	if c.allowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
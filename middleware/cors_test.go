@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	phttp "github.com/kitabisa/perkakas/v2/http"
+	"github.com/kitabisa/perkakas/v2/structs"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestCORS() http.Handler {
+	hctx := phttp.NewContextHandler(structs.Meta{})
+	cors := NewCORS(hctx, CORSOption{
+		AllowedOrigins:        []string{"https://kitabisa.com", "https://*.kitabisa.xyz"},
+		AllowedOriginPatterns: []string{`^https://[a-z]+\.ktbs\.io$`},
+		ExposedHeaders:        []string{"X-Ktbs-Request-ID"},
+		AllowCredentials:      true,
+		MaxAge:                10 * time.Minute,
+	})
+
+	return cors(testHandler)
+}
+
+func TestCORSPreflight(t *testing.T) {
+	handler := newTestCORS()
+
+	for _, origin := range []string{"https://kitabisa.com", "https://api.kitabisa.xyz", "https://staging.ktbs.io"} {
+		req := httptest.NewRequest(http.MethodOptions, "/", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		req.Header.Set("Access-Control-Request-Headers", "x-ktbs-request-id, x-ktbs-signature, content-type")
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusNoContent, rec.Code, origin)
+		assert.Equal(t, origin, rec.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "true", rec.Header().Get("Access-Control-Allow-Credentials"))
+		assert.Equal(t, "600", rec.Header().Get("Access-Control-Max-Age"))
+		assert.Contains(t, rec.Header().Get("Access-Control-Allow-Headers"), "X-Ktbs-Client-Name")
+	}
+}
+
+func TestCORSPreflightRejected(t *testing.T) {
+	handler := newTestCORS()
+
+	cases := []struct {
+		origin  string
+		method  string
+		headers string
+	}{
+		{origin: "https://evil.com", method: http.MethodGet},
+		{origin: "https://kitabisa.xyz", method: http.MethodGet},
+		{origin: "https://kitabisa.com", method: "TRACE"},
+		{origin: "https://kitabisa.com", method: http.MethodGet, headers: "X-Unknown"},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodOptions, "/", nil)
+		req.Header.Set("Origin", c.origin)
+		req.Header.Set("Access-Control-Request-Method", c.method)
+		req.Header.Set("Access-Control-Request-Headers", c.headers)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+
+		var resp structs.ErrorResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &resp)
+		assert.NoError(t, err)
+		assert.Equal(t, structs.ErrOriginNotAllowed.ResponseCode, resp.ResponseCode)
+	}
+}
+
+func TestCORSActualRequest(t *testing.T) {
+	handler := newTestCORS()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Origin", "https://kitabisa.com")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "https://kitabisa.com", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "X-Ktbs-Request-ID", rec.Header().Get("Access-Control-Expose-Headers"))
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Origin", "https://evil.com")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORSAllowAll(t *testing.T) {
+	hctx := phttp.NewContextHandler(structs.Meta{})
+	handler := NewCORS(hctx, CORSOption{AllowedOrigins: []string{"*"}})(testHandler)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Origin", "https://anything.com")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORSOriginPatternAnchored(t *testing.T) {
+	hctx := phttp.NewContextHandler(structs.Meta{})
+	handler := NewCORS(hctx, CORSOption{
+		AllowedOriginPatterns: []string{`https://[a-z]+\.kitabisa\.com`},
+	})(testHandler)
+
+	cases := map[string]int{
+		"https://api.kitabisa.com":                   http.StatusNoContent,
+		"https://evil.kitabisa.com.attacker.io":      http.StatusForbidden,
+		"https://attacker.io/https://a.kitabisa.com": http.StatusForbidden,
+		"https://API.kitabisa.com":                   http.StatusForbidden, // pattern is matched as is
+	}
+
+	for origin, status := range cases {
+		req := httptest.NewRequest(http.MethodOptions, "/", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, status, rec.Code, origin)
+	}
+}
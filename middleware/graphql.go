@@ -3,15 +3,26 @@ package middleware
 import (
 	"context"
 	"net/http"
+	"strings"
+
+	"github.com/kitabisa/perkakas/v2/ctxkeys"
 )
 
 // MapHeaderToContext is used for assigning header to the request context to be processed in graphql resolver.
 func MapHeaderToContext(next http.Handler) (wrapped http.Handler) {
 	wrapped = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := ctxkeys.WithClientInfo(r.Context(), ctxkeys.ClientInfoFromHeader(r.Header))
+		ctx = ctxkeys.WithRequestID(ctx, r.Header.Get("X-Ktbs-Request-ID"))
+
+		if strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+			ctx = ctxkeys.WithBearerToken(ctx, bearerToken(r))
+		}
+
+		// deprecated, use ctxkeys.ClientInfoFrom, ctxkeys.RequestIDFrom or ctxkeys.BearerTokenFrom
 		for key := range r.Header {
-			r = r.WithContext(context.WithValue(r.Context(), key, r.Header.Get(key)))
+			ctx = context.WithValue(ctx, key, r.Header.Get(key))
 		}
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 	return
 }
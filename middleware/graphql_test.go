@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kitabisa/perkakas/v2/ctxkeys"
+)
+
+func TestMapHeaderToContext(t *testing.T) {
+	var reqID, token, legacyReqID string
+	var info ctxkeys.ClientInfo
+	handler := MapHeaderToContext(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqID = ctxkeys.RequestIDFrom(r.Context())
+		token = ctxkeys.BearerTokenFrom(r.Context())
+		info, _ = ctxkeys.ClientInfoFrom(r.Context())
+		legacyReqID, _ = r.Context().Value("X-Ktbs-Request-Id").(string)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+	req.Header.Set("X-Ktbs-Request-ID", "req-1")
+	req.Header.Set("X-Ktbs-Client-Name", "kitabisa-web")
+	req.Header.Set("Authorization", "Bearer abc.def")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, "req-1", reqID)
+	assert.Equal(t, "abc.def", token)
+	assert.Equal(t, "kitabisa-web", info.ClientName)
+	assert.Equal(t, "req-1", legacyReqID)
+
+	// non bearer authorization is not stored
+	req = httptest.NewRequest(http.MethodPost, "/graphql", nil)
+	req.Header.Set("Authorization", "Basic Og==")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Empty(t, reqID)
+	assert.Empty(t, token)
+}
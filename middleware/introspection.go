@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kitabisa/perkakas/v2/ctxkeys"
+	phttp "github.com/kitabisa/perkakas/v2/http"
+	"github.com/kitabisa/perkakas/v2/log"
+	"github.com/kitabisa/perkakas/v2/structs"
+	"github.com/kitabisa/perkakas/v2/token/introspection"
+	"github.com/kitabisa/perkakas/v2/token/jwt"
+)
+
+// NewIntrospection validates opaque bearer token using OAuth2 token introspection, see token/introspection package.
+// Wrap the introspector with introspection.NewCachedIntrospector to avoid calling the authorization server on every
+// request. Scope, sub and client_id are mapped into *jwt.UserClaim, stored in context like NewJWT middleware does.
+func NewIntrospection(hctx phttp.HttpHandlerContext, introspector introspection.Introspector) func(next http.Handler) http.Handler {
+	writer := phttp.CustomWriter{
+		C: hctx,
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			log := log.GetSublogger(ctx, "Middleware.Introspection")
+
+			token := bearerToken(r)
+			if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") || token == "" {
+				log.Error().Msg("invalid bearer token")
+				writer.WriteError(w, structs.ErrUnauthorized)
+				return
+			}
+
+			resp, err := introspector.Introspect(ctx, token)
+			if err == nil && !resp.Valid(time.Now()) {
+				err = errors.New("token is not active")
+			}
+
+			if err != nil {
+				log.Error().Msg(err.Error())
+				writer.WriteError(w, structs.ErrUnauthorized)
+				return
+			}
+
+			claims := introspection.ToUserClaim(resp)
+			ctx = jwt.WithClaims(ctx, claims)
+			ctx = context.WithValue(ctx, "token", claims) // deprecated, use jwt.UserClaimFrom or jwt.ClaimsFrom
+			ctx = ctxkeys.WithBearerToken(ctx, token)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kitabisa/perkakas/v2/ctxkeys"
+	phttp "github.com/kitabisa/perkakas/v2/http"
+	"github.com/kitabisa/perkakas/v2/structs"
+	"github.com/kitabisa/perkakas/v2/token/introspection"
+	"github.com/kitabisa/perkakas/v2/token/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntrospection(t *testing.T) {
+	introspector := introspection.StaticIntrospector{
+		"opaque-1": {Active: true, Scope: "donation:read", ClientID: "web", Sub: "12345", Exp: time.Now().Add(time.Hour).Unix()},
+		"opaque-2": {Active: true, Scope: "campaign:read", Sub: "12345"},
+		"expired":  {Active: true, Scope: "donation:read", Sub: "12345", Exp: time.Now().Add(-time.Minute).Unix()},
+	}
+
+	hctx := phttp.NewContextHandler(structs.Meta{})
+
+	var (
+		claims      *jwt.UserClaim
+		bearerToken string
+	)
+	handler := NewIntrospection(hctx, introspector)(
+		RequireScopes(hctx, "donation:read")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, _ = jwt.UserClaimFrom(r.Context())
+			bearerToken = ctxkeys.BearerTokenFrom(r.Context())
+		})),
+	)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer opaque-1")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, claims)
+	assert.Equal(t, int64(12345), claims.UserID)
+	assert.Equal(t, "web", claims.ClientID)
+	assert.Equal(t, []string{"donation:read"}, claims.Scopes)
+	assert.Equal(t, "opaque-1", bearerToken)
+
+	// token without the scope is forbidden
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer opaque-2")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	for _, header := range []string{"", "Bearer ", "Basic opaque-1", "Bearer expired", "Bearer unknown"} {
+		r = httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Authorization", header)
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusUnauthorized, w.Code, header)
+	}
+}
@@ -3,40 +3,144 @@ package middleware
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"regexp"
 	"strings"
+	"time"
 
+	libjwt "github.com/golang-jwt/jwt"
+	"github.com/kitabisa/perkakas/v2/ctxkeys"
 	phttp "github.com/kitabisa/perkakas/v2/http"
 	"github.com/kitabisa/perkakas/v2/structs"
 	"github.com/kitabisa/perkakas/v2/token/jwt"
+	"github.com/kitabisa/perkakas/v2/token/revocation"
 	"github.com/rs/zerolog/log"
 )
 
-func NewJWT(hctx phttp.HttpHandlerContext, signKey []byte) func(next http.Handler) http.Handler {
-	jwtt := jwt.NewJWT(signKey)
+// JWTOption is optional configuration of jwt middleware
+type JWTOption func(*jwtOption)
+
+type jwtOption struct {
+	keySource jwt.KeySource
+	verifier  jwt.Verifier
+	verify    jwt.VerifyOption
+	revoked   revocation.List
+}
+
+// WithKeySource verifies token using key picked by kid from key source, e.g. JWKS, instead of the sign key
+func WithKeySource(source jwt.KeySource) JWTOption {
+	return func(o *jwtOption) {
+		o.keySource = source
+	}
+}
+
+// WithVerifier verifies token using the verifier, e.g. jwt.JWTRSA, instead of the sign key or key source
+func WithVerifier(verifier jwt.Verifier) JWTOption {
+	return func(o *jwtOption) {
+		o.verifier = verifier
+	}
+}
+
+// WithAlgorithms only accepts token signed with the algorithms, preventing algorithm confusion
+func WithAlgorithms(algorithms ...string) JWTOption {
+	return func(o *jwtOption) {
+		o.verify.Algorithms = algorithms
+	}
+}
+
+// WithIssuer only accepts token issued by one of the issuers
+func WithIssuer(issuers ...string) JWTOption {
+	return func(o *jwtOption) {
+		o.verify.Issuers = issuers
+	}
+}
+
+// WithAudience only accepts token whose aud claim contains the audience
+func WithAudience(audience string) JWTOption {
+	return func(o *jwtOption) {
+		o.verify.Audience = audience
+	}
+}
+
+// WithLeeway allows clock skew when checking exp, nbf and iat claim
+func WithLeeway(leeway time.Duration) JWTOption {
+	return func(o *jwtOption) {
+		o.verify.Leeway = leeway
+	}
+}
+
+// WithClaims decodes token into custom claims created by the factory, get it with jwt.ClaimsFrom.
+// Default is *jwt.UserClaim.
+func WithClaims(factory func() libjwt.Claims) JWTOption {
+	return func(o *jwtOption) {
+		o.verify.Claims = factory
+	}
+}
+
+// WithRevocationList rejects token revoked by jti or by its subject, see token/revocation package
+func WithRevocationList(list revocation.List) JWTOption {
+	return func(o *jwtOption) {
+		o.revoked = list
+	}
+}
+
+// jwtAuthenticator verifies bearer token
+type jwtAuthenticator struct {
+	verifier jwt.Verifier
+	option   jwt.VerifyOption
+	revoked  revocation.List
+}
+
+func newJWTAuthenticator(signKey []byte, opts ...JWTOption) *jwtAuthenticator {
+	opt := &jwtOption{}
+	for _, o := range opts {
+		o(opt)
+	}
+
+	verifier := opt.verifier
+	if verifier == nil && opt.keySource != nil {
+		verifier = jwt.NewJWTKeySource(opt.keySource)
+	}
+
+	if verifier == nil {
+		verifier = jwt.NewJWT(signKey)
+	}
+
+	return &jwtAuthenticator{
+		verifier: verifier,
+		option:   opt.verify,
+		revoked:  opt.revoked,
+	}
+}
+
+// NewJWT checks jwt bearer token signed with HS256 sign key. Use WithKeySource or WithVerifier to verify token
+// using JWKS or other algorithm, then sign key is ignored.
+func NewJWT(hctx phttp.HttpHandlerContext, signKey []byte, opts ...JWTOption) func(next http.Handler) http.Handler {
+	auth := newJWTAuthenticator(signKey, opts...)
 	writer := phttp.CustomWriter{
 		C: hctx,
 	}
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			claims, err := bearerAuth(r, jwtt)
+			claims, err := bearerAuth(r, auth)
 			if err != nil {
 				log.Error().Msg(err.Error())
 				writer.WriteError(w, structs.ErrUnauthorized)
 				return
 			}
 
-			parentCtx := r.Context()
-			ctx := context.WithValue(parentCtx, "token", claims)
+			ctx := jwt.WithClaims(r.Context(), claims)
+			ctx = context.WithValue(ctx, "token", claims) // deprecated, use jwt.UserClaimFrom or jwt.ClaimsFrom
+			ctx = ctxkeys.WithBearerToken(ctx, bearerToken(r))
 
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
-func bearerAuth(r *http.Request, jwtt *jwt.JWT) (*jwt.UserClaim, error) {
+func bearerAuth(r *http.Request, auth *jwtAuthenticator) (libjwt.Claims, error) {
 	authorization := r.Header.Get("Authorization")
 	match, err := regexp.MatchString("^Bearer .+", authorization)
 	if !match {
@@ -46,17 +150,45 @@ func bearerAuth(r *http.Request, jwtt *jwt.JWT) (*jwt.UserClaim, error) {
 		return nil, err
 	}
 
-	tokenString := strings.Split(authorization, " ")
-
-	token, err := jwtt.Parse(tokenString[1])
+	token, err := jwt.Verify(bearerToken(r), auth.verifier, auth.option)
 	if err != nil {
 		return nil, err
 	}
 
-	claims, ok := token.Claims.(*jwt.UserClaim)
-	if !ok {
-		return nil, errors.New("invalid jwt token")
+	if auth.revoked != nil {
+		revocationToken, ok := jwt.RevocationToken(token.Claims)
+		if !ok {
+			return nil, fmt.Errorf("claims %T doesn't support revocation", token.Claims)
+		}
+
+		if err = checkRevocation(auth.revoked, revocationToken); err != nil {
+			return nil, err
+		}
+	}
+
+	return token.Claims, nil
+}
+
+// checkRevocation returns error when the token is revoked, or the revocation list can't be checked
+func checkRevocation(list revocation.List, token revocation.Token) error {
+	revoked, err := list.IsRevoked(token)
+	if err != nil {
+		return fmt.Errorf("failed to check token revocation: %w", err)
+	}
+
+	if revoked {
+		return fmt.Errorf("token %s of %s is revoked", token.ID, token.Subject)
+	}
+
+	return nil
+}
+
+// bearerToken gets raw token from Authorization header
+func bearerToken(r *http.Request) string {
+	tokenString := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+	if len(tokenString) != 2 {
+		return ""
 	}
 
-	return claims, nil
+	return tokenString[1]
 }
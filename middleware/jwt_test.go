@@ -0,0 +1,196 @@
+package middleware
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	libjwt "github.com/golang-jwt/jwt"
+	"github.com/kitabisa/perkakas/v2/ctxkeys"
+	phttp "github.com/kitabisa/perkakas/v2/http"
+	"github.com/kitabisa/perkakas/v2/structs"
+	"github.com/kitabisa/perkakas/v2/token/jwt"
+	"github.com/kitabisa/perkakas/v2/token/revocation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestKeySource(t *testing.T) (*jwt.JWTSigner, jwt.KeySource) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	signer, err := jwt.NewJWTSigner("key-1", privateKey)
+	require.NoError(t, err)
+
+	jwks, err := json.Marshal(jwt.JSONWebKeySet{Keys: []jwt.JSONWebKey{signer.JSONWebKey()}})
+	require.NoError(t, err)
+
+	source, err := jwt.NewStaticKeySource(jwks)
+	require.NoError(t, err)
+
+	return signer, source
+}
+
+func TestJWTWithKeySource(t *testing.T) {
+	signer, source := newTestKeySource(t)
+
+	claims := jwt.UserClaim{UserID: 12345}
+	claims.ExpiresAt = time.Now().Add(time.Minute).Unix()
+	token, err := signer.Create(claims)
+	require.NoError(t, err)
+
+	hctx := phttp.NewContextHandler(structs.Meta{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := r.Context().Value("token").(*jwt.UserClaim)
+		require.True(t, ok)
+		assert.Equal(t, int64(12345), claims.UserID)
+		assert.Equal(t, token, ctxkeys.BearerTokenFrom(r.Context()))
+	})
+
+	middlewares := []func(http.Handler) http.Handler{
+		NewJWT(hctx, nil, WithKeySource(source)),
+		NewAuthentication(hctx, AuthOption{KeySource: source}),
+	}
+
+	for _, m := range middlewares {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		m(handler).ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		// token signed with sign key is rejected
+		hsToken, _ := jwt.NewJWT([]byte("abcde")).Create(claims)
+		req = httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+hsToken)
+		rec = httptest.NewRecorder()
+		m(handler).ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	}
+}
+
+type donorClaim struct {
+	DonorID string `json:"donor_id"`
+	libjwt.StandardClaims
+}
+
+func TestJWTWithVerifierAndClaims(t *testing.T) {
+	pubKey, err := ioutil.ReadFile("../token/jwt/dummy.rsa.pub")
+	require.NoError(t, err)
+	privKey, err := ioutil.ReadFile("../token/jwt/dummy.rsa")
+	require.NoError(t, err)
+
+	jwtrsa, err := jwt.NewJWTRSA(pubKey, privKey)
+	require.NoError(t, err)
+
+	claims := donorClaim{DonorID: "d-1"}
+	claims.Issuer = "auth-service"
+	claims.Audience = "donation-service"
+	claims.ExpiresAt = time.Now().Add(-5 * time.Second).Unix()
+	token, err := jwtrsa.Create(claims)
+	require.NoError(t, err)
+
+	hctx := phttp.NewContextHandler(structs.Meta{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := r.Context().Value("token").(*donorClaim)
+		require.True(t, ok)
+		assert.Equal(t, "d-1", claims.DonorID)
+	})
+
+	opts := []JWTOption{
+		WithVerifier(jwtrsa),
+		WithAlgorithms(jwt.AlgRS256),
+		WithIssuer("auth-service"),
+		WithAudience("donation-service"),
+		WithLeeway(time.Minute),
+		WithClaims(func() libjwt.Claims { return &donorClaim{} }),
+	}
+
+	middlewares := []func(http.Handler) http.Handler{
+		NewJWT(hctx, nil, opts...),
+		NewAuthentication(hctx, AuthOption{JWTOptions: opts}),
+	}
+
+	serve := func(m func(http.Handler) http.Handler, token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		m(handler).ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for _, m := range middlewares {
+		assert.Equal(t, http.StatusOK, serve(m, token))
+
+		// HS256 token signed with the public key is rejected
+		hsToken, _ := jwt.NewJWT(pubKey).Create(claims)
+		assert.Equal(t, http.StatusUnauthorized, serve(m, hsToken))
+	}
+
+	// wrong audience
+	m := NewJWT(hctx, nil, WithVerifier(jwtrsa), WithAudience("payment-service"), WithLeeway(time.Minute))
+	assert.Equal(t, http.StatusUnauthorized, serve(m, token))
+
+	// expired without leeway
+	m = NewJWT(hctx, nil, WithVerifier(jwtrsa))
+	assert.Equal(t, http.StatusUnauthorized, serve(m, token))
+}
+
+// revocationList revokes tokens by jti
+type revocationList map[string]bool
+
+func (l revocationList) Revoke(token revocation.Token) error {
+	l[token.ID] = true
+	return nil
+}
+
+func (l revocationList) RevokeSubject(subject string, before time.Time) error {
+	return nil
+}
+
+func (l revocationList) IsRevoked(token revocation.Token) (bool, error) {
+	return l[token.ID], nil
+}
+
+func TestJWTWithRevocationList(t *testing.T) {
+	jwtt := jwt.NewJWT([]byte("abcde"))
+	list := revocationList{}
+
+	claims := jwt.UserClaim{UserID: 12345}
+	claims.Id = "jti-1"
+	claims.ExpiresAt = time.Now().Add(time.Minute).Unix()
+	token, err := jwtt.Create(claims)
+	require.NoError(t, err)
+
+	hctx := phttp.NewContextHandler(structs.Meta{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	middlewares := []func(http.Handler) http.Handler{
+		NewJWT(hctx, []byte("abcde"), WithRevocationList(list)),
+		NewAuthentication(hctx, AuthOption{SignKey: []byte("abcde"), JWTOptions: []JWTOption{WithRevocationList(list)}}),
+	}
+
+	serve := func(m func(http.Handler) http.Handler) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		m(handler).ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for _, m := range middlewares {
+		assert.Equal(t, http.StatusOK, serve(m))
+	}
+
+	rt, _ := jwt.RevocationToken(&claims)
+	require.NoError(t, list.Revoke(rt))
+
+	for _, m := range middlewares {
+		assert.Equal(t, http.StatusUnauthorized, serve(m))
+	}
+}
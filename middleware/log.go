@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"math"
 	"net/http"
 	"strings"
 
@@ -14,6 +13,8 @@ import (
 	zlog "github.com/rs/zerolog/log"
 )
 
+const maxLoggedBodySize = 1000 * 1000
+
 type HttpRequestLoggerMiddleware struct {
 	logger *log.Logger
 }
@@ -34,28 +35,23 @@ func RequestLogger(next http.Handler) http.Handler {
 	fn := func(w http.ResponseWriter, r *http.Request) {
 		ww := cmiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
 
-		payloadSize := math.Ceil(float64(r.ContentLength / 1000))
-
-		var body string
-		if payloadSize <= 1000 { // print request body if size < 1 MB
-			body = httputil.ReadRequestBody(r)
-			if body != "" {
-				bodyClean := new(bytes.Buffer)
-				err := json.Compact(bodyClean, []byte(body))
-
-				// prevent print error "invalid character '-' in numeric literal" when compacting body if payload has blob data
-				if err != nil &&
-					r.Header.Get("Content-type") != "multipart/form-data" &&
-					r.Header.Get("Content-type") != "application/octet-stream" &&
-					r.Header.Get("Content-type") != "application/x-binary" {
-
-					zlog.Err(err).Send()
-				}
+		// print request body if size < 1 MB. Reading is bounded, so a huge or chunked body is not loaded into memory
+		body, _ := httputil.ReadRequestBodyLimit(r, maxLoggedBodySize)
+		if body != "" {
+			bodyClean := new(bytes.Buffer)
+			err := json.Compact(bodyClean, []byte(body))
 
-				body = bodyClean.String()
-				httputil.ExcludeSensitiveRequestBody(&body)
+			// prevent print error "invalid character '-' in numeric literal" when compacting body if payload has blob data
+			if err != nil &&
+				r.Header.Get("Content-type") != "multipart/form-data" &&
+				r.Header.Get("Content-type") != "application/octet-stream" &&
+				r.Header.Get("Content-type") != "application/x-binary" {
 
+				zlog.Err(err).Send()
 			}
+
+			body = bodyClean.String()
+			httputil.ExcludeSensitiveRequestBody(&body)
 		}
 
 		next.ServeHTTP(ww, r)
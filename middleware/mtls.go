@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/kitabisa/perkakas/v2/ctxkeys"
+	phttp "github.com/kitabisa/perkakas/v2/http"
+	"github.com/kitabisa/perkakas/v2/log"
+	"github.com/kitabisa/perkakas/v2/mtls"
+	"github.com/kitabisa/perkakas/v2/structs"
+)
+
+// NewMTLS authenticates service identity from verified client certificate, see mtls package. Request without verified
+// certificate is rejected with ErrUnauthorized, and certificate not allowed by the policy with ErrForbidden.
+// Service identity and its scopes are stored in context, get them with ctxkeys.PrincipalFrom.
+func NewMTLS(hctx phttp.HttpHandlerContext, verifier *mtls.Verifier) func(next http.Handler) http.Handler {
+	writer := phttp.CustomWriter{
+		C: hctx,
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			log := log.GetSublogger(ctx, "Middleware.MTLS")
+
+			identity, err := verifier.Verify(r.TLS)
+			if err != nil {
+				log.Error().Msg(err.Error())
+
+				if errors.Is(err, mtls.ErrIdentityNotAllowed) {
+					writer.WriteError(w, structs.ErrForbidden)
+					return
+				}
+
+				writer.WriteError(w, structs.ErrUnauthorized)
+				return
+			}
+
+			ctx = ctxkeys.WithPrincipal(ctx, ctxkeys.Principal{
+				ID:     identity.ID,
+				Method: ctxkeys.AuthMethodMTLS,
+				Scopes: identity.Scopes,
+			})
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kitabisa/perkakas/v2/ctxkeys"
+	phttp "github.com/kitabisa/perkakas/v2/http"
+	"github.com/kitabisa/perkakas/v2/mtls"
+	"github.com/kitabisa/perkakas/v2/structs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMTLS(t *testing.T) {
+	verifier, err := mtls.NewVerifier(mtls.Policy{
+		CommonNames: []string{"report-cron"},
+		Scopes:      map[string][]string{"report-cron": {"donation:read"}},
+	})
+	require.NoError(t, err)
+
+	hctx := phttp.NewContextHandler(structs.Meta{})
+	handler := NewMTLS(hctx, verifier)(RequireScopes(hctx, "donation:read")(testHandler))
+
+	withCert := func(commonName string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.TLS = &tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: commonName}}}},
+		}
+		return r
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, withCert("report-cron"))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, withCert("other-service"))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	var principal ctxkeys.Principal
+	NewMTLS(hctx, verifier)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ = ctxkeys.PrincipalFrom(r.Context())
+	})).ServeHTTP(httptest.NewRecorder(), withCert("report-cron"))
+	assert.Equal(t, ctxkeys.Principal{ID: "report-cron", Method: ctxkeys.AuthMethodMTLS, Scopes: []string{"donation:read"}}, principal)
+}
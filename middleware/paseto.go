@@ -6,19 +6,69 @@ import (
 	"fmt"
 	"net/http"
 	"regexp"
-	"strings"
 
+	"github.com/kitabisa/perkakas/v2/ctxkeys"
 	phttp "github.com/kitabisa/perkakas/v2/http"
 	"github.com/kitabisa/perkakas/v2/structs"
+	"github.com/kitabisa/perkakas/v2/token/jwt"
 	"github.com/kitabisa/perkakas/v2/token/paseto"
+	"github.com/kitabisa/perkakas/v2/token/revocation"
 	libpaseto "github.com/o1egl/paseto"
 	"github.com/rs/zerolog/log"
 )
 
-func NewPaseto(hctx phttp.HttpHandlerContext, publicKey string) func(next http.Handler) http.Handler {
-	pst, err := paseto.NewAsymmetric(publicKey, "")
-	if err != nil {
-		panic(err)
+// PasetoOption is optional configuration of paseto middleware
+type PasetoOption func(*pasetoOption)
+
+type pasetoOption struct {
+	revoked    revocation.List
+	keyring    *paseto.Keyring
+	validation paseto.ValidationOption
+}
+
+// pasetoDecrypter decrypts or verifies paseto token, implemented by paseto.PasetoAsymmetric and paseto.Keyring
+type pasetoDecrypter interface {
+	Decrypt(encToken string) (libpaseto.JSONToken, string, error)
+}
+
+// WithKeyring verifies v4 token using key picked by kid in the footer, instead of the v2 public key
+func WithKeyring(keyring *paseto.Keyring) PasetoOption {
+	return func(o *pasetoOption) {
+		o.keyring = keyring
+	}
+}
+
+// WithPasetoRevocationList rejects token revoked by jti or by its subject, see token/revocation package
+func WithPasetoRevocationList(list revocation.List) PasetoOption {
+	return func(o *pasetoOption) {
+		o.revoked = list
+	}
+}
+
+// WithPasetoValidation validates iss, aud, sub and required claims of the token, with leeway when checking iat,
+// nbf and exp. By default only iat, nbf and exp are checked.
+func WithPasetoValidation(validation paseto.ValidationOption) PasetoOption {
+	return func(o *pasetoOption) {
+		o.validation = validation
+	}
+}
+
+// NewPaseto checks v2.public paseto bearer token signed with the public key pair. Use WithKeyring to verify v4 token,
+// then public key is ignored.
+func NewPaseto(hctx phttp.HttpHandlerContext, publicKey string, opts ...PasetoOption) func(next http.Handler) http.Handler {
+	opt := &pasetoOption{}
+	for _, o := range opts {
+		o(opt)
+	}
+
+	var pst pasetoDecrypter = opt.keyring
+	if opt.keyring == nil {
+		asymmetric, err := paseto.NewAsymmetric(publicKey, "")
+		if err != nil {
+			panic(err)
+		}
+
+		pst = asymmetric
 	}
 
 	writer := phttp.CustomWriter{
@@ -34,32 +84,42 @@ func NewPaseto(hctx phttp.HttpHandlerContext, publicKey string) func(next http.H
 				return
 			}
 
-			err = token.Validate()
+			err = paseto.Validate(token, opt.validation)
 			if err != nil {
 				tokenValidationErr := fmt.Errorf("paseto token validation: %w", err)
 				log.Error().Msg(tokenValidationErr.Error())
 				writer.WriteError(w, structs.ErrUnauthorized)
+				return
 			}
 
-			parentCtx := r.Context()
-			ctxToken := context.WithValue(parentCtx, "token", token)
-			ctx := context.WithValue(ctxToken, "token_footer", footer)
+			if opt.revoked != nil {
+				if err = checkRevocation(opt.revoked, paseto.RevocationToken(token)); err != nil {
+					log.Error().Msg(err.Error())
+					writer.WriteError(w, structs.ErrUnauthorized)
+					return
+				}
+			}
+
+			ctx := paseto.WithToken(r.Context(), token, footer)
+			ctx = jwt.WithUserClaim(ctx, paseto.ToUserClaim(token))
+			// deprecated, use paseto.TokenFrom
+			ctx = context.WithValue(ctx, "token", token)
+			ctx = context.WithValue(ctx, "token_footer", footer)
+			ctx = ctxkeys.WithBearerToken(ctx, bearerToken(r))
 
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
-func decrypt(r *http.Request, pst *paseto.PasetoAsymmetric) (libpaseto.JSONToken, string, error) {
+func decrypt(r *http.Request, pst pasetoDecrypter) (libpaseto.JSONToken, string, error) {
 	authorization := r.Header.Get("Authorization")
 	match, err := regexp.MatchString("^Bearer .+", authorization)
 	if err != nil || !match {
 		return libpaseto.JSONToken{}, "", errors.New("invalid bearer token")
 	}
 
-	tokenString := strings.Split(authorization, " ")
-
-	token, footer, err := pst.Decrypt(tokenString[1])
+	token, footer, err := pst.Decrypt(bearerToken(r))
 	if err != nil {
 		return libpaseto.JSONToken{}, "", err
 	}
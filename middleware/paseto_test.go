@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	phttp "github.com/kitabisa/perkakas/v2/http"
+	"github.com/kitabisa/perkakas/v2/structs"
+	"github.com/kitabisa/perkakas/v2/token/jwt"
+	"github.com/kitabisa/perkakas/v2/token/paseto"
+	libpaseto "github.com/o1egl/paseto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestKeyring(t *testing.T, kid string) *paseto.Keyring {
+	seed := make([]byte, 32)
+	_, err := rand.Read(seed)
+	require.NoError(t, err)
+
+	key, err := paseto.NewPublicKeyFromSeed(kid, seed)
+	require.NoError(t, err)
+
+	keyring := paseto.NewKeyring(paseto.KeyringOption{})
+	require.NoError(t, keyring.Rotate(key))
+
+	return keyring
+}
+
+func newTestPasetoToken() libpaseto.JSONToken {
+	now := time.Now()
+	token := libpaseto.JSONToken{
+		Jti:        "706cbfce-c031-4a44-815e-030f963f7d4e",
+		Subject:    "cac2ee7e-70d0-4220-badd-7b5695f53ad8",
+		Expiration: now.Add(time.Hour),
+		IssuedAt:   now,
+		NotBefore:  now,
+	}
+
+	return token
+}
+
+func serveBearer(m func(http.Handler) http.Handler, handler http.Handler, token string) int {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	m(handler).ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestPasetoWithKeyring(t *testing.T) {
+	keyring := newTestKeyring(t, "2021-09")
+	encToken, err := keyring.Encrypt(newTestPasetoToken(), "")
+	require.NoError(t, err)
+
+	hctx := phttp.NewContextHandler(structs.Meta{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, footer, ok := paseto.TokenFrom(r.Context())
+		require.True(t, ok)
+		assert.Equal(t, "cac2ee7e-70d0-4220-badd-7b5695f53ad8", token.Subject)
+		assert.JSONEq(t, `{"kid":"2021-09"}`, footer)
+	})
+
+	m := NewPaseto(hctx, "", WithKeyring(keyring))
+	assert.Equal(t, http.StatusOK, serveBearer(m, handler, encToken))
+
+	// token signed by other keyring with the same kid
+	otherToken, _ := newTestKeyring(t, "2021-09").Encrypt(newTestPasetoToken(), "")
+	assert.Equal(t, http.StatusUnauthorized, serveBearer(m, handler, otherToken))
+}
+
+func TestPasetoValidation(t *testing.T) {
+	keyring := newTestKeyring(t, "2021-09")
+	hctx := phttp.NewContextHandler(structs.Meta{})
+
+	called := false
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+
+		claim, ok := jwt.UserClaimFrom(r.Context())
+		require.True(t, ok)
+		assert.Equal(t, int64(12345), claim.UserID)
+		assert.Equal(t, []string{"campaign:read"}, claim.Scopes)
+	})
+
+	m := NewPaseto(hctx, "", WithKeyring(keyring), WithPasetoValidation(paseto.ValidationOption{
+		Issuers:        []string{"Kulonuwun"},
+		RequiredClaims: []string{"user_id"},
+		Leeway:         time.Minute,
+	}))
+
+	token := newTestPasetoToken()
+	token.Issuer = "Kulonuwun"
+	token.Expiration = time.Now().Add(-30 * time.Second)
+	token.Set("user_id", "12345")
+	token.Set("scopes", "campaign:read")
+	encToken, _ := keyring.Encrypt(token, "")
+	assert.Equal(t, http.StatusOK, serveBearer(m, handler, encToken))
+	assert.True(t, called)
+
+	// validation failure doesn't call next handler
+	called = false
+	token.Issuer = "Evil"
+	encToken, _ = keyring.Encrypt(token, "")
+	assert.Equal(t, http.StatusUnauthorized, serveBearer(m, handler, encToken))
+	assert.False(t, called)
+
+	token.Issuer = "Kulonuwun"
+	token.Expiration = time.Now().Add(-2 * time.Minute)
+	encToken, _ = keyring.Encrypt(token, "")
+	assert.Equal(t, http.StatusUnauthorized, serveBearer(m, handler, encToken))
+	assert.False(t, called)
+
+	// works with authorization middleware
+	token.Expiration = time.Now().Add(time.Minute)
+	encToken, _ = keyring.Encrypt(token, "")
+	authz := func(next http.Handler) http.Handler {
+		return m(RequireScopes(hctx, "campaign:write")(next))
+	}
+	assert.Equal(t, http.StatusForbidden, serveBearer(authz, handler, encToken))
+}
@@ -6,20 +6,30 @@ import (
 
 	"github.com/kitabisa/perkakas/v2/ctxkeys"
 	"github.com/rs/zerolog/log"
+	uuid "github.com/satori/go.uuid"
 )
 
-// RequestIDToContextAndLogMiddleware set X-Ktbs-Request-ID header value and logger to context
+// RequestIDToContextAndLogMiddleware set X-Ktbs-Request-ID header value and logger to context.
+// If the header is missing, new request ID will be generated. The request ID is echoed in response header.
 func RequestIDToContextAndLogMiddleware(next http.Handler) http.Handler {
 	fn := func(w http.ResponseWriter, r *http.Request) {
 		reqID := r.Header.Get(ctxkeys.CtxXKtbsRequestID.String())
-		r = r.WithContext(context.WithValue(r.Context(), ctxkeys.CtxXKtbsRequestID.String(), reqID))
+		if reqID == "" {
+			reqID = uuid.NewV4().String()
+			r.Header.Set(ctxkeys.CtxXKtbsRequestID.String(), reqID)
+		}
+
+		w.Header().Set(ctxkeys.CtxXKtbsRequestID.String(), reqID)
+
+		ctx := ctxkeys.WithRequestID(r.Context(), reqID)
+		ctx = context.WithValue(ctx, ctxkeys.CtxXKtbsRequestID.String(), reqID) // compatibility with existing logic in all our services
 
 		logger := log.With().
 			Str(ctxkeys.CtxXKtbsRequestID.String(), reqID).
 			Logger()
-		r = r.WithContext(context.WithValue(r.Context(), ctxkeys.CtxLogger, logger))
+		ctx = context.WithValue(ctx, ctxkeys.CtxLogger, logger)
 
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	}
 	return http.HandlerFunc(fn)
 }
@@ -13,6 +13,7 @@ import (
 	"github.com/rs/zerolog/log"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/kitabisa/perkakas/v2/ctxkeys"
 	plog "github.com/kitabisa/perkakas/v2/log"
 
 	uuid "github.com/satori/go.uuid"
@@ -47,6 +48,30 @@ func TestRequestIDToContextAndLogMiddleware(t *testing.T) {
 	assert.Contains(t, out.String(), reqID)
 }
 
+func TestRequestIDToContextAndLogMiddlewareGenerateID(t *testing.T) {
+	var ctxReqID string
+	handlerToTest := RequestIDToContextAndLogMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctxReqID = ctxkeys.RequestIDFrom(r.Context())
+	}))
+
+	rec := httptest.NewRecorder()
+	handlerToTest.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	respReqID := rec.Header().Get("X-Ktbs-Request-ID")
+	_, err := uuid.FromString(respReqID)
+	assert.NoError(t, err)
+	assert.Equal(t, respReqID, ctxReqID)
+
+	reqID := uuid.NewV4().String()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Ktbs-Request-ID", reqID)
+
+	rec = httptest.NewRecorder()
+	handlerToTest.ServeHTTP(rec, req)
+	assert.Equal(t, reqID, rec.Header().Get("X-Ktbs-Request-ID"))
+	assert.Equal(t, reqID, ctxReqID)
+}
+
 func TestRaceRequestIDToContextAndLogMiddleware(t *testing.T) {
 	handlerToTest := RequestIDToContextAndLogMiddleware(RequestLogger(testReqIDHandler))
 	ts := httptest.NewServer(handlerToTest)
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	opt := DefaultSecurityHeaderOption()
+	opt.HSTSPreload = true
+	handler := NewSecurityHeaders(opt)(testHandler)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, "max-age=31536000; includeSubDomains; preload", rec.Header().Get("Strict-Transport-Security"))
+	assert.Equal(t, "default-src 'none'; frame-ancestors 'none'", rec.Header().Get("Content-Security-Policy"))
+	assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "strict-origin-when-cross-origin", rec.Header().Get("Referrer-Policy"))
+	assert.Equal(t, "DENY", rec.Header().Get("X-Frame-Options"))
+}
+
+func TestSecurityHeadersEmptyOption(t *testing.T) {
+	handler := NewSecurityHeaders(SecurityHeaderOption{})(testHandler)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Empty(t, rec.Header().Get("Strict-Transport-Security"))
+	assert.Empty(t, rec.Header().Get("X-Content-Type-Options"))
+}
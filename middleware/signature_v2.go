@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/kitabisa/perkakas/v2/ctxkeys"
+	phttp "github.com/kitabisa/perkakas/v2/http"
+	"github.com/kitabisa/perkakas/v2/log"
+	"github.com/kitabisa/perkakas/v2/signature"
+	"github.com/kitabisa/perkakas/v2/structs"
+)
+
+// NewSignatureV2 verifies canonical request signature of signature.SignerV2. Client name and key id of the signing
+// key are stored in context, get them with ctxkeys.PrincipalFrom. Request body is read into memory to verify its hash,
+// up to VerifierV2Option.MaxBodySize. Use NewBodyLimit before it to set tighter limit per route.
+func NewSignatureV2(hctx phttp.HttpHandlerContext, verifier *signature.VerifierV2) func(next http.Handler) http.Handler {
+	writer := phttp.CustomWriter{
+		C: hctx,
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			log := log.GetSublogger(ctx, "Middleware.SignatureV2")
+
+			key, err := verifier.Verify(r)
+			if err != nil {
+				log.Error().Msg(err.Error())
+
+				switch {
+				case errors.Is(err, signature.ErrSignatureExpired):
+					writer.WriteError(w, structs.ErrInvalidHeaderTime)
+				case errors.Is(err, signature.ErrSignatureReplayed):
+					writer.WriteError(w, structs.ErrRequestReplayed)
+				case errors.Is(err, signature.ErrBodyTooLarge):
+					writer.WriteError(w, structs.ErrPayloadTooLarge)
+				case errors.Is(err, signature.ErrMissingSignature), errors.Is(err, signature.ErrInvalidSignature),
+					errors.Is(err, signature.ErrKeyNotFound):
+					writer.WriteError(w, structs.ErrInvalidHeaderSignature)
+				default:
+					writer.WriteError(w, structs.ErrUnknown)
+				}
+				return
+			}
+
+			ctx = ctxkeys.WithPrincipal(ctx, ctxkeys.Principal{
+				ID:     key.ClientName,
+				Method: ctxkeys.AuthMethodSignature,
+				KeyID:  key.ID,
+			})
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kitabisa/perkakas/v2/ctxkeys"
+	phttp "github.com/kitabisa/perkakas/v2/http"
+	"github.com/kitabisa/perkakas/v2/signature"
+	"github.com/kitabisa/perkakas/v2/structs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignatureV2(t *testing.T) {
+	key := signature.Key{ID: "donation-2021-09", ClientName: "donation-service", Secret: "secret"}
+	verifier := signature.NewVerifierV2(signature.VerifierV2Option{Keys: signature.NewMemoryKeyStore(key)})
+	hctx := phttp.NewContextHandler(structs.Meta{})
+
+	var principal ctxkeys.Principal
+	handler := NewSignatureV2(hctx, verifier)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ = ctxkeys.PrincipalFrom(r.Context())
+	}))
+
+	newRequest := func() *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/donations", strings.NewReader(`{"amount":10000}`))
+		require.NoError(t, (&signature.SignerV2{Key: key}).Sign(r))
+		return r
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, newRequest())
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, ctxkeys.Principal{ID: "donation-service", Method: ctxkeys.AuthMethodSignature, KeyID: key.ID}, principal)
+
+	r := newRequest()
+	r.Header.Set(signature.TimeHeader, strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Contains(t, w.Body.String(), structs.ErrInvalidHeaderTime.ResponseCode)
+
+	r = newRequest()
+	r.Body = http.NoBody
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Contains(t, w.Body.String(), structs.ErrInvalidHeaderSignature.ResponseCode)
+
+	// body larger than the verifier limit
+	verifier = signature.NewVerifierV2(signature.VerifierV2Option{Keys: signature.NewMemoryKeyStore(key), MaxBodySize: 8})
+	w = httptest.NewRecorder()
+	NewSignatureV2(hctx, verifier)(testHandler).ServeHTTP(w, newRequest())
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), structs.ErrPayloadTooLarge.ResponseCode)
+}
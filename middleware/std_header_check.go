@@ -3,13 +3,22 @@ package middleware
 import (
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/asaskevich/govalidator"
+	"github.com/kitabisa/perkakas/v2/ctxkeys"
 	phttp "github.com/kitabisa/perkakas/v2/http"
+	"github.com/kitabisa/perkakas/v2/log"
+	"github.com/kitabisa/perkakas/v2/nonce"
 	"github.com/kitabisa/perkakas/v2/signature"
 	"github.com/kitabisa/perkakas/v2/structs"
 )
 
+// DefaultClockSkew is max difference between X-Ktbs-Time and server time used with WithNonceStore, when WithClockSkew
+// is not set
+const DefaultClockSkew = 5 * time.Minute
+
 type Header struct {
 	XKtbsRequestID     string `valid:"uuidv4,required"`
 	XKtbsAPIVersion    string `valid:"semver,required"`
@@ -20,10 +29,50 @@ type Header struct {
 	// Optional
 	XKtbsSignature string `valid:"optional"`
 	XKtbsTime      string `valid:"int,optional"`
+	XKtbsNonce     string `valid:"printableascii,optional"`
 	Authorization  string `valid:"optional"`
 }
 
-func NewHeaderCheck(hctx phttp.HttpHandlerContext, secretKey string) func(next http.Handler) http.Handler {
+// HeaderCheckOption is optional configuration of header check middleware
+type HeaderCheckOption func(*headerCheckOption)
+
+type headerCheckOption struct {
+	clockSkew  time.Duration
+	nonceStore nonce.Store
+}
+
+// WithClockSkew rejects request whose X-Ktbs-Time is more than skew from server time with ErrInvalidHeaderTime.
+// The check is disabled by default, zero or negative skew disables it unless WithNonceStore is set.
+func WithClockSkew(skew time.Duration) HeaderCheckOption {
+	return func(o *headerCheckOption) {
+		o.clockSkew = skew
+	}
+}
+
+// WithNonceStore requires X-Ktbs-Nonce header, so a signature is accepted once. Reused nonce is rejected with
+// ErrRequestReplayed. Nonce is remembered for twice the clock skew, older request is already rejected by its time,
+// so the clock skew check is always enabled with DefaultClockSkew when WithClockSkew is not set or disabled.
+func WithNonceStore(store nonce.Store) HeaderCheckOption {
+	return func(o *headerCheckOption) {
+		o.nonceStore = store
+	}
+}
+
+// NewHeaderCheck validates X-Ktbs-* headers and X-Ktbs-Signature, which is hmac of client name, time and nonce
+// when X-Ktbs-Nonce is sent.
+func NewHeaderCheck(hctx phttp.HttpHandlerContext, secretKey string, opts ...HeaderCheckOption) func(next http.Handler) http.Handler {
+	opt := &headerCheckOption{}
+	for _, o := range opts {
+		o(opt)
+	}
+
+	// without the time check, a captured request could be replayed once its nonce expires
+	if opt.nonceStore != nil && opt.clockSkew <= 0 {
+		opt.clockSkew = DefaultClockSkew
+	}
+
+	nonceTTL := 2 * opt.clockSkew
+
 	writer := phttp.CustomWriter{
 		C: hctx,
 	}
@@ -38,23 +87,61 @@ func NewHeaderCheck(hctx phttp.HttpHandlerContext, secretKey string) func(next h
 				XKtbsClientName:    r.Header.Get("X-Ktbs-Client-Name"),
 				XKtbsSignature:     r.Header.Get("X-Ktbs-Signature"),
 				XKtbsTime:          r.Header.Get("X-Ktbs-Time"),
+				XKtbsNonce:         r.Header.Get("X-Ktbs-Nonce"),
 				Authorization:      r.Header.Get("Authorization"),
 			}
 
 			_, err := govalidator.ValidateStruct(header)
-			if err != nil {
+			if err != nil || (opt.nonceStore != nil && header.XKtbsNonce == "") {
 				writer.WriteError(w, structs.ErrInvalidHeader)
 				return
 			}
 
-			data := fmt.Sprintf("%s%s", header.XKtbsClientName, header.XKtbsTime)
+			if opt.clockSkew > 0 && !withinClockSkew(header.XKtbsTime, opt.clockSkew) {
+				writer.WriteError(w, structs.ErrInvalidHeaderTime)
+				return
+			}
+
+			data := fmt.Sprintf("%s%s%s", header.XKtbsClientName, header.XKtbsTime, header.XKtbsNonce)
 			match := signature.IsMatchHmac(data, header.XKtbsSignature, secretKey)
 			if !match {
 				writer.WriteError(w, structs.ErrInvalidHeaderSignature)
 				return
 			}
 
-			next.ServeHTTP(w, r)
+			// nonce is checked after signature, so unsigned request can't fill the store
+			if opt.nonceStore != nil {
+				first, err := opt.nonceStore.MarkUsed(header.XKtbsClientName+":"+header.XKtbsNonce, nonceTTL)
+				if err != nil {
+					log := log.GetSublogger(r.Context(), "Middleware.HeaderCheck")
+					log.Error().Err(err).Msg("failed to check nonce")
+					writer.WriteError(w, structs.ErrUnknown)
+					return
+				}
+
+				if !first {
+					writer.WriteError(w, structs.ErrRequestReplayed)
+					return
+				}
+			}
+
+			ctx := ctxkeys.WithClientInfo(r.Context(), ctxkeys.ClientInfoFromHeader(r.Header))
+			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
+
+// withinClockSkew checks unix timestamp is within the skew of server time
+func withinClockSkew(timestamp string, skew time.Duration) bool {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+
+	diff := time.Since(time.Unix(unix, 0))
+	if diff < 0 {
+		diff = -diff
+	}
+
+	return diff <= skew
+}
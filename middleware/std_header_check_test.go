@@ -11,9 +11,11 @@ import (
 	"time"
 
 	phttp "github.com/kitabisa/perkakas/v2/http"
+	"github.com/kitabisa/perkakas/v2/nonce"
 	"github.com/kitabisa/perkakas/v2/signature"
 	"github.com/kitabisa/perkakas/v2/structs"
 	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
 )
 
 var testHandler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -60,3 +62,104 @@ func TestStdHeaderValidation(t *testing.T) {
 		t.Fatal(res.StatusCode, string(greeting))
 	}
 }
+
+func newSignedHeaderRequest(clientName string, now time.Time, nonce, secretKey string) *http.Request {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Ktbs-Request-ID", uuid.NewV4().String())
+	req.Header.Set("X-Ktbs-Api-Version", "1.0.1")
+	req.Header.Set("X-Ktbs-Client-Version", "1.1.1")
+	req.Header.Set("X-Ktbs-Platform-Name", "android")
+	req.Header.Set("X-Ktbs-Client-Name", clientName)
+	req.Header.Set("X-Ktbs-Time", timestamp)
+	if nonce != "" {
+		req.Header.Set("X-Ktbs-Nonce", nonce)
+	}
+	req.Header.Set("X-Ktbs-Signature", signature.GenerateHmac(clientName+timestamp+nonce, secretKey))
+
+	return req
+}
+
+func TestHeaderCheckClockSkew(t *testing.T) {
+	hctx := phttp.NewContextHandler(structs.Meta{})
+	handler := NewHeaderCheck(hctx, "key", WithClockSkew(5*time.Minute))(testHandler)
+
+	tests := []struct {
+		name   string
+		now    time.Time
+		status int
+	}{
+		{name: "current", now: time.Now(), status: http.StatusOK},
+		{name: "slightly ahead", now: time.Now().Add(time.Minute), status: http.StatusOK},
+		{name: "too old", now: time.Now().Add(-10 * time.Minute), status: http.StatusBadRequest},
+		{name: "too far ahead", now: time.Now().Add(10 * time.Minute), status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, newSignedHeaderRequest("kitabisa-apps", tt.now, "", "key"))
+			assert.Equal(t, tt.status, w.Code)
+			if tt.status != http.StatusOK {
+				assert.Contains(t, w.Body.String(), structs.ErrInvalidHeaderTime.ResponseCode)
+			}
+		})
+	}
+
+	// the check is disabled by default
+	w := httptest.NewRecorder()
+	NewHeaderCheck(hctx, "key")(testHandler).ServeHTTP(w, newSignedHeaderRequest("kitabisa-apps", time.Now().Add(-time.Hour), "", "key"))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// nonce store can't disable the check, otherwise old request could be replayed after its nonce expires
+	w = httptest.NewRecorder()
+	NewHeaderCheck(hctx, "key", WithClockSkew(-1), WithNonceStore(nonce.NewMemoryStore()))(testHandler).
+		ServeHTTP(w, newSignedHeaderRequest("kitabisa-apps", time.Now().Add(-time.Hour), "n-1", "key"))
+	assert.Contains(t, w.Body.String(), structs.ErrInvalidHeaderTime.ResponseCode)
+}
+
+func TestHeaderCheckWithoutTime(t *testing.T) {
+	hctx := phttp.NewContextHandler(structs.Meta{})
+
+	// existing client which doesn't send X-Ktbs-Time is still accepted
+	req := newSignedHeaderRequest("kitabisa-apps", time.Now(), "", "key")
+	req.Header.Del("X-Ktbs-Time")
+	req.Header.Set("X-Ktbs-Signature", signature.GenerateHmac("kitabisa-apps", "key"))
+
+	w := httptest.NewRecorder()
+	NewHeaderCheck(hctx, "key")(testHandler).ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestHeaderCheckNonce(t *testing.T) {
+	hctx := phttp.NewContextHandler(structs.Meta{})
+	handler := NewHeaderCheck(hctx, "key", WithNonceStore(nonce.NewMemoryStore()))(testHandler)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, newSignedHeaderRequest("kitabisa-apps", time.Now(), "n-1", "key"))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// replayed request
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, newSignedHeaderRequest("kitabisa-apps", time.Now(), "n-1", "key"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), structs.ErrRequestReplayed.ResponseCode)
+
+	// same nonce of other client is accepted
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, newSignedHeaderRequest("kitabisa-web", time.Now(), "n-1", "key"))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// nonce is signed
+	req := newSignedHeaderRequest("kitabisa-apps", time.Now(), "n-2", "key")
+	req.Header.Set("X-Ktbs-Nonce", "n-3")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Contains(t, w.Body.String(), structs.ErrInvalidHeaderSignature.ResponseCode)
+
+	// nonce is required
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, newSignedHeaderRequest("kitabisa-apps", time.Now(), "", "key"))
+	assert.Contains(t, w.Body.String(), structs.ErrInvalidHeader.ResponseCode)
+}
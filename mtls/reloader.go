@@ -0,0 +1,184 @@
+package mtls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// TLSOption is certificate files of the service
+type TLSOption struct {
+	CertFile string // PEM certificate of the service, may contain intermediate certificates
+	KeyFile  string // PEM private key of the service
+	CAFile   string // PEM CA certificates trusted to verify the peer
+	// ReloadInterval is minimum interval between checking the files for change. Default 1 minute.
+	ReloadInterval time.Duration
+}
+
+// Reloader reloads certificate and CA from disk when the files change, so short lived certificate can be rotated
+// without restart. Files are checked during handshake, at most once per ReloadInterval. Failed reload is logged
+// and the previous certificate is kept.
+type Reloader struct {
+	option TLSOption
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	caPool    *x509.CertPool
+	modTimes  [3]time.Time
+	checkedAt time.Time
+}
+
+// NewReloader loads the files. It returns error when the files can't be loaded.
+func NewReloader(option TLSOption) (*Reloader, error) {
+	if option.ReloadInterval <= 0 {
+		option.ReloadInterval = time.Minute
+	}
+
+	r := &Reloader{
+		option: option,
+	}
+
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// ServerConfig creates server tls config requiring client certificate verified by the CA. NextProtos is h2 and
+// http/1.1 by default, since servers and grpc credentials add h2 to their own copy of the config, which isn't used
+// for the handshake. Change the returned config, e.g. its NextProtos, before use, every handshake clones it.
+func (r *Reloader) ServerConfig() *tls.Config {
+	base := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		ClientAuth:     tls.RequireAndVerifyClientCert,
+		GetCertificate: r.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}
+
+	base.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		r.maybeReload()
+
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+
+		c := base.Clone()
+		c.ClientCAs = r.caPool
+		c.GetConfigForClient = nil
+		return c, nil
+	}
+
+	return base
+}
+
+// ClientConfig creates client tls config sending the certificate. Server is verified by the CA loaded at this call,
+// create new config after the CA is rotated.
+func (r *Reloader) ClientConfig() *tls.Config {
+	r.maybeReload()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return &tls.Config{
+		MinVersion:           tls.VersionTLS12,
+		RootCAs:              r.caPool,
+		GetClientCertificate: r.GetClientCertificate,
+	}
+}
+
+// GetCertificate is tls.Config GetCertificate returning the current certificate
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.maybeReload()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.cert, nil
+}
+
+// GetClientCertificate is tls.Config GetClientCertificate returning the current certificate
+func (r *Reloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.GetCertificate(nil)
+}
+
+func (r *Reloader) maybeReload() {
+	r.mu.RLock()
+	due := time.Since(r.checkedAt) >= r.option.ReloadInterval
+	r.mu.RUnlock()
+
+	if !due {
+		return
+	}
+
+	if err := r.reload(); err != nil {
+		log.Error().Err(err).Str("cert_file", r.option.CertFile).Msg("failed to reload tls certificate, keeping previous certificate")
+	}
+}
+
+func (r *Reloader) reload() error {
+	modTimes, err := r.statFiles()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.checkedAt = time.Now()
+	if err != nil {
+		return err
+	}
+
+	if r.cert != nil && sameTimes(modTimes, r.modTimes) {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.option.CertFile, r.option.KeyFile)
+	if err != nil {
+		return fmt.Errorf("mtls: failed to load certificate: %w", err)
+	}
+
+	caPEM, err := ioutil.ReadFile(r.option.CAFile)
+	if err != nil {
+		return fmt.Errorf("mtls: failed to read CA file: %w", err)
+	}
+
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM(caPEM) {
+		return errors.New("mtls: no certificate found in CA file")
+	}
+
+	r.cert = &cert
+	r.caPool = caPool
+	r.modTimes = modTimes
+	return nil
+}
+
+// statFiles gets modification time of the files
+func (r *Reloader) statFiles() ([3]time.Time, error) {
+	var modTimes [3]time.Time
+	for i, file := range []string{r.option.CertFile, r.option.KeyFile, r.option.CAFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return modTimes, fmt.Errorf("mtls: %w", err)
+		}
+
+		modTimes[i] = info.ModTime()
+	}
+
+	return modTimes, nil
+}
+
+func sameTimes(a, b [3]time.Time) bool {
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,234 @@
+package mtls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue issues leaf certificate for server and client auth, returning PEM certificate and key
+func (ca *testCA) issue(t *testing.T, commonName string, serial int64) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFiles(t *testing.T, dir string, cert, key, ca []byte, modTime time.Time) TLSOption {
+	option := TLSOption{
+		CertFile: filepath.Join(dir, "tls.crt"),
+		KeyFile:  filepath.Join(dir, "tls.key"),
+		CAFile:   filepath.Join(dir, "ca.crt"),
+	}
+
+	for file, content := range map[string][]byte{option.CertFile: cert, option.KeyFile: key, option.CAFile: ca} {
+		require.NoError(t, ioutil.WriteFile(file, content, 0600))
+		require.NoError(t, os.Chtimes(file, modTime, modTime))
+	}
+
+	return option
+}
+
+func TestReloaderHandshake(t *testing.T) {
+	ca := newTestCA(t)
+
+	serverDir, err := ioutil.TempDir("", "mtls-server")
+	require.NoError(t, err)
+	defer os.RemoveAll(serverDir)
+
+	clientDir, err := ioutil.TempDir("", "mtls-client")
+	require.NoError(t, err)
+	defer os.RemoveAll(clientDir)
+
+	cert, key := ca.issue(t, "campaign-service", 2)
+	server, err := NewReloader(writeFiles(t, serverDir, cert, key, ca.pem, time.Now()))
+	require.NoError(t, err)
+
+	cert, key = ca.issue(t, "report-cron", 3)
+	clientOption := writeFiles(t, clientDir, cert, key, ca.pem, time.Now().Add(-time.Minute))
+	clientOption.ReloadInterval = time.Nanosecond
+	client, err := NewReloader(clientOption)
+	require.NoError(t, err)
+
+	verifier, err := NewVerifier(Policy{CommonNames: []string{"report-.*"}})
+	require.NoError(t, err)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, err := verifier.Verify(r.TLS)
+		if err != nil {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		w.Write([]byte(identity.ID))
+	}))
+	srv.TLS = server.ServerConfig()
+	srv.StartTLS()
+	defer srv.Close()
+
+	get := func() (int, string) {
+		httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: client.ClientConfig(), DisableKeepAlives: true}}
+		resp, err := httpClient.Get(srv.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	status, body := get()
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "report-cron", body)
+
+	// rotated client certificate is used without restart
+	cert, key = ca.issue(t, "report-worker", 4)
+	writeFiles(t, clientDir, cert, key, ca.pem, time.Now())
+
+	status, body = get()
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "report-worker", body)
+
+	// broken file keeps previous certificate
+	require.NoError(t, ioutil.WriteFile(clientOption.KeyFile, []byte("broken"), 0600))
+	require.NoError(t, os.Chtimes(clientOption.KeyFile, time.Now().Add(time.Minute), time.Now().Add(time.Minute)))
+
+	status, body = get()
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "report-worker", body)
+
+	// client without certificate is rejected during handshake
+	noCert := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: client.ClientConfig().RootCAs}}}
+	_, err = noCert.Get(srv.URL)
+	assert.Error(t, err)
+}
+
+func TestReloaderServerConfigALPN(t *testing.T) {
+	ca := newTestCA(t)
+
+	dir, err := ioutil.TempDir("", "mtls-alpn")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cert, key := ca.issue(t, "campaign-service", 2)
+	reloader, err := NewReloader(writeFiles(t, dir, cert, key, ca.pem, time.Now()))
+	require.NoError(t, err)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+	srv.EnableHTTP2 = true
+	srv.TLS = reloader.ServerConfig()
+	srv.StartTLS()
+	defer srv.Close()
+
+	handshake := func(protos ...string) string {
+		clientConfig := reloader.ClientConfig()
+		clientConfig.NextProtos = protos
+
+		conn, err := tls.Dial("tcp", srv.Listener.Addr().String(), clientConfig)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		return conn.ConnectionState().NegotiatedProtocol
+	}
+
+	assert.Equal(t, "h2", handshake("h2", "http/1.1"))
+	assert.Equal(t, "http/1.1", handshake("http/1.1"))
+
+	httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: reloader.ClientConfig(), ForceAttemptHTTP2: true}}
+	resp, err := httpClient.Get(srv.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, "HTTP/2.0", string(body))
+
+	// change of the returned config is used by the handshake
+	config := reloader.ServerConfig()
+	config.NextProtos = []string{"http/1.1"}
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	require.NoError(t, err)
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	clientConfig := reloader.ClientConfig()
+	clientConfig.NextProtos = []string{"h2", "http/1.1"}
+	conn, err := tls.Dial("tcp", listener.Addr().String(), clientConfig)
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Equal(t, "http/1.1", conn.ConnectionState().NegotiatedProtocol)
+}
+
+func TestNewReloaderMissingFile(t *testing.T) {
+	_, err := NewReloader(TLSOption{CertFile: "missing.crt", KeyFile: "missing.key", CAFile: "missing.ca"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,156 @@
+// Package mtls authenticates service identity from verified client certificate of mutual TLS
+
+package mtls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrNoPeerCertificate is returned when connection is not TLS or client certificate is not verified
+	ErrNoPeerCertificate = errors.New("mtls: no verified peer certificate")
+	// ErrIdentityNotAllowed is returned when client certificate doesn't match the policy
+	ErrIdentityNotAllowed = errors.New("mtls: identity not allowed")
+)
+
+// Policy is allowed identities of client certificate. Certificate matching any of them is allowed.
+type Policy struct {
+	// SPIFFEIDs is allowed SPIFFE ID in URI SAN. Trailing "/*" matches any path below,
+	// e.g. spiffe://kitabisa.com/ns/payment/*
+	SPIFFEIDs []string
+	// DNSNames is allowed DNS SAN. Leading "*." matches exactly one label, e.g. *.payment.svc.cluster.local
+	DNSNames []string
+	// CommonNames is allowed subject common name regular expression, matched against the whole common name
+	CommonNames []string
+	// Scopes is scopes granted per identity id, checked by authorization middleware
+	Scopes map[string][]string
+}
+
+// Identity is authenticated service identity
+type Identity struct {
+	// ID is the SPIFFE ID, DNS SAN or common name which matched the policy, checked in that order
+	ID         string
+	SPIFFEID   string
+	DNSNames   []string
+	CommonName string
+	Scopes     []string
+}
+
+// Verifier checks verified client certificate against the policy
+type Verifier struct {
+	policy      Policy
+	commonNames []*regexp.Regexp
+}
+
+// NewVerifier creates verifier. It returns error when common name pattern is invalid.
+func NewVerifier(policy Policy) (*Verifier, error) {
+	v := &Verifier{
+		policy: policy,
+	}
+
+	for _, pattern := range policy.CommonNames {
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("mtls: invalid common name pattern %s: %w", pattern, err)
+		}
+
+		v.commonNames = append(v.commonNames, re)
+	}
+
+	return v, nil
+}
+
+// Verify gets identity of the verified client certificate of the connection
+func (v *Verifier) Verify(state *tls.ConnectionState) (*Identity, error) {
+	// peer certificate without verified chain is not trusted, e.g. tls.RequestClientCert
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil, ErrNoPeerCertificate
+	}
+
+	return v.VerifyCertificate(state.VerifiedChains[0][0])
+}
+
+// VerifyCertificate gets identity of the client certificate, which must already be verified against trusted CA
+func (v *Verifier) VerifyCertificate(cert *x509.Certificate) (*Identity, error) {
+	identity := &Identity{
+		SPIFFEID:   spiffeID(cert),
+		DNSNames:   cert.DNSNames,
+		CommonName: cert.Subject.CommonName,
+	}
+
+	identity.ID = v.match(identity)
+	if identity.ID == "" {
+		return nil, fmt.Errorf("%w: %s", ErrIdentityNotAllowed, describe(identity))
+	}
+
+	identity.Scopes = v.policy.Scopes[identity.ID]
+	return identity, nil
+}
+
+func (v *Verifier) match(identity *Identity) string {
+	if identity.SPIFFEID != "" {
+		for _, allowed := range v.policy.SPIFFEIDs {
+			if matchSPIFFEID(allowed, identity.SPIFFEID) {
+				return identity.SPIFFEID
+			}
+		}
+	}
+
+	for _, name := range identity.DNSNames {
+		for _, allowed := range v.policy.DNSNames {
+			if matchDNSName(allowed, name) {
+				return name
+			}
+		}
+	}
+
+	if identity.CommonName != "" {
+		for _, re := range v.commonNames {
+			if re.MatchString(identity.CommonName) {
+				return identity.CommonName
+			}
+		}
+	}
+
+	return ""
+}
+
+// spiffeID gets the first spiffe URI SAN
+func spiffeID(cert *x509.Certificate) string {
+	for _, uri := range cert.URIs {
+		if uri.Scheme == "spiffe" {
+			return uri.String()
+		}
+	}
+
+	return ""
+}
+
+func matchSPIFFEID(allowed, id string) bool {
+	if strings.HasSuffix(allowed, "/*") {
+		return strings.HasPrefix(id, strings.TrimSuffix(allowed, "*"))
+	}
+
+	return allowed == id
+}
+
+func matchDNSName(allowed, name string) bool {
+	allowed = strings.ToLower(allowed)
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+
+	if !strings.HasPrefix(allowed, "*.") {
+		return allowed == name
+	}
+
+	i := strings.Index(name, ".")
+	return i > 0 && name[i:] == allowed[1:]
+}
+
+func describe(identity *Identity) string {
+	return fmt.Sprintf("spiffe_id=%q dns_names=%q common_name=%q", identity.SPIFFEID, identity.DNSNames, identity.CommonName)
+}
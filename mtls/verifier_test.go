@@ -0,0 +1,89 @@
+package mtls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCert(commonName string, dnsNames []string, spiffeID string) *x509.Certificate {
+	cert := &x509.Certificate{
+		Subject:  pkix.Name{CommonName: commonName},
+		DNSNames: dnsNames,
+	}
+
+	if spiffeID != "" {
+		u, _ := url.Parse(spiffeID)
+		cert.URIs = []*url.URL{u}
+	}
+
+	return cert
+}
+
+func TestVerifyCertificate(t *testing.T) {
+	verifier, err := NewVerifier(Policy{
+		SPIFFEIDs:   []string{"spiffe://kitabisa.com/ns/payment/*", "spiffe://kitabisa.com/ns/campaign/sa/api"},
+		DNSNames:    []string{"*.donation.svc.cluster.local"},
+		CommonNames: []string{`report-(worker|cron)`},
+		Scopes:      map[string][]string{"spiffe://kitabisa.com/ns/campaign/sa/api": {"campaign:read"}},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		cert *x509.Certificate
+		id   string
+	}{
+		{name: "spiffe prefix", cert: newCert("", nil, "spiffe://kitabisa.com/ns/payment/sa/worker"), id: "spiffe://kitabisa.com/ns/payment/sa/worker"},
+		{name: "spiffe exact", cert: newCert("", nil, "spiffe://kitabisa.com/ns/campaign/sa/api"), id: "spiffe://kitabisa.com/ns/campaign/sa/api"},
+		{name: "spiffe other namespace", cert: newCert("", nil, "spiffe://kitabisa.com/ns/paymentx/sa/worker")},
+		{name: "spiffe exact only", cert: newCert("", nil, "spiffe://kitabisa.com/ns/campaign/sa/api/x")},
+		{name: "dns wildcard", cert: newCert("", []string{"localhost", "api.donation.svc.cluster.local"}, ""), id: "api.donation.svc.cluster.local"},
+		{name: "dns wildcard matches one label", cert: newCert("", []string{"a.api.donation.svc.cluster.local"}, "")},
+		{name: "dns wildcard needs a label", cert: newCert("", []string{"donation.svc.cluster.local"}, "")},
+		{name: "common name", cert: newCert("report-cron", nil, ""), id: "report-cron"},
+		{name: "common name is anchored", cert: newCert("report-cron-evil", nil, "")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := verifier.VerifyCertificate(tt.cert)
+			if tt.id == "" {
+				assert.True(t, errors.Is(err, ErrIdentityNotAllowed))
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.id, identity.ID)
+		})
+	}
+
+	identity, err := verifier.VerifyCertificate(newCert("", nil, "spiffe://kitabisa.com/ns/campaign/sa/api"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"campaign:read"}, identity.Scopes)
+}
+
+func TestVerifyRequiresVerifiedChain(t *testing.T) {
+	verifier, err := NewVerifier(Policy{CommonNames: []string{".*"}})
+	require.NoError(t, err)
+
+	_, err = verifier.Verify(nil)
+	assert.Equal(t, ErrNoPeerCertificate, err)
+
+	// unverified peer certificate is not trusted
+	_, err = verifier.Verify(&tls.ConnectionState{PeerCertificates: []*x509.Certificate{newCert("report-cron", nil, "")}})
+	assert.Equal(t, ErrNoPeerCertificate, err)
+
+	identity, err := verifier.Verify(&tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{newCert("report-cron", nil, "")}}})
+	require.NoError(t, err)
+	assert.Equal(t, "report-cron", identity.ID)
+
+	_, err = NewVerifier(Policy{CommonNames: []string{"("}})
+	assert.Error(t, err)
+}
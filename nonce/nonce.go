@@ -0,0 +1,100 @@
+// Package nonce stores seen nonce, so a signed request is accepted once
+
+package nonce
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// Store is seen-set of nonce
+type Store interface {
+	// MarkUsed atomically marks nonce as used until ttl. It returns false when the nonce is already used.
+	MarkUsed(nonce string, ttl time.Duration) (first bool, err error)
+}
+
+// MemoryStore is in-memory store, for single instance service and test
+type MemoryStore struct {
+	mu      sync.Mutex
+	seen    map[string]time.Time
+	expires expiryQueue
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore creates in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		seen: make(map[string]time.Time),
+	}
+}
+
+func (s *MemoryStore) MarkUsed(nonce string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if expiresAt, ok := s.seen[nonce]; ok && now.Before(expiresAt) {
+		return false, nil
+	}
+
+	s.evict(now)
+	s.seen[nonce] = now.Add(ttl)
+	heap.Push(&s.expires, entry{nonce: nonce, expiresAt: now.Add(ttl)})
+	return true, nil
+}
+
+// evict removes expired nonce from the earliest expiry, caller must hold the lock
+func (s *MemoryStore) evict(now time.Time) {
+	for len(s.expires) > 0 && !now.Before(s.expires[0].expiresAt) {
+		e := heap.Pop(&s.expires).(entry)
+
+		// the nonce may be marked again after it expired
+		if s.seen[e.nonce].Equal(e.expiresAt) {
+			delete(s.seen, e.nonce)
+		}
+	}
+}
+
+type entry struct {
+	nonce     string
+	expiresAt time.Time
+}
+
+// expiryQueue is min-heap of nonce by expiry time
+type expiryQueue []entry
+
+func (q expiryQueue) Len() int            { return len(q) }
+func (q expiryQueue) Less(i, j int) bool  { return q[i].expiresAt.Before(q[j].expiresAt) }
+func (q expiryQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *expiryQueue) Push(x interface{}) { *q = append(*q, x.(entry)) }
+
+func (q *expiryQueue) Pop() interface{} {
+	old := *q
+	e := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return e
+}
+
+// RedisStore stores nonce in redis, shared by all instances of the service
+type RedisStore struct {
+	client redis.Cmdable
+	prefix string
+}
+
+var _ Store = (*RedisStore)(nil)
+
+// NewRedisStore creates redis store, prefix is key prefix e.g. "campaign-service:nonce:"
+func NewRedisStore(client redis.Cmdable, prefix string) *RedisStore {
+	return &RedisStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+func (s *RedisStore) MarkUsed(nonce string, ttl time.Duration) (bool, error) {
+	return s.client.SetNX(s.prefix+nonce, 1, ttl).Result()
+}
@@ -0,0 +1,65 @@
+package nonce
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kitabisa/perkakas/v2/internal/redistest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisStore(t *testing.T) {
+	client := redistest.New()
+	store := NewRedisStore(client, "campaign-service:nonce:")
+
+	first, err := store.MarkUsed("abc", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, first)
+	assert.Equal(t, time.Minute, client.Expiration("campaign-service:nonce:abc"))
+
+	first, err = store.MarkUsed("abc", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, first)
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+
+	first, err := store.MarkUsed("abc", 10*time.Millisecond)
+	require.NoError(t, err)
+	assert.True(t, first)
+
+	first, err = store.MarkUsed("abc", 10*time.Millisecond)
+	require.NoError(t, err)
+	assert.False(t, first)
+
+	time.Sleep(20 * time.Millisecond)
+	first, err = store.MarkUsed("abc", 10*time.Millisecond)
+	require.NoError(t, err)
+	assert.True(t, first)
+	assert.Len(t, store.seen, 1)
+}
+
+func TestMemoryStoreEviction(t *testing.T) {
+	store := NewMemoryStore()
+
+	for _, nonce := range []string{"a", "b", "c"} {
+		_, err := store.MarkUsed(nonce, 10*time.Millisecond)
+		require.NoError(t, err)
+	}
+
+	_, err := store.MarkUsed("long", time.Minute)
+	require.NoError(t, err)
+
+	// expired nonces are evicted on the next mark, the unexpired one is kept
+	time.Sleep(20 * time.Millisecond)
+	_, err = store.MarkUsed("d", time.Minute)
+	require.NoError(t, err)
+	assert.Len(t, store.seen, 2)
+	assert.Len(t, store.expires, 2)
+
+	first, err := store.MarkUsed("long", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, first)
+}
@@ -0,0 +1,83 @@
+package kafka
+
+import (
+	"context"
+
+	"github.com/Shopify/sarama"
+	"github.com/kitabisa/perkakas/v2/ctxkeys"
+)
+
+// RequestIDHeaderKey is the kafka record header key that carries the request ID
+const RequestIDHeaderKey = "X-Ktbs-Request-ID"
+
+// InjectRequestID adds the request ID from context into message header, unless the header is already set.
+// Record header requires kafka version 0.11 or later.
+func InjectRequestID(ctx context.Context, msg *sarama.ProducerMessage) {
+	reqID := ctxkeys.RequestIDFrom(ctx)
+	if reqID == "" {
+		return
+	}
+
+	for _, h := range msg.Headers {
+		if string(h.Key) == RequestIDHeaderKey {
+			return
+		}
+	}
+
+	msg.Headers = append(msg.Headers, sarama.RecordHeader{
+		Key:   []byte(RequestIDHeaderKey),
+		Value: []byte(reqID),
+	})
+}
+
+// RequestIDFromMessage gets the request ID from consumed message header
+func RequestIDFromMessage(msg *sarama.ConsumerMessage) string {
+	for _, h := range msg.Headers {
+		if h != nil && string(h.Key) == RequestIDHeaderKey {
+			return string(h.Value)
+		}
+	}
+
+	return ""
+}
+
+// ContextSyncProducer is sarama.SyncProducer that propagates the request ID from context into message header
+type ContextSyncProducer struct {
+	sarama.SyncProducer
+}
+
+// NewContextSyncProducer wraps producer created by NewKafkaProducer
+func NewContextSyncProducer(producer sarama.SyncProducer) *ContextSyncProducer {
+	return &ContextSyncProducer{SyncProducer: producer}
+}
+
+// SendMessageContext produces a message carrying the request ID from context
+func (p *ContextSyncProducer) SendMessageContext(ctx context.Context, msg *sarama.ProducerMessage) (partition int32, offset int64, err error) {
+	InjectRequestID(ctx, msg)
+	return p.SendMessage(msg)
+}
+
+// SendMessagesContext produces messages carrying the request ID from context
+func (p *ContextSyncProducer) SendMessagesContext(ctx context.Context, msgs []*sarama.ProducerMessage) error {
+	for _, msg := range msgs {
+		InjectRequestID(ctx, msg)
+	}
+
+	return p.SendMessages(msgs)
+}
+
+// ContextAsyncProducer is sarama.AsyncProducer that propagates the request ID from context into message header
+type ContextAsyncProducer struct {
+	sarama.AsyncProducer
+}
+
+// NewContextAsyncProducer wraps producer created by NewKafkaAsyncProducer
+func NewContextAsyncProducer(producer sarama.AsyncProducer) *ContextAsyncProducer {
+	return &ContextAsyncProducer{AsyncProducer: producer}
+}
+
+// SendContext queues a message carrying the request ID from context to the producer input channel
+func (p *ContextAsyncProducer) SendContext(ctx context.Context, msg *sarama.ProducerMessage) {
+	InjectRequestID(ctx, msg)
+	p.Input() <- msg
+}
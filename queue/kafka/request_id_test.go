@@ -0,0 +1,51 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/kitabisa/perkakas/v2/ctxkeys"
+	"github.com/stretchr/testify/assert"
+)
+
+type testSyncProducer struct {
+	sarama.SyncProducer
+	msgs []*sarama.ProducerMessage
+}
+
+func (p *testSyncProducer) SendMessage(msg *sarama.ProducerMessage) (int32, int64, error) {
+	p.msgs = append(p.msgs, msg)
+	return 0, 0, nil
+}
+
+func TestContextSyncProducer(t *testing.T) {
+	fake := &testSyncProducer{}
+	producer := NewContextSyncProducer(fake)
+
+	ctx := ctxkeys.WithRequestID(context.Background(), "b5a3ee2f-0d0d-4b8e-a7a6-3f4f0c6d8c11")
+	_, _, err := producer.SendMessageContext(ctx, &sarama.ProducerMessage{Topic: "donation"})
+	assert.NoError(t, err)
+
+	if assert.Len(t, fake.msgs, 1) && assert.Len(t, fake.msgs[0].Headers, 1) {
+		assert.Equal(t, RequestIDHeaderKey, string(fake.msgs[0].Headers[0].Key))
+		assert.Equal(t, "b5a3ee2f-0d0d-4b8e-a7a6-3f4f0c6d8c11", string(fake.msgs[0].Headers[0].Value))
+	}
+}
+
+func TestInjectRequestIDWithoutRequestID(t *testing.T) {
+	msg := &sarama.ProducerMessage{Topic: "donation"}
+	InjectRequestID(context.Background(), msg)
+	assert.Empty(t, msg.Headers)
+}
+
+func TestRequestIDFromMessage(t *testing.T) {
+	msg := &sarama.ConsumerMessage{
+		Headers: []*sarama.RecordHeader{
+			{Key: []byte(RequestIDHeaderKey), Value: []byte("b5a3ee2f-0d0d-4b8e-a7a6-3f4f0c6d8c11")},
+		},
+	}
+
+	assert.Equal(t, "b5a3ee2f-0d0d-4b8e-a7a6-3f4f0c6d8c11", RequestIDFromMessage(msg))
+	assert.Empty(t, RequestIDFromMessage(&sarama.ConsumerMessage{}))
+}
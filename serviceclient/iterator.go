@@ -0,0 +1,68 @@
+package serviceclient
+
+import (
+	"context"
+	"net/url"
+)
+
+// Iterator iterates pages of GET response by following the next page token
+type Iterator struct {
+	c     *Client
+	ctx   context.Context
+	path  string
+	query url.Values
+
+	next    *string
+	started bool
+	err     error
+}
+
+// Iterate creates iterator for GET request. The next page token is sent as Conf.CursorParam query param.
+//
+//	it := client.Iterate(ctx, "/campaigns", nil)
+//	var campaigns []Campaign
+//	for it.Next(&campaigns) {
+//		// process campaigns
+//	}
+//
+//	if err := it.Err(); err != nil {
+//		// handle error
+//	}
+func (c *Client) Iterate(ctx context.Context, path string, query url.Values) *Iterator {
+	q := make(url.Values)
+	for k, v := range query {
+		q[k] = v
+	}
+
+	return &Iterator{
+		c:     c,
+		ctx:   ctx,
+		path:  path,
+		query: q,
+	}
+}
+
+// Next fetches the next page and decodes its data into out. It returns false when there is no more page or error happens.
+func (it *Iterator) Next(out interface{}) bool {
+	if it.err != nil {
+		return false
+	}
+
+	if it.started {
+		if it.next == nil || *it.next == "" {
+			return false
+		}
+
+		it.query.Set(it.c.conf.CursorParam, *it.next)
+	}
+
+	it.started = true
+	it.next, it.err = it.c.Get(it.ctx, it.path, it.query, out)
+
+	return it.err == nil
+}
+
+// Err returns the error happened during iteration
+func (it *Iterator) Err() error {
+	return it.err
+}
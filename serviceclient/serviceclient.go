@@ -0,0 +1,238 @@
+// Package serviceclient is client for calling other Kitabisa internal service. It signs the request with X-Ktbs-* headers
+// and decodes the standard response envelope.
+
+package serviceclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kitabisa/perkakas/v2/ctxkeys"
+	"github.com/kitabisa/perkakas/v2/httpclient"
+	"github.com/kitabisa/perkakas/v2/httputil"
+	"github.com/kitabisa/perkakas/v2/signature"
+	"github.com/kitabisa/perkakas/v2/structs"
+)
+
+// Conf is service client configuration
+type Conf struct {
+	BaseURL       string // base url of the called service, e.g. http://campaign-service
+	ClientName    string // X-Ktbs-Client-Name header
+	ClientVersion string // X-Ktbs-Client-Version header, default 1.0.0
+	APIVersion    string // X-Ktbs-Api-Version header, default 1.0.0
+	PlatformName  string // X-Ktbs-Platform-Name header, default "service"
+	SecretKey     string // secret key for X-Ktbs-Signature. Signature is not set when empty.
+	SignNonce     bool   // sends signed X-Ktbs-Nonce header, required by middleware.WithNonceStore
+
+	// CursorParam is query param name to send the next page token. Default "next".
+	CursorParam string
+}
+
+// Client calls other service through httpclient.HttpClient
+type Client struct {
+	conf       Conf
+	httpClient *httpclient.HttpClient
+}
+
+// NewClient creates service client. If httpClient is nil, httpclient with default configuration will be used.
+func NewClient(httpClient *httpclient.HttpClient, conf Conf) *Client {
+	if httpClient == nil {
+		httpClient = httpclient.NewHttpClient(nil)
+	}
+
+	conf.BaseURL = strings.TrimRight(conf.BaseURL, "/")
+
+	if conf.ClientVersion == "" {
+		conf.ClientVersion = "1.0.0"
+	}
+
+	if conf.APIVersion == "" {
+		conf.APIVersion = "1.0.0"
+	}
+
+	if conf.PlatformName == "" {
+		conf.PlatformName = "service"
+	}
+
+	if conf.CursorParam == "" {
+		conf.CursorParam = "next"
+	}
+
+	if conf.SecretKey != "" {
+		// sign every attempt, so retried request has fresh time and nonce
+		httpClient = httpClient.WithSigner(&headerSigner{conf: conf})
+	}
+
+	return &Client{
+		conf:       conf,
+		httpClient: httpClient,
+	}
+}
+
+// Get calls GET method and decodes response data into out. It returns the next page token, if any.
+func (c *Client) Get(ctx context.Context, path string, query url.Values, out interface{}) (next *string, err error) {
+	return c.Do(ctx, http.MethodGet, path, query, nil, out)
+}
+
+// Post calls POST method with json encoded body and decodes response data into out
+func (c *Client) Post(ctx context.Context, path string, body, out interface{}) (next *string, err error) {
+	return c.Do(ctx, http.MethodPost, path, nil, body, out)
+}
+
+// Put calls PUT method with json encoded body and decodes response data into out
+func (c *Client) Put(ctx context.Context, path string, body, out interface{}) (next *string, err error) {
+	return c.Do(ctx, http.MethodPut, path, nil, body, out)
+}
+
+// Patch calls PATCH method with json encoded body and decodes response data into out
+func (c *Client) Patch(ctx context.Context, path string, body, out interface{}) (next *string, err error) {
+	return c.Do(ctx, http.MethodPatch, path, nil, body, out)
+}
+
+// Delete calls DELETE method and decodes response data into out
+func (c *Client) Delete(ctx context.Context, path string, out interface{}) (next *string, err error) {
+	return c.Do(ctx, http.MethodDelete, path, nil, nil, out)
+}
+
+// Do calls the service. Request ID and bearer token in context are propagated.
+// Data of success response is decoded into out, which can be pointer to slice, or pointer to struct to decode the first data.
+// Out can be nil to ignore the data. Error response is returned as *structs.ErrorResponse with HttpStatus set,
+// use errors.As to get the response code.
+func (c *Client) Do(ctx context.Context, method, path string, query url.Values, body, out interface{}) (next *string, err error) {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("serviceclient: failed to marshal request body: %w", err)
+		}
+
+		reqBody = bytes.NewReader(b)
+	}
+
+	u := c.conf.BaseURL + "/" + strings.TrimLeft(path, "/")
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest(method, u, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("serviceclient: failed to create request: %w", err)
+	}
+
+	req = c.signRequest(req.WithContext(ctx))
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("serviceclient: failed to read response body: %w", err)
+	}
+
+	if !httputil.IsSuccess(resp.StatusCode) {
+		return nil, decodeError(resp.StatusCode, respBody)
+	}
+
+	var successResp struct {
+		structs.Response
+		Next *string         `json:"next"`
+		Data json.RawMessage `json:"data"`
+	}
+
+	if err = json.Unmarshal(respBody, &successResp); err != nil {
+		return nil, fmt.Errorf("serviceclient: failed to decode response: %w", err)
+	}
+
+	if out != nil && len(successResp.Data) > 0 {
+		if err = decodeData(successResp.Data, out); err != nil {
+			return nil, err
+		}
+	}
+
+	return successResp.Next, nil
+}
+
+// signRequest sets X-Ktbs-* headers and bearer token from context. Signature is set by headerSigner.
+func (c *Client) signRequest(req *http.Request) *http.Request {
+	ctx := req.Context()
+	req = httputil.KitabisaHeader(req, c.conf.ClientName, c.conf.ClientVersion, ctxkeys.RequestIDFrom(ctx))
+	req.Header.Set("X-Ktbs-Api-Version", c.conf.APIVersion)
+	req.Header.Set("X-Ktbs-Platform-Name", c.conf.PlatformName)
+
+	if token := ctxkeys.BearerTokenFrom(ctx); token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	}
+
+	return req
+}
+
+// headerSigner signs X-Ktbs-Signature with new time and nonce for every attempt of the request
+type headerSigner struct {
+	conf Conf
+}
+
+func (s *headerSigner) Sign(req *http.Request) error {
+	req.Header.Set("X-Ktbs-Time", strconv.FormatInt(time.Now().Unix(), 10))
+	if s.conf.SignNonce {
+		req.Header.Set("X-Ktbs-Nonce", uuid.New().String())
+	}
+
+	data := fmt.Sprintf("%s%s%s", s.conf.ClientName, req.Header.Get("X-Ktbs-Time"), req.Header.Get("X-Ktbs-Nonce"))
+	req.Header.Set("X-Ktbs-Signature", signature.GenerateHmac(data, s.conf.SecretKey))
+	return nil
+}
+
+func decodeError(status int, body []byte) error {
+	errResp := &structs.ErrorResponse{}
+	if err := json.Unmarshal(body, errResp); err != nil || errResp.ResponseCode == "" {
+		errResp = &structs.ErrorResponse{
+			Response: structs.Response{
+				ResponseDesc: structs.ResponseDesc{
+					ID: http.StatusText(status),
+					EN: http.StatusText(status),
+				},
+			},
+		}
+	}
+
+	errResp.HttpStatus = status
+	return errResp
+}
+
+// decodeData decodes data array into out. If out is not pointer to slice, the first element is decoded.
+func decodeData(data json.RawMessage, out interface{}) error {
+	rv := reflect.ValueOf(out)
+	if rv.Kind() == reflect.Ptr && rv.Elem().Kind() != reflect.Slice && rv.Elem().Kind() != reflect.Interface {
+		var arr []json.RawMessage
+		if err := json.Unmarshal(data, &arr); err == nil {
+			if len(arr) == 0 {
+				return nil
+			}
+
+			data = arr[0]
+		}
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("serviceclient: failed to decode response data: %w", err)
+	}
+
+	return nil
+}
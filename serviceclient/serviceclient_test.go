@@ -0,0 +1,217 @@
+package serviceclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kitabisa/perkakas/v2/ctxkeys"
+	phttp "github.com/kitabisa/perkakas/v2/http"
+	"github.com/kitabisa/perkakas/v2/httpclient"
+	"github.com/kitabisa/perkakas/v2/middleware"
+	"github.com/kitabisa/perkakas/v2/nonce"
+	"github.com/kitabisa/perkakas/v2/signature"
+	"github.com/kitabisa/perkakas/v2/structs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type campaign struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+}
+
+func writeSuccess(w http.ResponseWriter, data interface{}, next *string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"response_code": "000000",
+		"response_desc": map[string]string{"id": "Sukses", "en": "Success"},
+		"data":          data,
+		"next":          next,
+	})
+}
+
+func TestClientHeaders(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "req-123", r.Header.Get("X-Ktbs-Request-ID"))
+		assert.Equal(t, "donation-service", r.Header.Get("X-Ktbs-Client-Name"))
+		assert.Equal(t, "1.0.0", r.Header.Get("X-Ktbs-Api-Version"))
+		assert.Equal(t, "service", r.Header.Get("X-Ktbs-Platform-Name"))
+		assert.Equal(t, "Bearer token-abc", r.Header.Get("Authorization"))
+
+		data := fmt.Sprintf("%s%s", r.Header.Get("X-Ktbs-Client-Name"), r.Header.Get("X-Ktbs-Time"))
+		assert.Equal(t, signature.GenerateHmac(data, "secret"), r.Header.Get("X-Ktbs-Signature"))
+
+		writeSuccess(w, []campaign{{ID: 1, Title: "first"}}, nil)
+	}))
+	defer srv.Close()
+
+	c := NewClient(nil, Conf{
+		BaseURL:    srv.URL,
+		ClientName: "donation-service",
+		SecretKey:  "secret",
+	})
+
+	ctx := ctxkeys.WithRequestID(context.Background(), "req-123")
+	ctx = ctxkeys.WithBearerToken(ctx, "token-abc")
+
+	var got campaign
+	next, err := c.Get(ctx, "/campaigns/1", nil, &got)
+	require.NoError(t, err)
+	assert.Nil(t, next)
+	assert.Equal(t, campaign{ID: 1, Title: "first"}, got)
+}
+
+func TestClientSignNonce(t *testing.T) {
+	nonces := map[string]bool{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nonce := r.Header.Get("X-Ktbs-Nonce")
+		assert.NotEmpty(t, nonce)
+		assert.False(t, nonces[nonce])
+		nonces[nonce] = true
+
+		data := fmt.Sprintf("%s%s%s", r.Header.Get("X-Ktbs-Client-Name"), r.Header.Get("X-Ktbs-Time"), nonce)
+		assert.Equal(t, signature.GenerateHmac(data, "secret"), r.Header.Get("X-Ktbs-Signature"))
+
+		writeSuccess(w, nil, nil)
+	}))
+	defer srv.Close()
+
+	c := NewClient(nil, Conf{
+		BaseURL:    srv.URL,
+		ClientName: "donation-service",
+		SecretKey:  "secret",
+		SignNonce:  true,
+	})
+
+	for i := 0; i < 2; i++ {
+		_, err := c.Get(context.Background(), "/campaigns/1", nil, nil)
+		require.NoError(t, err)
+	}
+	assert.Len(t, nonces, 2)
+}
+
+func TestClientSignNonceRetry(t *testing.T) {
+	var attempts int32
+	hctx := phttp.NewContextHandler(structs.Meta{})
+	handler := middleware.NewHeaderCheck(hctx, "secret", middleware.WithNonceStore(nonce.NewMemoryStore()))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&attempts, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+
+			writeSuccess(w, nil, nil)
+		}),
+	)
+
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	httpClient := httpclient.NewHttpClient(&httpclient.HttpClientConf{
+		Timeout:    time.Second,
+		RetryCount: 1,
+		Backoff:    httpclient.NewConstantBackoff(time.Millisecond, 0),
+	})
+
+	c := NewClient(httpClient, Conf{
+		BaseURL:    srv.URL,
+		ClientName: "donation-service",
+		SecretKey:  "secret",
+		SignNonce:  true,
+	})
+
+	// retried request is signed again with new nonce, so it is not rejected as replayed
+	_, err := c.Get(context.Background(), "/campaigns/1", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), attempts)
+}
+
+func TestClientPostDecodeSlice(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.JSONEq(t, `{"id":0,"title":"new"}`, string(body))
+
+		writeSuccess(w, []campaign{{ID: 2, Title: "new"}}, nil)
+	}))
+	defer srv.Close()
+
+	c := NewClient(nil, Conf{BaseURL: srv.URL, ClientName: "donation-service"})
+
+	var got []campaign
+	_, err := c.Post(context.Background(), "campaigns", campaign{Title: "new"}, &got)
+	require.NoError(t, err)
+	assert.Equal(t, []campaign{{ID: 2, Title: "new"}}, got)
+}
+
+func TestClientErrorResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/envelope" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(structs.ErrUnauthorized)
+			return
+		}
+
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	c := NewClient(nil, Conf{BaseURL: srv.URL, ClientName: "donation-service"})
+
+	_, err := c.Get(context.Background(), "/envelope", nil, nil)
+	var errResp *structs.ErrorResponse
+	require.True(t, errors.As(err, &errResp))
+	assert.Equal(t, http.StatusUnauthorized, errResp.HttpStatus)
+	assert.Equal(t, structs.ErrUnauthorized.ResponseCode, errResp.ResponseCode)
+
+	_, err = c.Get(context.Background(), "/plain", nil, nil)
+	require.True(t, errors.As(err, &errResp))
+	assert.Equal(t, http.StatusNotFound, errResp.HttpStatus)
+	assert.Equal(t, http.StatusText(http.StatusNotFound), errResp.Error())
+}
+
+func TestIterator(t *testing.T) {
+	pages := map[string][]campaign{
+		"":  {{ID: 1}, {ID: 2}},
+		"2": {{ID: 3}},
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "active", r.URL.Query().Get("status"))
+
+		cursor := r.URL.Query().Get("cursor")
+		var next *string
+		if cursor == "" {
+			n := "2"
+			next = &n
+		}
+
+		writeSuccess(w, pages[cursor], next)
+	}))
+	defer srv.Close()
+
+	c := NewClient(nil, Conf{BaseURL: srv.URL, ClientName: "donation-service", CursorParam: "cursor"})
+
+	it := c.Iterate(context.Background(), "/campaigns", map[string][]string{"status": {"active"}})
+
+	var ids []int64
+	var page []campaign
+	for it.Next(&page) {
+		for _, p := range page {
+			ids = append(ids, p.ID)
+		}
+	}
+
+	require.NoError(t, it.Err())
+	assert.Equal(t, []int64{1, 2, 3}, ids)
+}
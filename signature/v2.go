@@ -0,0 +1,416 @@
+package signature
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kitabisa/perkakas/v2/nonce"
+)
+
+// Signature v2 headers
+const (
+	AlgorithmV2         = "KTBS-HMAC-SHA256"
+	SignatureV2Header   = "X-Ktbs-Signature-V2"
+	ContentSHA256Header = "X-Ktbs-Content-Sha256"
+	TimeHeader          = "X-Ktbs-Time"
+	NonceHeader         = "X-Ktbs-Nonce"
+)
+
+// DefaultMaxBodySize is max request body size read by VerifierV2 to verify the body hash, 10 MiB
+const DefaultMaxBodySize = 10 << 20
+
+// DefaultSignedHeaders is headers signed by default, besides time, nonce and body hash which are always signed
+var DefaultSignedHeaders = []string{"host", "content-type"}
+
+var (
+	// ErrMissingSignature is returned when request has no v2 signature
+	ErrMissingSignature = errors.New("signature: missing signature")
+	// ErrInvalidSignature is returned when signature is malformed or doesn't match the request
+	ErrInvalidSignature = errors.New("signature: invalid signature")
+	// ErrSignatureExpired is returned when request time is outside of the clock skew
+	ErrSignatureExpired = errors.New("signature: signature is expired")
+	// ErrSignatureReplayed is returned when nonce is already used
+	ErrSignatureReplayed = errors.New("signature: signature is replayed")
+	// ErrKeyNotFound is returned when key id is unknown
+	ErrKeyNotFound = errors.New("signature: key not found")
+	// ErrBodyTooLarge is returned when request body is larger than VerifierV2Option.MaxBodySize
+	ErrBodyTooLarge = errors.New("signature: request body is too large")
+)
+
+// Key is signing secret of a client. Client can have several keys, so the secret can be rotated without downtime.
+type Key struct {
+	ID         string // e.g. "donation-service-2021-09"
+	ClientName string
+	Secret     string
+}
+
+// KeyStore finds key by its id
+type KeyStore interface {
+	// Key gets key by id. Unknown id returns ErrKeyNotFound.
+	Key(id string) (*Key, error)
+}
+
+// MemoryKeyStore is in-memory key store
+type MemoryKeyStore struct {
+	mu   sync.RWMutex
+	keys map[string]Key
+}
+
+var _ KeyStore = (*MemoryKeyStore)(nil)
+
+// NewMemoryKeyStore creates in-memory key store
+func NewMemoryKeyStore(keys ...Key) *MemoryKeyStore {
+	s := &MemoryKeyStore{
+		keys: make(map[string]Key, len(keys)),
+	}
+
+	for _, key := range keys {
+		s.keys[key.ID] = key
+	}
+
+	return s
+}
+
+func (s *MemoryKeyStore) Key(id string) (*Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+
+	return &key, nil
+}
+
+// Add adds key, e.g. new key of the client during rotation
+func (s *MemoryKeyStore) Add(key Key) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[key.ID] = key
+}
+
+// Remove removes key, e.g. old key of the client after rotation
+func (s *MemoryKeyStore) Remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, id)
+}
+
+// SignerV2 signs http request with canonical request signature, covering method, path, sorted query,
+// signed headers, body hash, time and nonce
+type SignerV2 struct {
+	Key Key
+	// SignedHeaders is signed header names. Default DefaultSignedHeaders.
+	SignedHeaders []string
+	// Nonce sends random X-Ktbs-Nonce header, required by verifier with nonce store
+	Nonce bool
+}
+
+// Sign sets time, nonce, body hash and signature headers of the request. Request body is read and restored.
+func (s *SignerV2) Sign(req *http.Request) error {
+	bodyHash, err := hashBody(req, 0)
+	if err != nil {
+		return err
+	}
+
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+
+	req.Header.Set(TimeHeader, strconv.FormatInt(time.Now().Unix(), 10))
+	req.Header.Set(ContentSHA256Header, bodyHash)
+	if s.Nonce {
+		req.Header.Set(NonceHeader, uuid.New().String())
+	}
+
+	signedHeaders := s.SignedHeaders
+	if signedHeaders == nil {
+		signedHeaders = DefaultSignedHeaders
+	}
+
+	signedHeaders = normalizeHeaderNames(signedHeaders)
+	sig := GenerateHmac(CanonicalRequest(req, signedHeaders, bodyHash), s.Key.Secret)
+
+	req.Header.Set(SignatureV2Header, fmt.Sprintf("%s KeyId=%s, SignedHeaders=%s, Signature=%s",
+		AlgorithmV2, s.Key.ID, strings.Join(signedHeaders, ";"), sig))
+	return nil
+}
+
+// VerifierV2Option is verifier option
+type VerifierV2Option struct {
+	// Keys finds signing key by key id
+	Keys KeyStore
+	// ClockSkew is max difference between X-Ktbs-Time and server time. Default 5 minutes.
+	ClockSkew time.Duration
+	// RequiredHeaders must be signed, e.g. "host". Default none, time, nonce and body hash are always signed.
+	RequiredHeaders []string
+	// NonceStore requires signed X-Ktbs-Nonce header, so a signature is accepted once
+	NonceStore nonce.Store
+	// MaxBodySize is max request body size in bytes. Body is read into memory before the signature is checked,
+	// larger body is rejected with ErrBodyTooLarge. Default DefaultMaxBodySize, negative means no limit.
+	MaxBodySize int64
+}
+
+// VerifierV2 verifies canonical request signature of SignerV2
+type VerifierV2 struct {
+	option VerifierV2Option
+}
+
+// NewVerifierV2 creates signature v2 verifier
+func NewVerifierV2(option VerifierV2Option) *VerifierV2 {
+	if option.ClockSkew <= 0 {
+		option.ClockSkew = 5 * time.Minute
+	}
+
+	if option.MaxBodySize == 0 {
+		option.MaxBodySize = DefaultMaxBodySize
+	}
+
+	option.RequiredHeaders = normalizeHeaderNames(option.RequiredHeaders)
+
+	return &VerifierV2{
+		option: option,
+	}
+}
+
+// Verify verifies request signature and returns the signing key. Request body is read and restored.
+func (v *VerifierV2) Verify(req *http.Request) (*Key, error) {
+	header := req.Header.Get(SignatureV2Header)
+	if header == "" {
+		return nil, ErrMissingSignature
+	}
+
+	keyID, signedHeaders, sig, err := parseSignatureV2(header)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, required := range v.option.RequiredHeaders {
+		if !containsString(signedHeaders, required) {
+			return nil, fmt.Errorf("%w: header %s is not signed", ErrInvalidSignature, required)
+		}
+	}
+
+	unix, err := strconv.ParseInt(req.Header.Get(TimeHeader), 10, 64)
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+
+	skew := time.Since(time.Unix(unix, 0))
+	if skew > v.option.ClockSkew || skew < -v.option.ClockSkew {
+		return nil, ErrSignatureExpired
+	}
+
+	nonceValue := req.Header.Get(NonceHeader)
+	if v.option.NonceStore != nil && nonceValue == "" {
+		return nil, fmt.Errorf("%w: missing nonce", ErrInvalidSignature)
+	}
+
+	key, err := v.option.Keys.Key(keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	bodyHash, err := hashBody(req, v.option.MaxBodySize)
+	if err != nil {
+		return nil, err
+	}
+
+	if !IsMatchHmac(CanonicalRequest(req, signedHeaders, bodyHash), sig, key.Secret) {
+		return nil, ErrInvalidSignature
+	}
+
+	// nonce is checked after signature, so unsigned request can't fill the store
+	if v.option.NonceStore != nil {
+		first, err := v.option.NonceStore.MarkUsed(key.ClientName+":"+nonceValue, 2*v.option.ClockSkew)
+		if err != nil {
+			return nil, err
+		}
+
+		if !first {
+			return nil, ErrSignatureReplayed
+		}
+	}
+
+	return key, nil
+}
+
+// CanonicalRequest builds the signed string of the request, one part per line: algorithm, time, nonce, method,
+// escaped path, sorted query, signed headers as "name:value", signed header names and body hash
+func CanonicalRequest(req *http.Request, signedHeaders []string, bodyHash string) string {
+	var b strings.Builder
+
+	b.WriteString(AlgorithmV2 + "\n")
+	b.WriteString(req.Header.Get(TimeHeader) + "\n")
+	b.WriteString(req.Header.Get(NonceHeader) + "\n")
+	b.WriteString(strings.ToUpper(req.Method) + "\n")
+	b.WriteString(canonicalPath(req.URL) + "\n")
+	b.WriteString(canonicalQuery(req.URL) + "\n")
+
+	for _, name := range signedHeaders {
+		b.WriteString(name + ":" + canonicalHeaderValue(req, name) + "\n")
+	}
+
+	b.WriteString(strings.Join(signedHeaders, ";") + "\n")
+	b.WriteString(bodyHash)
+
+	return b.String()
+}
+
+func canonicalPath(u *url.URL) string {
+	path := u.EscapedPath()
+	if path == "" {
+		return "/"
+	}
+
+	return path
+}
+
+func canonicalQuery(u *url.URL) string {
+	query, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		// malformed query is signed as is
+		return u.RawQuery
+	}
+
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(query))
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+
+		for _, v := range values {
+			pairs = append(pairs, queryEscape(k)+"="+queryEscape(v))
+		}
+	}
+
+	return strings.Join(pairs, "&")
+}
+
+func queryEscape(s string) string {
+	return strings.Replace(url.QueryEscape(s), "+", "%20", -1)
+}
+
+func canonicalHeaderValue(req *http.Request, name string) string {
+	if name == "host" {
+		if req.Host != "" {
+			return strings.ToLower(req.Host)
+		}
+
+		return strings.ToLower(req.URL.Host)
+	}
+
+	values := req.Header[http.CanonicalHeaderKey(name)]
+	trimmed := make([]string, len(values))
+	for i, v := range values {
+		trimmed[i] = strings.Join(strings.Fields(v), " ")
+	}
+
+	return strings.Join(trimmed, ",")
+}
+
+// normalizeHeaderNames lowercases and sorts header names
+func normalizeHeaderNames(names []string) []string {
+	normalized := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "" && !containsString(normalized, name) {
+			normalized = append(normalized, name)
+		}
+	}
+
+	sort.Strings(normalized)
+	return normalized
+}
+
+func parseSignatureV2(header string) (keyID string, signedHeaders []string, sig string, err error) {
+	if !strings.HasPrefix(header, AlgorithmV2+" ") {
+		return "", nil, "", ErrInvalidSignature
+	}
+
+	for _, part := range strings.Split(header[len(AlgorithmV2)+1:], ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			return "", nil, "", ErrInvalidSignature
+		}
+
+		switch kv[0] {
+		case "KeyId":
+			keyID = kv[1]
+		case "SignedHeaders":
+			signedHeaders = normalizeHeaderNames(strings.Split(kv[1], ";"))
+		case "Signature":
+			sig = kv[1]
+		}
+	}
+
+	if keyID == "" || sig == "" {
+		return "", nil, "", ErrInvalidSignature
+	}
+
+	return keyID, signedHeaders, sig, nil
+}
+
+// hashBody is hex sha256 of the request body. Body is restored so it can be read again. Body larger than maxSize
+// is rejected with ErrBodyTooLarge, zero or negative maxSize means no limit.
+func hashBody(req *http.Request, maxSize int64) (string, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		sum := sha256.Sum256(nil)
+		return hex.EncodeToString(sum[:]), nil
+	}
+
+	if maxSize > 0 && req.ContentLength > maxSize {
+		return "", ErrBodyTooLarge
+	}
+
+	var reader io.Reader = req.Body
+	if maxSize > 0 {
+		reader = io.LimitReader(req.Body, maxSize+1)
+	}
+
+	body, err := ioutil.ReadAll(reader)
+	req.Body.Close()
+	if err != nil {
+		return "", fmt.Errorf("signature: failed to read body: %w", err)
+	}
+
+	if maxSize > 0 && int64(len(body)) > maxSize {
+		return "", ErrBodyTooLarge
+	}
+
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
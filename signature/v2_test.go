@@ -0,0 +1,169 @@
+package signature_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kitabisa/perkakas/v2/nonce"
+	"github.com/kitabisa/perkakas/v2/signature"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	oldKey = signature.Key{ID: "donation-2021-08", ClientName: "donation-service", Secret: "old-secret"}
+	newKey = signature.Key{ID: "donation-2021-09", ClientName: "donation-service", Secret: "new-secret"}
+)
+
+// toServerRequest converts signed client request into request received by server
+func toServerRequest(t *testing.T, req *http.Request) *http.Request {
+	body, err := ioutil.ReadAll(req.Body)
+	require.NoError(t, err)
+
+	server := httptest.NewRequest(req.Method, req.URL.String(), strings.NewReader(string(body)))
+	server.Header = req.Header.Clone()
+	return server
+}
+
+func newSignedRequest(t *testing.T, signer *signature.SignerV2, target, body string) *http.Request {
+	req, err := http.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	require.NoError(t, signer.Sign(req))
+	return toServerRequest(t, req)
+}
+
+func TestSignAndVerifyV2(t *testing.T) {
+	verifier := signature.NewVerifierV2(signature.VerifierV2Option{
+		Keys:            signature.NewMemoryKeyStore(oldKey, newKey),
+		RequiredHeaders: []string{"Host"},
+	})
+
+	// both keys are valid during rotation
+	for _, key := range []signature.Key{oldKey, newKey} {
+		req := newSignedRequest(t, &signature.SignerV2{Key: key}, "http://campaign-service/campaigns/1/donations?b=2&a=1&a=0", `{"amount":10000}`)
+		assert.True(t, strings.HasPrefix(req.Header.Get(signature.SignatureV2Header), "KTBS-HMAC-SHA256 KeyId="+key.ID))
+
+		verified, err := verifier.Verify(req)
+		require.NoError(t, err)
+		assert.Equal(t, key, *verified)
+
+		// body is restored
+		body, _ := ioutil.ReadAll(req.Body)
+		assert.Equal(t, `{"amount":10000}`, string(body))
+	}
+}
+
+func TestVerifyV2Rejects(t *testing.T) {
+	keys := signature.NewMemoryKeyStore(newKey)
+	verifier := signature.NewVerifierV2(signature.VerifierV2Option{Keys: keys, RequiredHeaders: []string{"host"}})
+	signer := &signature.SignerV2{Key: newKey}
+	target := "http://campaign-service/campaigns/1/donations?a=1"
+
+	tests := []struct {
+		name   string
+		tamper func(req *http.Request)
+		err    error
+	}{
+		{name: "missing signature", tamper: func(req *http.Request) { req.Header.Del(signature.SignatureV2Header) }, err: signature.ErrMissingSignature},
+		{name: "body", tamper: func(req *http.Request) { req.Body = ioutil.NopCloser(strings.NewReader(`{"amount":1}`)) }, err: signature.ErrInvalidSignature},
+		{name: "method", tamper: func(req *http.Request) { req.Method = http.MethodPut }, err: signature.ErrInvalidSignature},
+		{name: "path", tamper: func(req *http.Request) { req.URL.Path = "/campaigns/2/donations" }, err: signature.ErrInvalidSignature},
+		{name: "query", tamper: func(req *http.Request) { req.URL.RawQuery = "a=2" }, err: signature.ErrInvalidSignature},
+		{name: "signed header", tamper: func(req *http.Request) { req.Header.Set("Content-Type", "text/plain") }, err: signature.ErrInvalidSignature},
+		{name: "time", tamper: func(req *http.Request) {
+			req.Header.Set(signature.TimeHeader, strconv.FormatInt(time.Now().Add(time.Second).Unix()+1, 10))
+		}, err: signature.ErrInvalidSignature},
+		{name: "expired", tamper: func(req *http.Request) {
+			req.Header.Set(signature.TimeHeader, strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10))
+		}, err: signature.ErrSignatureExpired},
+		{name: "unknown key", tamper: func(req *http.Request) { keys.Remove(newKey.ID) }, err: signature.ErrKeyNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys.Add(newKey)
+			req := newSignedRequest(t, signer, target, `{"amount":10000}`)
+			tt.tamper(req)
+
+			_, err := verifier.Verify(req)
+			assert.True(t, errors.Is(err, tt.err), err)
+		})
+	}
+
+	// required header is not signed
+	req := newSignedRequest(t, &signature.SignerV2{Key: newKey, SignedHeaders: []string{"content-type"}}, target, "")
+	_, err := verifier.Verify(req)
+	assert.True(t, errors.Is(err, signature.ErrInvalidSignature))
+}
+
+func TestVerifyV2Nonce(t *testing.T) {
+	verifier := signature.NewVerifierV2(signature.VerifierV2Option{
+		Keys:       signature.NewMemoryKeyStore(newKey),
+		NonceStore: nonce.NewMemoryStore(),
+	})
+
+	req := newSignedRequest(t, &signature.SignerV2{Key: newKey, Nonce: true}, "http://campaign-service/campaigns", "")
+	replayed := req.Clone(req.Context())
+
+	_, err := verifier.Verify(req)
+	require.NoError(t, err)
+
+	_, err = verifier.Verify(replayed)
+	assert.Equal(t, signature.ErrSignatureReplayed, err)
+
+	req = newSignedRequest(t, &signature.SignerV2{Key: newKey}, "http://campaign-service/campaigns", "")
+	_, err = verifier.Verify(req)
+	assert.True(t, errors.Is(err, signature.ErrInvalidSignature))
+}
+
+func TestVerifyV2MaxBodySize(t *testing.T) {
+	verifier := signature.NewVerifierV2(signature.VerifierV2Option{
+		Keys:        signature.NewMemoryKeyStore(newKey),
+		MaxBodySize: 16,
+	})
+
+	req := newSignedRequest(t, &signature.SignerV2{Key: newKey}, "http://campaign-service/donations", `{"amount":10000}`)
+	_, err := verifier.Verify(req)
+	require.NoError(t, err)
+
+	// declared length
+	req = newSignedRequest(t, &signature.SignerV2{Key: newKey}, "http://campaign-service/donations", `{"amount":100000}`)
+	_, err = verifier.Verify(req)
+	assert.True(t, errors.Is(err, signature.ErrBodyTooLarge))
+
+	// chunked body is not read beyond the limit
+	req = newSignedRequest(t, &signature.SignerV2{Key: newKey}, "http://campaign-service/donations", `{"amount":100000}`)
+	req.ContentLength = -1
+	_, err = verifier.Verify(req)
+	assert.True(t, errors.Is(err, signature.ErrBodyTooLarge))
+}
+
+func TestCanonicalRequest(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "http://Campaign-Service/campaigns/a%2Fb?z=1&a=b%20c&a=a", nil)
+	req.Header.Set(signature.TimeHeader, "1630000000")
+	req.Header.Set("X-Custom", "  a   b ")
+
+	expected := strings.Join([]string{
+		"KTBS-HMAC-SHA256",
+		"1630000000",
+		"",
+		"GET",
+		"/campaigns/a%2Fb",
+		"a=a&a=b%20c&z=1",
+		"host:campaign-service",
+		"x-custom:a b",
+		"host;x-custom",
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+	}, "\n")
+
+	assert.Equal(t, expected, signature.CanonicalRequest(req, []string{"host", "x-custom"},
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"))
+}
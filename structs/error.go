@@ -58,3 +58,58 @@ var ErrInvalidHeaderTime *ErrorResponse = &ErrorResponse{
 	},
 	HttpStatus: http.StatusBadRequest,
 }
+
+var ErrOriginNotAllowed *ErrorResponse = &ErrorResponse{
+	Response: Response{
+		ResponseCode: "00006",
+		ResponseDesc: ResponseDesc{
+			ID: "Origin tidak diijinkan",
+			EN: "Origin not allowed",
+		},
+	},
+	HttpStatus: http.StatusForbidden,
+}
+
+var ErrPayloadTooLarge *ErrorResponse = &ErrorResponse{
+	Response: Response{
+		ResponseCode: "00007",
+		ResponseDesc: ResponseDesc{
+			ID: "Ukuran request terlalu besar",
+			EN: "Request payload too large",
+		},
+	},
+	HttpStatus: http.StatusRequestEntityTooLarge,
+}
+
+var ErrUnsupportedMediaType *ErrorResponse = &ErrorResponse{
+	Response: Response{
+		ResponseCode: "00008",
+		ResponseDesc: ResponseDesc{
+			ID: "Tipe konten tidak didukung",
+			EN: "Unsupported media type",
+		},
+	},
+	HttpStatus: http.StatusUnsupportedMediaType,
+}
+
+var ErrForbidden *ErrorResponse = &ErrorResponse{
+	Response: Response{
+		ResponseCode: "00009",
+		ResponseDesc: ResponseDesc{
+			ID: "Anda tidak memiliki akses",
+			EN: "You don't have permission to access this resource",
+		},
+	},
+	HttpStatus: http.StatusForbidden,
+}
+
+var ErrRequestReplayed *ErrorResponse = &ErrorResponse{
+	Response: Response{
+		ResponseCode: "00010",
+		ResponseDesc: ResponseDesc{
+			ID: "Request sudah pernah dikirim",
+			EN: "Request already sent",
+		},
+	},
+	HttpStatus: http.StatusBadRequest,
+}
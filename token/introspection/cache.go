@@ -0,0 +1,130 @@
+package introspection
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/kitabisa/perkakas/v2/httpclient"
+	"github.com/rs/zerolog/log"
+)
+
+// CacheOption is introspection cache option
+type CacheOption struct {
+	// Store stores introspection result by token hash. Default httpclient.NewMemoryCacheStore(10000),
+	// use httpclient.NewRedisCacheStore to share the cache between instances.
+	Store httpclient.CacheStore
+	// Prefix is cache key prefix. Default "introspection:".
+	Prefix string
+	// MaxTTL caps how long active token is cached, so token revoked at the authorization server is rejected after at
+	// most MaxTTL. Zero caches active token until it expires. Active token without exp is cached only when MaxTTL is set.
+	MaxTTL time.Duration
+	// NegativeTTL is how long inactive token is cached. Default 1 minute, negative disables negative caching.
+	NegativeTTL time.Duration
+}
+
+// CachedIntrospector caches positive and negative introspection result
+type CachedIntrospector struct {
+	introspector Introspector
+	option       CacheOption
+}
+
+var _ Introspector = (*CachedIntrospector)(nil)
+
+// NewCachedIntrospector creates caching introspector
+func NewCachedIntrospector(introspector Introspector, option CacheOption) *CachedIntrospector {
+	if option.Store == nil {
+		option.Store = httpclient.NewMemoryCacheStore(10000)
+	}
+
+	if option.Prefix == "" {
+		option.Prefix = "introspection:"
+	}
+
+	if option.NegativeTTL == 0 {
+		option.NegativeTTL = time.Minute
+	}
+
+	return &CachedIntrospector{
+		introspector: introspector,
+		option:       option,
+	}
+}
+
+// Introspect returns cached result, or introspects the token and caches the result
+func (c *CachedIntrospector) Introspect(ctx context.Context, token string) (*Response, error) {
+	key := c.cacheKey(token)
+
+	if resp, ok := c.load(key); ok {
+		return resp, nil
+	}
+
+	resp, err := c.introspector.Introspect(ctx, token)
+	if err != nil {
+		// failure is not cached, so the next request retries
+		return nil, err
+	}
+
+	c.save(key, resp)
+	return resp, nil
+}
+
+func (c *CachedIntrospector) load(key string) (*Response, bool) {
+	b, found, err := c.option.Store.Get(key)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to load cached introspection result")
+		return nil, false
+	}
+
+	if !found {
+		return nil, false
+	}
+
+	var resp Response
+	if err = json.Unmarshal(b, &resp); err != nil {
+		return nil, false
+	}
+
+	return &resp, true
+}
+
+func (c *CachedIntrospector) save(key string, resp *Response) {
+	ttl := c.ttl(resp)
+	if ttl <= 0 {
+		return
+	}
+
+	b, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+
+	if err = c.option.Store.Set(key, b, ttl); err != nil {
+		log.Error().Err(err).Msg("failed to cache introspection result")
+	}
+}
+
+func (c *CachedIntrospector) ttl(resp *Response) time.Duration {
+	if !resp.Valid(time.Now()) {
+		return c.option.NegativeTTL
+	}
+
+	if resp.Exp == 0 {
+		return c.option.MaxTTL
+	}
+
+	ttl := time.Until(time.Unix(resp.Exp, 0))
+	if c.option.MaxTTL > 0 && ttl > c.option.MaxTTL {
+		ttl = c.option.MaxTTL
+	}
+
+	return ttl
+}
+
+// cacheKey is hash of the token, so the cache doesn't contain usable token
+func (c *CachedIntrospector) cacheKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return c.option.Prefix + hex.EncodeToString(sum[:])
+}
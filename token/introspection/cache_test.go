@@ -0,0 +1,96 @@
+package introspection
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kitabisa/perkakas/v2/httpclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingIntrospector counts introspection call
+type countingIntrospector struct {
+	Introspector
+	calls int
+	err   error
+}
+
+func (c *countingIntrospector) Introspect(ctx context.Context, token string) (*Response, error) {
+	c.calls++
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	return c.Introspector.Introspect(ctx, token)
+}
+
+func TestCachedIntrospector(t *testing.T) {
+	upstream := &countingIntrospector{Introspector: StaticIntrospector{
+		"opaque-1": {Active: true, Sub: "12345", Exp: time.Now().Add(time.Hour).Unix()},
+		"opaque-2": {Active: true, Sub: "12345", Exp: time.Now().Add(time.Hour).Unix()},
+		"no-exp":   {Active: true, Sub: "12345"},
+	}}
+	store := httpclient.NewMemoryCacheStore(100)
+	cached := NewCachedIntrospector(upstream, CacheOption{Store: store, MaxTTL: 10 * time.Millisecond})
+
+	// positive result is cached
+	for i := 0; i < 3; i++ {
+		resp, err := cached.Introspect(context.Background(), "opaque-1")
+		require.NoError(t, err)
+		assert.True(t, resp.Active)
+	}
+	assert.Equal(t, 1, upstream.calls)
+
+	// negative result is cached
+	for i := 0; i < 3; i++ {
+		resp, err := cached.Introspect(context.Background(), "unknown")
+		require.NoError(t, err)
+		assert.False(t, resp.Active)
+	}
+	assert.Equal(t, 2, upstream.calls)
+
+	// positive result expires after MaxTTL, negative result is still cached
+	time.Sleep(20 * time.Millisecond)
+	_, err := cached.Introspect(context.Background(), "opaque-1")
+	require.NoError(t, err)
+	_, err = cached.Introspect(context.Background(), "unknown")
+	require.NoError(t, err)
+	assert.Equal(t, 3, upstream.calls)
+
+	// token is not stored in cache key
+	_, found, _ := store.Get("introspection:opaque-1")
+	assert.False(t, found)
+
+	// failure is not cached
+	upstream.err = errors.New("connection refused")
+	_, err = cached.Introspect(context.Background(), "opaque-2")
+	assert.Error(t, err)
+	upstream.err = nil
+	_, err = cached.Introspect(context.Background(), "opaque-2")
+	require.NoError(t, err)
+	assert.Equal(t, 5, upstream.calls)
+}
+
+func TestCachedIntrospectorWithoutMaxTTL(t *testing.T) {
+	upstream := &countingIntrospector{Introspector: StaticIntrospector{
+		"no-exp": {Active: true, Sub: "12345"},
+	}}
+	cached := NewCachedIntrospector(upstream, CacheOption{NegativeTTL: -1})
+
+	// active token without exp is not cached
+	for i := 0; i < 2; i++ {
+		_, err := cached.Introspect(context.Background(), "no-exp")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, upstream.calls)
+
+	// negative caching is disabled
+	for i := 0; i < 2; i++ {
+		_, err := cached.Introspect(context.Background(), "unknown")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 4, upstream.calls)
+}
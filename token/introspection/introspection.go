@@ -0,0 +1,190 @@
+// Package introspection validates opaque OAuth2 access token using RFC 7662 token introspection
+
+package introspection
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kitabisa/perkakas/v2/token/jwt"
+)
+
+// ErrIntrospectionFailed is returned when introspection endpoint doesn't return valid response
+var ErrIntrospectionFailed = errors.New("introspection: introspection request failed")
+
+// Introspector introspects access token, implemented by Client, CachedIntrospector and StaticIntrospector
+type Introspector interface {
+	Introspect(ctx context.Context, token string) (*Response, error)
+}
+
+// Response is RFC 7662 introspection response
+type Response struct {
+	Active    bool     `json:"active"`
+	Scope     string   `json:"scope,omitempty"` // space separated scopes
+	ClientID  string   `json:"client_id,omitempty"`
+	Username  string   `json:"username,omitempty"`
+	TokenType string   `json:"token_type,omitempty"`
+	Exp       int64    `json:"exp,omitempty"`
+	Iat       int64    `json:"iat,omitempty"`
+	Nbf       int64    `json:"nbf,omitempty"`
+	Sub       string   `json:"sub,omitempty"`
+	Aud       Audience `json:"aud,omitempty"`
+	Iss       string   `json:"iss,omitempty"`
+	Jti       string   `json:"jti,omitempty"`
+}
+
+// Valid checks the token is active and within its exp and nbf at the time
+func (r *Response) Valid(at time.Time) bool {
+	if !r.Active {
+		return false
+	}
+
+	if r.Exp != 0 && at.Unix() >= r.Exp {
+		return false
+	}
+
+	return r.Nbf == 0 || at.Unix() >= r.Nbf
+}
+
+// Scopes gets granted scopes
+func (r *Response) Scopes() []string {
+	return strings.Fields(r.Scope)
+}
+
+// Audience is aud claim, which is either a string or an array of string
+type Audience []string
+
+func (a *Audience) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(b, &multiple); err != nil {
+		return fmt.Errorf("introspection: aud must be string or array of string: %w", err)
+	}
+
+	*a = multiple
+	return nil
+}
+
+// ToUserClaim maps introspection response into jwt.UserClaim, the claims stored by jwt middleware. Scope is split into
+// Scopes, and sub is parsed into UserID when it is numeric. Only the first audience is kept.
+func ToUserClaim(r *Response) *jwt.UserClaim {
+	claims := &jwt.UserClaim{
+		ClientID: r.ClientID,
+		Scopes:   r.Scopes(),
+	}
+
+	claims.Subject = r.Sub
+	claims.Issuer = r.Iss
+	claims.Id = r.Jti
+	claims.ExpiresAt = r.Exp
+	claims.IssuedAt = r.Iat
+	claims.NotBefore = r.Nbf
+	if len(r.Aud) > 0 {
+		claims.Audience = r.Aud[0]
+	}
+
+	if userID, err := strconv.ParseInt(r.Sub, 10, 64); err == nil {
+		claims.UserID = userID
+	}
+
+	return claims
+}
+
+// Doer sends http request, implemented by http.Client and httpclient.HttpClient
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// ClientOption is introspection client option
+type ClientOption struct {
+	// Endpoint is introspection endpoint of the authorization server
+	Endpoint string
+	// ClientID and ClientSecret authenticate this resource server to the authorization server using basic auth
+	ClientID     string
+	ClientSecret string
+	// HTTPClient sends the request. Default http.Client with 5 seconds timeout.
+	HTTPClient Doer
+}
+
+// Client calls introspection endpoint
+type Client struct {
+	option ClientOption
+}
+
+var _ Introspector = (*Client)(nil)
+
+// NewClient creates introspection client
+func NewClient(option ClientOption) *Client {
+	if option.HTTPClient == nil {
+		option.HTTPClient = &http.Client{Timeout: 5 * time.Second}
+	}
+
+	return &Client{
+		option: option,
+	}
+}
+
+func (c *Client) Introspect(ctx context.Context, token string) (*Response, error) {
+	form := url.Values{}
+	form.Set("token", token)
+	form.Set("token_type_hint", "access_token")
+
+	req, err := http.NewRequest(http.MethodPost, c.option.Endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrIntrospectionFailed, err)
+	}
+
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.option.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(c.option.ClientID), url.QueryEscape(c.option.ClientSecret))
+	}
+
+	resp, err := c.option.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrIntrospectionFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil, fmt.Errorf("%w: status %d", ErrIntrospectionFailed, resp.StatusCode)
+	}
+
+	var result Response
+	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrIntrospectionFailed, err)
+	}
+
+	return &result, nil
+}
+
+// StaticIntrospector introspects token from fixed responses, e.g. to stub the authorization server in test.
+// Unknown token is inactive.
+type StaticIntrospector map[string]Response
+
+var _ Introspector = StaticIntrospector(nil)
+
+func (s StaticIntrospector) Introspect(ctx context.Context, token string) (*Response, error) {
+	resp, ok := s[token]
+	if !ok {
+		return &Response{Active: false}, nil
+	}
+
+	return &resp, nil
+}
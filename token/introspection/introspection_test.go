@@ -0,0 +1,82 @@
+package introspection
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newStubServer stubs the introspection endpoint of the authorization server
+func newStubServer(t *testing.T, tokens map[string]Response) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "campaign-service" || password != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "access_token", r.PostForm.Get("token_type_hint"))
+
+		resp, ok := tokens[r.PostForm.Get("token")]
+		if !ok {
+			resp = Response{Active: false}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func TestClient(t *testing.T) {
+	exp := time.Now().Add(time.Hour).Unix()
+	srv := newStubServer(t, map[string]Response{
+		"opaque-1": {Active: true, Scope: "campaign:read donation:read", ClientID: "web", Sub: "12345", Exp: exp, Aud: Audience{"campaign-service"}},
+	})
+	defer srv.Close()
+
+	client := NewClient(ClientOption{Endpoint: srv.URL, ClientID: "campaign-service", ClientSecret: "s3cret"})
+
+	resp, err := client.Introspect(context.Background(), "opaque-1")
+	require.NoError(t, err)
+	assert.True(t, resp.Valid(time.Now()))
+	assert.Equal(t, []string{"campaign:read", "donation:read"}, resp.Scopes())
+
+	resp, err = client.Introspect(context.Background(), "unknown")
+	require.NoError(t, err)
+	assert.False(t, resp.Valid(time.Now()))
+
+	wrongSecret := NewClient(ClientOption{Endpoint: srv.URL, ClientID: "campaign-service", ClientSecret: "wrong"})
+	_, err = wrongSecret.Introspect(context.Background(), "opaque-1")
+	assert.True(t, errors.Is(err, ErrIntrospectionFailed))
+}
+
+func TestResponse(t *testing.T) {
+	var resp Response
+	require.NoError(t, json.Unmarshal([]byte(`{"active":true,"aud":"campaign-service","sub":"12345","scope":"campaign:read","client_id":"web","exp":2000000000}`), &resp))
+	assert.Equal(t, Audience{"campaign-service"}, resp.Aud)
+
+	claims := ToUserClaim(&resp)
+	assert.Equal(t, int64(12345), claims.UserID)
+	assert.Equal(t, "12345", claims.Subject)
+	assert.Equal(t, "web", claims.ClientID)
+	assert.Equal(t, []string{"campaign:read"}, claims.Scopes)
+	assert.Equal(t, "campaign-service", claims.Audience)
+	assert.Equal(t, int64(2000000000), claims.ExpiresAt)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"active":true,"aud":["a","b"],"sub":"cac2ee7e"}`), &resp))
+	assert.Equal(t, Audience{"a", "b"}, resp.Aud)
+	assert.Equal(t, int64(0), ToUserClaim(&resp).UserID)
+
+	now := time.Now()
+	assert.False(t, (&Response{Active: true, Exp: now.Add(-time.Second).Unix()}).Valid(now))
+	assert.False(t, (&Response{Active: true, Nbf: now.Add(time.Minute).Unix()}).Valid(now))
+	assert.True(t, (&Response{Active: true}).Valid(now))
+}
@@ -0,0 +1,39 @@
+package jwt
+
+import (
+	"context"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/kitabisa/perkakas/v2/ctxkeys"
+)
+
+// WithClaims stores claims of verified jwt token into context
+func WithClaims(ctx context.Context, claims jwt.Claims) context.Context {
+	return ctxkeys.WithUserClaim(ctx, claims)
+}
+
+// ClaimsFrom gets claims of verified jwt token from context, e.g. custom claims of middleware.WithClaims.
+// It falls back to the deprecated string key "token" for context that is set by older middleware.
+func ClaimsFrom(ctx context.Context) (jwt.Claims, bool) {
+	if v, ok := ctxkeys.UserClaimFrom(ctx); ok {
+		if claims, ok := v.(jwt.Claims); ok {
+			return claims, true
+		}
+	}
+
+	claims, ok := ctx.Value("token").(jwt.Claims)
+	return claims, ok
+}
+
+// WithUserClaim stores claim of verified jwt token into context
+func WithUserClaim(ctx context.Context, claim *UserClaim) context.Context {
+	return WithClaims(ctx, claim)
+}
+
+// UserClaimFrom gets claim of verified jwt token from context. It returns false when there is no token,
+// or the token is decoded into custom claims.
+func UserClaimFrom(ctx context.Context) (*UserClaim, bool) {
+	claims, _ := ClaimsFrom(ctx)
+	claim, ok := claims.(*UserClaim)
+	return claim, ok
+}
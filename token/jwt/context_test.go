@@ -0,0 +1,34 @@
+package jwt
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kitabisa/perkakas/v2/ctxkeys"
+	libpaseto "github.com/o1egl/paseto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserClaimFrom(t *testing.T) {
+	claim := &UserClaim{UserID: 12345}
+
+	got, ok := UserClaimFrom(WithUserClaim(context.Background(), claim))
+	assert.True(t, ok)
+	assert.Equal(t, claim, got)
+
+	stored, ok := ctxkeys.UserClaimFrom(WithUserClaim(context.Background(), claim))
+	assert.True(t, ok)
+	assert.Equal(t, claim, stored)
+
+	// deprecated key
+	got, ok = UserClaimFrom(context.WithValue(context.Background(), "token", claim))
+	assert.True(t, ok)
+	assert.Equal(t, claim, got)
+
+	_, ok = UserClaimFrom(context.Background())
+	assert.False(t, ok)
+
+	// paseto token under the deprecated key is not a claim
+	_, ok = UserClaimFrom(context.WithValue(context.Background(), "token", libpaseto.JSONToken{}))
+	assert.False(t, ok)
+}
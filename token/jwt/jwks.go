@@ -0,0 +1,514 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+var (
+	// ErrKeyNotFound is returned when key with the kid is not found in key source
+	ErrKeyNotFound = errors.New("jwt: key not found")
+	// ErrMissingKeyID is returned when token header has no kid
+	ErrMissingKeyID = errors.New("jwt: token has no kid header")
+	// ErrUnsupportedKey is returned when JWK key type, curve or algorithm is not supported
+	ErrUnsupportedKey = errors.New("jwt: unsupported key")
+)
+
+// Supported JWKS signing algorithm
+const (
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+	AlgEdDSA = "EdDSA"
+)
+
+// Key is public key for verifying token
+type Key struct {
+	ID        string
+	Algorithm string
+	PublicKey crypto.PublicKey
+}
+
+// KeySource provides verification key by kid, e.g. JWKS file or url
+type KeySource interface {
+	Key(kid string) (*Key, error)
+}
+
+// JSONWebKey is public JSON Web Key (RFC 7517). Only RSA, EC P-256 and OKP Ed25519 public key is supported.
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+	Use       string `json:"use,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+// JSONWebKeySet is JWKS document
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// NewJSONWebKey creates JWK from *rsa.PublicKey, *ecdsa.PublicKey or ed25519.PublicKey
+func NewJSONWebKey(kid string, publicKey crypto.PublicKey) (JSONWebKey, error) {
+	enc := base64.RawURLEncoding
+
+	switch pub := publicKey.(type) {
+	case *rsa.PublicKey:
+		return JSONWebKey{
+			KeyType:   "RSA",
+			KeyID:     kid,
+			Algorithm: AlgRS256,
+			Use:       "sig",
+			N:         enc.EncodeToString(pub.N.Bytes()),
+			E:         enc.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}, nil
+	case *ecdsa.PublicKey:
+		if pub.Curve != elliptic.P256() {
+			return JSONWebKey{}, ErrUnsupportedKey
+		}
+
+		return JSONWebKey{
+			KeyType:   "EC",
+			KeyID:     kid,
+			Algorithm: AlgES256,
+			Use:       "sig",
+			Curve:     "P-256",
+			X:         enc.EncodeToString(padBytes(pub.X.Bytes(), 32)),
+			Y:         enc.EncodeToString(padBytes(pub.Y.Bytes(), 32)),
+		}, nil
+	case ed25519.PublicKey:
+		return JSONWebKey{
+			KeyType:   "OKP",
+			KeyID:     kid,
+			Algorithm: AlgEdDSA,
+			Use:       "sig",
+			Curve:     "Ed25519",
+			X:         enc.EncodeToString(pub),
+		}, nil
+	}
+
+	return JSONWebKey{}, ErrUnsupportedKey
+}
+
+func padBytes(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+
+	return append(make([]byte, size-len(b)), b...)
+}
+
+// Key converts JWK into verification key. Algorithm is inferred from the key type when alg is empty.
+func (k JSONWebKey) Key() (*Key, error) {
+	dec := base64.RawURLEncoding
+	key := &Key{ID: k.KeyID, Algorithm: k.Algorithm}
+
+	switch k.KeyType {
+	case "RSA":
+		n, err := dec.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: invalid rsa modulus of key %s: %w", k.KeyID, err)
+		}
+
+		e, err := dec.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: invalid rsa exponent of key %s: %w", k.KeyID, err)
+		}
+
+		key.PublicKey = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if key.Algorithm == "" {
+			key.Algorithm = AlgRS256
+		}
+	case "EC":
+		if k.Curve != "P-256" {
+			return nil, fmt.Errorf("%w: curve %s of key %s", ErrUnsupportedKey, k.Curve, k.KeyID)
+		}
+
+		x, err := dec.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: invalid ec x coordinate of key %s: %w", k.KeyID, err)
+		}
+
+		y, err := dec.DecodeString(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: invalid ec y coordinate of key %s: %w", k.KeyID, err)
+		}
+
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, fmt.Errorf("jwt: invalid ec point of key %s", k.KeyID)
+		}
+
+		key.PublicKey = pub
+		if key.Algorithm == "" {
+			key.Algorithm = AlgES256
+		}
+	case "OKP":
+		if k.Curve != "Ed25519" {
+			return nil, fmt.Errorf("%w: curve %s of key %s", ErrUnsupportedKey, k.Curve, k.KeyID)
+		}
+
+		x, err := dec.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("jwt: invalid ed25519 public key of key %s", k.KeyID)
+		}
+
+		key.PublicKey = ed25519.PublicKey(x)
+		if key.Algorithm == "" {
+			key.Algorithm = AlgEdDSA
+		}
+	default:
+		return nil, fmt.Errorf("%w: key type %s of key %s", ErrUnsupportedKey, k.KeyType, k.KeyID)
+	}
+
+	switch key.Algorithm {
+	case AlgRS256, AlgES256, AlgEdDSA:
+	default:
+		return nil, fmt.Errorf("%w: algorithm %s of key %s", ErrUnsupportedKey, key.Algorithm, k.KeyID)
+	}
+
+	return key, nil
+}
+
+// parseJWKS parses JWKS document into keys by kid. Unsupported and non signature key is skipped.
+func parseJWKS(data []byte) (map[string]*Key, error) {
+	var set JSONWebKeySet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("jwt: invalid jwks: %w", err)
+	}
+
+	keys := make(map[string]*Key, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.Key()
+		if err != nil {
+			continue
+		}
+
+		keys[key.ID] = key
+	}
+
+	return keys, nil
+}
+
+// StaticKeySource is key source with fixed keys, e.g. loaded from JWKS file
+type StaticKeySource struct {
+	keys map[string]*Key
+}
+
+// NewStaticKeySource creates key source from JWKS document
+func NewStaticKeySource(jwks []byte) (*StaticKeySource, error) {
+	keys, err := parseJWKS(jwks)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StaticKeySource{keys: keys}, nil
+}
+
+// NewFileKeySource creates key source from JWKS file
+func NewFileKeySource(path string) (*StaticKeySource, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("jwt: failed to read jwks file: %w", err)
+	}
+
+	return NewStaticKeySource(data)
+}
+
+func (s *StaticKeySource) Key(kid string) (*Key, error) {
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, kid)
+	}
+
+	return key, nil
+}
+
+// HTTPDoer sends http request, implemented by *http.Client and httpclient.HttpClient
+type HTTPDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// RemoteKeySourceOption is remote JWKS key source option
+type RemoteKeySourceOption struct {
+	// HTTPClient fetches the JWKS. Default is http.Client with 10 seconds timeout.
+	HTTPClient HTTPDoer
+	// RefreshInterval is interval of background refresh. Default 1 hour.
+	RefreshInterval time.Duration
+	// MinRefreshInterval is minimum interval between refresh triggered by unknown kid, e.g. right after key rotation.
+	// Failed refresh is throttled too, so unknown kid doesn't hammer the JWKS endpoint while it is down. Default 1 minute.
+	MinRefreshInterval time.Duration
+}
+
+// RemoteKeySource is key source fetching JWKS from url. Keys are cached and refreshed in background,
+// or when token has unknown kid. Call Close to stop the background refresh.
+type RemoteKeySource struct {
+	url    string
+	option RemoteKeySourceOption
+	cancel context.CancelFunc
+
+	mu   sync.RWMutex
+	keys map[string]*Key
+
+	refreshMu   sync.Mutex
+	attemptedAt time.Time // last refresh attempt, guarded by refreshMu
+}
+
+// NewRemoteKeySource creates key source from JWKS url. It fetches the JWKS immediately and returns error when it fails.
+func NewRemoteKeySource(url string, option RemoteKeySourceOption) (*RemoteKeySource, error) {
+	if option.HTTPClient == nil {
+		option.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	if option.RefreshInterval <= 0 {
+		option.RefreshInterval = time.Hour
+	}
+
+	if option.MinRefreshInterval <= 0 {
+		option.MinRefreshInterval = time.Minute
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &RemoteKeySource{
+		url:    url,
+		option: option,
+		cancel: cancel,
+	}
+
+	if err := s.Refresh(ctx); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	go s.refreshLoop(ctx)
+	return s, nil
+}
+
+func (s *RemoteKeySource) Key(kid string) (*Key, error) {
+	if key, ok := s.cachedKey(kid); ok {
+		return key, nil
+	}
+
+	// the key may be just rotated
+	if err := s.refreshThrottled(context.Background()); err != nil {
+		return nil, err
+	}
+
+	if key, ok := s.cachedKey(kid); ok {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, kid)
+}
+
+func (s *RemoteKeySource) cachedKey(kid string) (*Key, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+// refreshThrottled refreshes the JWKS unless it was attempted within MinRefreshInterval, successful or not.
+// Concurrent callers wait for the running refresh and don't fetch again.
+func (s *RemoteKeySource) refreshThrottled(ctx context.Context) error {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	if time.Since(s.attemptedAt) < s.option.MinRefreshInterval {
+		return nil
+	}
+
+	return s.refresh(ctx)
+}
+
+// Refresh fetches the JWKS. Concurrent refresh is serialized.
+func (s *RemoteKeySource) Refresh(ctx context.Context) error {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	return s.refresh(ctx)
+}
+
+// refresh fetches the JWKS, refreshMu must be held
+func (s *RemoteKeySource) refresh(ctx context.Context) error {
+	s.attemptedAt = time.Now()
+
+	req, err := http.NewRequest(http.MethodGet, s.url, nil)
+	if err != nil {
+		return fmt.Errorf("jwt: failed to create jwks request: %w", err)
+	}
+
+	resp, err := s.option.HTTPClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("jwt: failed to fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("jwt: failed to fetch jwks: status %d", resp.StatusCode)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("jwt: failed to read jwks: %w", err)
+	}
+
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *RemoteKeySource) refreshLoop(ctx context.Context) {
+	ticker := time.NewTicker(s.option.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// keep the cached keys when refresh fails, it is retried on the next tick or unknown kid
+			_ = s.Refresh(ctx)
+		}
+	}
+}
+
+// Close stops the background refresh
+func (s *RemoteKeySource) Close() {
+	s.cancel()
+}
+
+// JWTKeySource verifies token using key from KeySource picked by kid header
+type JWTKeySource struct {
+	source KeySource
+	parser *jwt.Parser
+}
+
+// NewJWTKeySource creates token verifier using key source. Only RS256, ES256 and EdDSA token is accepted.
+func NewJWTKeySource(source KeySource) *JWTKeySource {
+	return &JWTKeySource{
+		source: source,
+		parser: &jwt.Parser{ValidMethods: []string{AlgRS256, AlgES256, AlgEdDSA}},
+	}
+}
+
+func (j *JWTKeySource) Parse(token string) (tkn *jwt.Token, err error) {
+	tkn, err = j.parser.ParseWithClaims(token, &UserClaim{}, j.keyFunction)
+	return
+}
+
+// VerificationKey returns key of the token kid, see Verifier
+func (j *JWTKeySource) VerificationKey(token *jwt.Token) (interface{}, error) {
+	return j.keyFunction(token)
+}
+
+// Algorithms returns RS256, ES256 and EdDSA, see Verifier
+func (j *JWTKeySource) Algorithms() []string {
+	return j.parser.ValidMethods
+}
+
+func (j *JWTKeySource) keyFunction(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, ErrMissingKeyID
+	}
+
+	key, err := j.source.Key(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	// prevent algorithm confusion, token must use the algorithm of the key
+	if key.Algorithm != token.Method.Alg() {
+		return nil, fmt.Errorf("jwt: token algorithm %s doesn't match key %s algorithm %s", token.Method.Alg(), kid, key.Algorithm)
+	}
+
+	return key.PublicKey, nil
+}
+
+func (j *JWTKeySource) Valid(token string) (valid bool, err error) {
+	tkn, err := j.Parse(token)
+	if err != nil {
+		return
+	}
+
+	valid = tkn.Valid
+	return
+}
+
+// JWTSigner creates token signed with private key, with kid in the token header
+type JWTSigner struct {
+	kid        string
+	method     jwt.SigningMethod
+	privateKey crypto.PrivateKey
+	publicKey  crypto.PublicKey
+}
+
+// NewJWTSigner creates signer from *rsa.PrivateKey (RS256), *ecdsa.PrivateKey P-256 (ES256) or ed25519.PrivateKey (EdDSA)
+func NewJWTSigner(kid string, privateKey crypto.PrivateKey) (*JWTSigner, error) {
+	s := &JWTSigner{kid: kid, privateKey: privateKey}
+
+	switch key := privateKey.(type) {
+	case *rsa.PrivateKey:
+		s.method = jwt.SigningMethodRS256
+		s.publicKey = &key.PublicKey
+	case *ecdsa.PrivateKey:
+		if key.Curve != elliptic.P256() {
+			return nil, ErrUnsupportedKey
+		}
+
+		s.method = jwt.SigningMethodES256
+		s.publicKey = &key.PublicKey
+	case ed25519.PrivateKey:
+		s.method = jwt.SigningMethodEdDSA
+		s.publicKey = key.Public()
+	default:
+		return nil, ErrUnsupportedKey
+	}
+
+	return s, nil
+}
+
+func (s *JWTSigner) Create(claims jwt.Claims) (tokenString string, err error) {
+	token := jwt.NewWithClaims(s.method, claims)
+	token.Header["kid"] = s.kid
+	tokenString, err = token.SignedString(s.privateKey)
+	return
+}
+
+// JSONWebKey returns public JWK of the signer, to be published in JWKS
+func (s *JWTSigner) JSONWebKey() JSONWebKey {
+	// public key type is always supported, it is checked in NewJWTSigner
+	jwk, _ := NewJSONWebKey(s.kid, s.publicKey)
+	return jwk
+}
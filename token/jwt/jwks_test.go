@@ -0,0 +1,245 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSigners(t *testing.T) []*JWTSigner {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	var signers []*JWTSigner
+	for kid, key := range map[string]interface{}{"rsa-1": rsaKey, "ec-1": ecKey, "ed-1": edKey} {
+		signer, err := NewJWTSigner(kid, key)
+		require.NoError(t, err)
+		signers = append(signers, signer)
+	}
+
+	return signers
+}
+
+func jwksOf(t *testing.T, signers ...*JWTSigner) []byte {
+	var set JSONWebKeySet
+	for _, s := range signers {
+		set.Keys = append(set.Keys, s.JSONWebKey())
+	}
+
+	b, err := json.Marshal(set)
+	require.NoError(t, err)
+
+	return b
+}
+
+func newTestClaim() UserClaim {
+	claims := UserClaim{}
+	claims.UserID = 12345
+	claims.ExpiresAt = time.Now().Add(time.Minute).Unix()
+	return claims
+}
+
+func TestJWTKeySource(t *testing.T) {
+	signers := newTestSigners(t)
+	source, err := NewStaticKeySource(jwksOf(t, signers...))
+	require.NoError(t, err)
+
+	verifier := NewJWTKeySource(source)
+	for _, signer := range signers {
+		token, err := signer.Create(newTestClaim())
+		require.NoError(t, err)
+
+		parsed, err := verifier.Parse(token)
+		require.NoError(t, err, signer.kid)
+		assert.Equal(t, signer.kid, parsed.Header["kid"])
+		assert.Equal(t, int64(12345), parsed.Claims.(*UserClaim).UserID)
+	}
+}
+
+func TestJWTKeySourceInvalid(t *testing.T) {
+	signers := newTestSigners(t)
+	source, err := NewStaticKeySource(jwksOf(t, signers[0]))
+	require.NoError(t, err)
+
+	verifier := NewJWTKeySource(source)
+
+	// unknown kid
+	token, _ := signers[1].Create(newTestClaim())
+	_, err = verifier.Parse(token)
+	var validationErr *jwt.ValidationError
+	require.True(t, errors.As(err, &validationErr))
+	assert.True(t, errors.Is(validationErr.Inner, ErrKeyNotFound))
+
+	// missing kid
+	token, _ = NewJWT([]byte("secret")).Create(newTestClaim())
+	_, err = verifier.Parse(token)
+	assert.Error(t, err)
+
+	// token signed by other key with the same kid
+	other := newTestSigners(t)
+	for _, s := range other {
+		if s.method == signers[0].method {
+			s.kid = signers[0].kid
+			token, _ = s.Create(newTestClaim())
+		}
+	}
+
+	valid, err := verifier.Valid(token)
+	assert.Error(t, err)
+	assert.False(t, valid)
+}
+
+func TestJSONWebKeyRoundTrip(t *testing.T) {
+	for _, signer := range newTestSigners(t) {
+		key, err := signer.JSONWebKey().Key()
+		require.NoError(t, err)
+		assert.Equal(t, signer.kid, key.ID)
+		assert.Equal(t, signer.method.Alg(), key.Algorithm)
+		assert.Equal(t, signer.publicKey, key.PublicKey)
+	}
+
+	_, err := JSONWebKey{KeyType: "oct", KeyID: "hmac"}.Key()
+	assert.True(t, errors.Is(err, ErrUnsupportedKey))
+}
+
+func TestRemoteKeySourceRotation(t *testing.T) {
+	signers := newTestSigners(t)
+
+	var fetches int32
+	var jwks atomic.Value
+	jwks.Store(jwksOf(t, signers[0]))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		w.Write(jwks.Load().([]byte))
+	}))
+	defer srv.Close()
+
+	source, err := NewRemoteKeySource(srv.URL, RemoteKeySourceOption{MinRefreshInterval: time.Millisecond})
+	require.NoError(t, err)
+	defer source.Close()
+
+	verifier := NewJWTKeySource(source)
+
+	token, _ := signers[0].Create(newTestClaim())
+	_, err = verifier.Parse(token)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches))
+
+	// rotate key, unknown kid triggers refresh
+	jwks.Store(jwksOf(t, signers[0], signers[1]))
+	time.Sleep(2 * time.Millisecond)
+
+	token, _ = signers[1].Create(newTestClaim())
+	_, err = verifier.Parse(token)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&fetches))
+}
+
+func TestRemoteKeySourceBackgroundRefresh(t *testing.T) {
+	signers := newTestSigners(t)
+
+	var jwks atomic.Value
+	jwks.Store(jwksOf(t, signers[0]))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(jwks.Load().([]byte))
+	}))
+	defer srv.Close()
+
+	source, err := NewRemoteKeySource(srv.URL, RemoteKeySourceOption{
+		RefreshInterval:    10 * time.Millisecond,
+		MinRefreshInterval: time.Hour,
+	})
+	require.NoError(t, err)
+	defer source.Close()
+
+	jwks.Store(jwksOf(t, signers[1]))
+
+	assert.Eventually(t, func() bool {
+		_, err := source.Key(signers[1].kid)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestRemoteKeySourceFetchError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	_, err := NewRemoteKeySource(srv.URL, RemoteKeySourceOption{})
+	assert.Error(t, err)
+}
+
+func TestRemoteKeySourceRefreshThrottled(t *testing.T) {
+	signers := newTestSigners(t)
+
+	var fetches int32
+	var down atomic.Value
+	down.Store(false)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		if down.Load().(bool) {
+			time.Sleep(10 * time.Millisecond)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Write(jwksOf(t, signers[0]))
+	}))
+	defer srv.Close()
+
+	source, err := NewRemoteKeySource(srv.URL, RemoteKeySourceOption{MinRefreshInterval: 50 * time.Millisecond})
+	require.NoError(t, err)
+	defer source.Close()
+
+	down.Store(true)
+	time.Sleep(60 * time.Millisecond)
+
+	keyConcurrently := func() {
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := source.Key("unknown")
+				assert.Error(t, err)
+			}()
+		}
+		wg.Wait()
+	}
+
+	// concurrent unknown kid fetches once
+	keyConcurrently()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&fetches))
+
+	// failed refresh is throttled too
+	keyConcurrently()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&fetches))
+
+	// cached key still works
+	_, err = source.Key(signers[0].kid)
+	assert.NoError(t, err)
+}
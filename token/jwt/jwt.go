@@ -17,6 +17,11 @@ type UserClaim struct {
 	jwt.StandardClaims
 }
 
+// GetScopes returns scopes granted to the token
+func (c *UserClaim) GetScopes() []string {
+	return c.Scopes
+}
+
 func NewJWT(signKey []byte) *JWT {
 	return &JWT{
 		SignKey: signKey,
@@ -30,7 +35,8 @@ func (j *JWT) Create(claims jwt.Claims) (tokenString string, err error) {
 }
 
 func (j *JWT) Parse(token string) (tkn *jwt.Token, err error) {
-	tkn, err = jwt.ParseWithClaims(token, &UserClaim{}, j.keyFunction)
+	parser := &jwt.Parser{ValidMethods: j.Algorithms()}
+	tkn, err = parser.ParseWithClaims(token, &UserClaim{}, j.keyFunction)
 	return
 }
 
@@ -38,6 +44,16 @@ func (j *JWT) keyFunction(token *jwt.Token) (interface{}, error) {
 	return []byte(j.SignKey), nil
 }
 
+// VerificationKey returns the sign key, see Verifier
+func (j *JWT) VerificationKey(token *jwt.Token) (interface{}, error) {
+	return j.keyFunction(token)
+}
+
+// Algorithms returns HS256, see Verifier
+func (j *JWT) Algorithms() []string {
+	return []string{jwt.SigningMethodHS256.Alg()}
+}
+
 func (j *JWT) Valid(token string) (valid bool, err error) {
 	tkn, err := j.Parse(token)
 	if err != nil {
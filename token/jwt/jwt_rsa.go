@@ -41,10 +41,21 @@ func (j *JWTRSA) Create(claims jwt.Claims) (tokenString string, err error) {
 }
 
 func (j *JWTRSA) Parse(token string) (tkn *jwt.Token, err error) {
-	tkn, err = jwt.ParseWithClaims(token, &UserClaim{}, j.keyFunction)
+	parser := &jwt.Parser{ValidMethods: j.Algorithms()}
+	tkn, err = parser.ParseWithClaims(token, &UserClaim{}, j.keyFunction)
 	return
 }
 
 func (j *JWTRSA) keyFunction(token *jwt.Token) (interface{}, error) {
 	return j.verifyKey, nil
 }
+
+// VerificationKey returns the rsa public key, see Verifier
+func (j *JWTRSA) VerificationKey(token *jwt.Token) (interface{}, error) {
+	return j.keyFunction(token)
+}
+
+// Algorithms returns RS256, see Verifier
+func (j *JWTRSA) Algorithms() []string {
+	return []string{jwt.SigningMethodRS256.Alg()}
+}
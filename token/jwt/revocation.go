@@ -0,0 +1,91 @@
+package jwt
+
+import (
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/kitabisa/perkakas/v2/token/revocation"
+)
+
+// RevocationToken gets jti, subject, iat and exp of the claims to check against revocation list.
+// Subject is sub claim, or user id of UserClaim without sub. Claims other than *UserClaim, struct embedding
+// jwt.StandardClaims and jwt.MapClaims are not supported.
+func RevocationToken(claims jwt.Claims) (revocation.Token, bool) {
+	var std *jwt.StandardClaims
+	switch c := claims.(type) {
+	case *UserClaim:
+		token := standardRevocationToken(&c.StandardClaims)
+		if token.Subject == "" && c.UserID != 0 {
+			token.Subject = strconv.FormatInt(c.UserID, 10)
+		}
+
+		return token, true
+	case *jwt.StandardClaims:
+		std = c
+	case jwt.MapClaims:
+		return mapRevocationToken(c), true
+	default:
+		std = embeddedStandardClaims(claims)
+	}
+
+	if std == nil {
+		return revocation.Token{}, false
+	}
+
+	return standardRevocationToken(std), true
+}
+
+// embeddedStandardClaims gets jwt.StandardClaims embedded in custom claims struct
+func embeddedStandardClaims(claims jwt.Claims) *jwt.StandardClaims {
+	v := reflect.ValueOf(claims)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+
+	field := v.Elem().FieldByName("StandardClaims")
+	if !field.IsValid() || !field.CanAddr() || !field.CanInterface() {
+		return nil
+	}
+
+	std, ok := field.Addr().Interface().(*jwt.StandardClaims)
+	if !ok {
+		return nil
+	}
+
+	return std
+}
+
+func standardRevocationToken(c *jwt.StandardClaims) revocation.Token {
+	token := revocation.Token{
+		ID:      c.Id,
+		Subject: c.Subject,
+	}
+
+	if c.IssuedAt != 0 {
+		token.IssuedAt = time.Unix(c.IssuedAt, 0)
+	}
+
+	if c.ExpiresAt != 0 {
+		token.ExpiresAt = time.Unix(c.ExpiresAt, 0)
+	}
+
+	return token
+}
+
+func mapRevocationToken(c jwt.MapClaims) revocation.Token {
+	token := revocation.Token{}
+	token.ID, _ = c["jti"].(string)
+	token.Subject, _ = c["sub"].(string)
+
+	if iat, ok := c["iat"].(float64); ok {
+		token.IssuedAt = time.Unix(int64(iat), 0)
+	}
+
+	if exp, ok := c["exp"].(float64); ok {
+		token.ExpiresAt = time.Unix(int64(exp), 0)
+	}
+
+	return token
+}
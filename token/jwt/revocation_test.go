@@ -0,0 +1,42 @@
+package jwt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRevocationToken(t *testing.T) {
+	now := time.Unix(time.Now().Unix(), 0)
+
+	claims := newTestClaim()
+	claims.Id = "jti-1"
+	claims.IssuedAt = now.Unix()
+	claims.ExpiresAt = now.Add(time.Hour).Unix()
+
+	token, ok := RevocationToken(&claims)
+	assert.True(t, ok)
+	assert.Equal(t, "jti-1", token.ID)
+	assert.Equal(t, "12345", token.Subject)
+	assert.Equal(t, now, token.IssuedAt)
+	assert.Equal(t, now.Add(time.Hour), token.ExpiresAt)
+
+	custom := &donorClaim{DonorID: "d-1"}
+	custom.Id = "jti-2"
+	custom.Subject = "d-1"
+	token, ok = RevocationToken(custom)
+	assert.True(t, ok)
+	assert.Equal(t, "jti-2", token.ID)
+	assert.Equal(t, "d-1", token.Subject)
+	assert.True(t, token.IssuedAt.IsZero())
+
+	token, ok = RevocationToken(jwt.MapClaims{"jti": "jti-3", "sub": "u-3", "exp": float64(now.Unix())})
+	assert.True(t, ok)
+	assert.Equal(t, "jti-3", token.ID)
+	assert.Equal(t, now, token.ExpiresAt)
+
+	_, ok = RevocationToken(&noStandardClaim{})
+	assert.False(t, ok)
+}
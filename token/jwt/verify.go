@@ -0,0 +1,174 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+var (
+	// ErrAlgorithmNotAllowed is returned when none of the verifier algorithms is in the allowlist
+	ErrAlgorithmNotAllowed = errors.New("jwt: signing algorithm is not allowed")
+	// ErrTokenExpired is returned when exp claim has passed
+	ErrTokenExpired = errors.New("jwt: token is expired")
+	// ErrTokenNotValidYet is returned when nbf claim hasn't come
+	ErrTokenNotValidYet = errors.New("jwt: token is not valid yet")
+	// ErrTokenUsedBeforeIat is returned when iat claim is in the future
+	ErrTokenUsedBeforeIat = errors.New("jwt: token is used before issued")
+	// ErrInvalidIssuer is returned when iss claim is not one of allowed issuers
+	ErrInvalidIssuer = errors.New("jwt: invalid issuer")
+	// ErrInvalidAudience is returned when aud claim doesn't contain the audience
+	ErrInvalidAudience = errors.New("jwt: invalid audience")
+	// ErrInvalidToken is returned when token signature is invalid
+	ErrInvalidToken = errors.New("jwt: invalid token")
+)
+
+// Verifier provides key to verify token signature, implemented by JWT, JWTRSA and JWTKeySource
+type Verifier interface {
+	// VerificationKey returns key to verify signature of the token
+	VerificationKey(token *jwt.Token) (interface{}, error)
+	// Algorithms returns signing algorithm accepted by the verifier
+	Algorithms() []string
+}
+
+var (
+	_ Verifier = (*JWT)(nil)
+	_ Verifier = (*JWTRSA)(nil)
+	_ Verifier = (*JWTKeySource)(nil)
+)
+
+// VerifyOption is token verification option
+type VerifyOption struct {
+	// Algorithms is allowed signing algorithm. It can only narrow the verifier algorithms, default is verifier Algorithms.
+	Algorithms []string
+	// Issuers is allowed iss claim. Empty means iss is not checked.
+	Issuers []string
+	// Audience is required aud claim. Empty means aud is not checked.
+	Audience string
+	// Leeway is allowed clock skew when checking exp, nbf and iat claim
+	Leeway time.Duration
+	// Claims creates claims to decode the token into. Default is &UserClaim{}. Valid of custom claims is called
+	// after the standard claim checks, its exp, nbf and iat errors are ignored since they are checked with Leeway.
+	Claims func() jwt.Claims
+}
+
+// timeValidationErrors is exp, nbf and iat errors of jwt.ValidationError
+const timeValidationErrors = jwt.ValidationErrorExpired | jwt.ValidationErrorNotValidYet | jwt.ValidationErrorIssuedAt
+
+// standardClaims is implemented by jwt.StandardClaims, struct embedding it, and jwt.MapClaims
+type standardClaims interface {
+	VerifyAudience(cmp string, req bool) bool
+	VerifyExpiresAt(cmp int64, req bool) bool
+	VerifyIssuedAt(cmp int64, req bool) bool
+	VerifyIssuer(cmp string, req bool) bool
+	VerifyNotBefore(cmp int64, req bool) bool
+}
+
+// Verify parses the token, verifies its signature using verifier, and validates its claims
+func Verify(tokenString string, verifier Verifier, opt VerifyOption) (*jwt.Token, error) {
+	algorithms := allowedAlgorithms(verifier.Algorithms(), opt.Algorithms)
+	if len(algorithms) == 0 {
+		return nil, ErrAlgorithmNotAllowed
+	}
+
+	claims := jwt.Claims(&UserClaim{})
+	if opt.Claims != nil {
+		claims = opt.Claims()
+	}
+
+	parser := &jwt.Parser{ValidMethods: algorithms, SkipClaimsValidation: true}
+	token, err := parser.ParseWithClaims(tokenString, claims, verifier.VerificationKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	if err = validateClaims(token.Claims, opt); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// allowedAlgorithms returns verifier algorithms allowed by the allowlist
+func allowedAlgorithms(verifierAlgs, allowlist []string) []string {
+	if len(allowlist) == 0 {
+		return verifierAlgs
+	}
+
+	var algs []string
+	for _, alg := range verifierAlgs {
+		for _, allowed := range allowlist {
+			if alg == allowed {
+				algs = append(algs, alg)
+			}
+		}
+	}
+
+	return algs
+}
+
+func validateClaims(claims jwt.Claims, opt VerifyOption) error {
+	std, ok := claims.(standardClaims)
+	if !ok {
+		if len(opt.Issuers) > 0 || opt.Audience != "" {
+			return fmt.Errorf("jwt: claims %T doesn't support iss and aud validation", claims)
+		}
+
+		return claims.Valid()
+	}
+
+	now := time.Now()
+	leeway := int64(opt.Leeway / time.Second)
+
+	if !std.VerifyExpiresAt(now.Unix()-leeway, false) {
+		return ErrTokenExpired
+	}
+
+	if !std.VerifyNotBefore(now.Unix()+leeway, false) {
+		return ErrTokenNotValidYet
+	}
+
+	if !std.VerifyIssuedAt(now.Unix()+leeway, false) {
+		return ErrTokenUsedBeforeIat
+	}
+
+	if len(opt.Issuers) > 0 {
+		valid := false
+		for _, iss := range opt.Issuers {
+			if std.VerifyIssuer(iss, true) {
+				valid = true
+				break
+			}
+		}
+
+		if !valid {
+			return ErrInvalidIssuer
+		}
+	}
+
+	if opt.Audience != "" && !std.VerifyAudience(opt.Audience, true) {
+		return ErrInvalidAudience
+	}
+
+	// Valid of these only re-checks exp, nbf and iat without leeway
+	switch claims.(type) {
+	case *UserClaim, *jwt.StandardClaims, jwt.MapClaims:
+		return nil
+	}
+
+	err := claims.Valid()
+
+	// e.g. promoted Valid of embedded jwt.StandardClaims, already checked with leeway above
+	var validationErr *jwt.ValidationError
+	if errors.As(err, &validationErr) && validationErr.Errors&^timeValidationErrors == 0 {
+		return nil
+	}
+
+	return err
+}
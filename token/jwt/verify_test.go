@@ -0,0 +1,202 @@
+package jwt
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestJWTRSA(t *testing.T) (*JWTRSA, []byte) {
+	pubKey, err := ioutil.ReadFile("dummy.rsa.pub")
+	require.NoError(t, err)
+	privKey, err := ioutil.ReadFile("dummy.rsa")
+	require.NoError(t, err)
+
+	jwtrsa, err := NewJWTRSA(pubKey, privKey)
+	require.NoError(t, err)
+
+	return jwtrsa, pubKey
+}
+
+func TestVerifyAlgorithmConfusion(t *testing.T) {
+	jwtrsa, pubKey := newTestJWTRSA(t)
+
+	// HS256 token signed with the public key as hmac secret
+	token, err := NewJWT(pubKey).Create(newTestClaim())
+	require.NoError(t, err)
+
+	_, err = Verify(token, jwtrsa, VerifyOption{})
+	assert.Error(t, err)
+
+	_, err = jwtrsa.Parse(token)
+	assert.Error(t, err)
+
+	// unsigned token
+	token, err = jwt.NewWithClaims(jwt.SigningMethodNone, newTestClaim()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+
+	_, err = Verify(token, jwtrsa, VerifyOption{})
+	assert.Error(t, err)
+
+	_, err = Verify(token, NewJWT(signKey), VerifyOption{})
+	assert.Error(t, err)
+}
+
+func TestVerifyAlgorithmAllowlist(t *testing.T) {
+	signers := newTestSigners(t)
+	source, err := NewStaticKeySource(jwksOf(t, signers...))
+	require.NoError(t, err)
+
+	verifier := NewJWTKeySource(source)
+	for _, signer := range signers {
+		token, err := signer.Create(newTestClaim())
+		require.NoError(t, err)
+
+		_, err = Verify(token, verifier, VerifyOption{Algorithms: []string{AlgEdDSA}})
+		if signer.method.Alg() == AlgEdDSA {
+			assert.NoError(t, err)
+		} else {
+			assert.Error(t, err, signer.kid)
+		}
+	}
+
+	_, err = Verify("token", NewJWT(signKey), VerifyOption{Algorithms: []string{AlgRS256}})
+	assert.Equal(t, ErrAlgorithmNotAllowed, err)
+}
+
+func TestVerifyClaims(t *testing.T) {
+	jwtrsa, _ := newTestJWTRSA(t)
+
+	claims := newTestClaim()
+	claims.Issuer = "https://auth.kitabisa.com"
+	claims.Audience = "campaign-service"
+	token, err := jwtrsa.Create(claims)
+	require.NoError(t, err)
+
+	parsed, err := Verify(token, jwtrsa, VerifyOption{
+		Issuers:  []string{"https://auth.kitabisa.xyz", "https://auth.kitabisa.com"},
+		Audience: "campaign-service",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(12345), parsed.Claims.(*UserClaim).UserID)
+
+	_, err = Verify(token, jwtrsa, VerifyOption{Issuers: []string{"https://evil.com"}})
+	assert.Equal(t, ErrInvalidIssuer, err)
+
+	_, err = Verify(token, jwtrsa, VerifyOption{Audience: "payment-service"})
+	assert.Equal(t, ErrInvalidAudience, err)
+}
+
+func TestVerifyLeeway(t *testing.T) {
+	jwtt := NewJWT(signKey)
+
+	expired := newTestClaim()
+	expired.ExpiresAt = time.Now().Add(-10 * time.Second).Unix()
+	token, _ := jwtt.Create(expired)
+
+	_, err := Verify(token, jwtt, VerifyOption{})
+	assert.Equal(t, ErrTokenExpired, err)
+
+	_, err = Verify(token, jwtt, VerifyOption{Leeway: 30 * time.Second})
+	assert.NoError(t, err)
+
+	notBefore := newTestClaim()
+	notBefore.NotBefore = time.Now().Add(10 * time.Second).Unix()
+	token, _ = jwtt.Create(notBefore)
+
+	_, err = Verify(token, jwtt, VerifyOption{})
+	assert.Equal(t, ErrTokenNotValidYet, err)
+
+	_, err = Verify(token, jwtt, VerifyOption{Leeway: 30 * time.Second})
+	assert.NoError(t, err)
+}
+
+type donorClaim struct {
+	DonorID  string `json:"donor_id"`
+	Campaign string `json:"campaign"`
+	jwt.StandardClaims
+}
+
+type noStandardClaim struct {
+	DonorID string `json:"donor_id"`
+}
+
+func (c *noStandardClaim) Valid() error {
+	if c.DonorID == "" {
+		return errors.New("missing donor id")
+	}
+
+	return nil
+}
+
+// campaignClaim embeds jwt.StandardClaims and validates its own claim
+type campaignClaim struct {
+	Campaign string `json:"campaign"`
+	jwt.StandardClaims
+}
+
+func (c *campaignClaim) Valid() error {
+	if c.Campaign == "" {
+		return errors.New("missing campaign")
+	}
+
+	return c.StandardClaims.Valid()
+}
+
+func TestVerifyCustomClaimsValid(t *testing.T) {
+	jwtt := NewJWT(signKey)
+	newClaims := func() jwt.Claims { return &campaignClaim{} }
+
+	token, _ := jwtt.Create(&campaignClaim{Campaign: "bantu-budi"})
+	_, err := Verify(token, jwtt, VerifyOption{Claims: newClaims})
+	assert.NoError(t, err)
+
+	token, _ = jwtt.Create(&campaignClaim{})
+	_, err = Verify(token, jwtt, VerifyOption{Claims: newClaims})
+	assert.EqualError(t, err, "missing campaign")
+
+	// exp checked by embedded jwt.StandardClaims gets the leeway
+	expired := &campaignClaim{Campaign: "bantu-budi"}
+	expired.ExpiresAt = time.Now().Add(-5 * time.Second).Unix()
+	token, _ = jwtt.Create(expired)
+	_, err = Verify(token, jwtt, VerifyOption{Claims: newClaims, Leeway: time.Minute})
+	assert.NoError(t, err)
+
+	_, err = Verify(token, jwtt, VerifyOption{Claims: newClaims})
+	assert.Equal(t, ErrTokenExpired, err)
+}
+
+func TestVerifyCustomClaims(t *testing.T) {
+	jwtt := NewJWT(signKey)
+
+	claims := donorClaim{DonorID: "d-1", Campaign: "bantu-budi"}
+	claims.Audience = "donation-service"
+	claims.ExpiresAt = time.Now().Add(time.Minute).Unix()
+	token, _ := jwtt.Create(claims)
+
+	parsed, err := Verify(token, jwtt, VerifyOption{
+		Audience: "donation-service",
+		Claims:   func() jwt.Claims { return &donorClaim{} },
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "bantu-budi", parsed.Claims.(*donorClaim).Campaign)
+
+	parsed, err = Verify(token, jwtt, VerifyOption{Claims: func() jwt.Claims { return &noStandardClaim{} }})
+	require.NoError(t, err)
+	assert.Equal(t, "d-1", parsed.Claims.(*noStandardClaim).DonorID)
+
+	_, err = Verify(token, jwtt, VerifyOption{
+		Audience: "donation-service",
+		Claims:   func() jwt.Claims { return &noStandardClaim{} },
+	})
+	assert.Error(t, err)
+
+	parsed, err = Verify(token, jwtt, VerifyOption{Claims: func() jwt.Claims { return jwt.MapClaims{} }})
+	require.NoError(t, err)
+	assert.Equal(t, "d-1", parsed.Claims.(jwt.MapClaims)["donor_id"])
+}
@@ -0,0 +1,33 @@
+package paseto
+
+import (
+	"context"
+
+	"github.com/kitabisa/perkakas/v2/ctxkeys"
+	"github.com/o1egl/paseto"
+)
+
+// WithToken stores verified paseto token and its footer into context
+func WithToken(ctx context.Context, token paseto.JSONToken, footer string) context.Context {
+	return ctxkeys.WithPasetoToken(ctx, token, footer)
+}
+
+// TokenFrom gets verified paseto token and its footer from context. It falls back to the deprecated
+// string keys "token" and "token_footer" for context that is set by older middleware.
+func TokenFrom(ctx context.Context) (token paseto.JSONToken, footer string, ok bool) {
+	if t, ok := ctxkeys.PasetoTokenFrom(ctx); ok {
+		if token, ok := t.Token.(paseto.JSONToken); ok {
+			return token, t.Footer, true
+		}
+	}
+
+	footer, _ = ctx.Value("token_footer").(string)
+	switch t := ctx.Value("token").(type) {
+	case paseto.JSONToken:
+		return t, footer, true
+	case *paseto.JSONToken:
+		return *t, footer, true
+	}
+
+	return paseto.JSONToken{}, "", false
+}
@@ -0,0 +1,35 @@
+package paseto
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kitabisa/perkakas/v2/ctxkeys"
+	"github.com/kitabisa/perkakas/v2/token/jwt"
+	"github.com/o1egl/paseto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenFrom(t *testing.T) {
+	token := paseto.JSONToken{Subject: "cac2ee7e"}
+
+	got, footer, ok := TokenFrom(WithToken(context.Background(), token, "Kitabisa.com"))
+	assert.True(t, ok)
+	assert.Equal(t, "cac2ee7e", got.Subject)
+	assert.Equal(t, "Kitabisa.com", footer)
+
+	stored, ok := ctxkeys.PasetoTokenFrom(WithToken(context.Background(), token, "Kitabisa.com"))
+	assert.True(t, ok)
+	assert.Equal(t, token, stored.Token)
+
+	// deprecated keys
+	ctx := context.WithValue(context.Background(), "token", token)
+	ctx = context.WithValue(ctx, "token_footer", "Kitabisa.com")
+	got, footer, ok = TokenFrom(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "cac2ee7e", got.Subject)
+	assert.Equal(t, "Kitabisa.com", footer)
+
+	_, _, ok = TokenFrom(jwt.WithUserClaim(context.Background(), &jwt.UserClaim{}))
+	assert.False(t, ok)
+}
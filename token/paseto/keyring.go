@@ -0,0 +1,246 @@
+package paseto
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/o1egl/paseto"
+)
+
+// Key purpose
+const (
+	PurposeLocal  = "local"
+	PurposePublic = "public"
+)
+
+var (
+	// ErrKeyNotFound is returned when key with the kid is not in keyring
+	ErrKeyNotFound = errors.New("paseto: key not found")
+	// ErrMissingKeyID is returned when token footer has no kid
+	ErrMissingKeyID = errors.New("paseto: token footer has no kid")
+	// ErrNoCurrentKey is returned when creating token using keyring without current key
+	ErrNoCurrentKey = errors.New("paseto: keyring has no current key")
+	// ErrPurposeMismatch is returned when token purpose differs from its key purpose
+	ErrPurposeMismatch = errors.New("paseto: token purpose doesn't match key purpose")
+)
+
+// Key is v4 key identified by kid. Public key without private key can only verify token.
+type Key struct {
+	ID         string
+	Purpose    string
+	Symmetric  []byte
+	PrivateKey ed25519.PrivateKey
+	PublicKey  ed25519.PublicKey
+}
+
+// NewLocalKey creates v4.local key from 32 bytes symmetric key
+func NewLocalKey(kid string, key []byte) (*Key, error) {
+	if len(key) != 32 {
+		return nil, ErrInvalidKey
+	}
+
+	return &Key{ID: kid, Purpose: PurposeLocal, Symmetric: key}, nil
+}
+
+// NewPublicKeyFromSeed creates v4.public signing key from 32 bytes Ed25519 seed
+func NewPublicKeyFromSeed(kid string, seed []byte) (*Key, error) {
+	p, err := NewV4PublicFromSeed(seed)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Key{ID: kid, Purpose: PurposePublic, PrivateKey: p.PrivateKey, PublicKey: p.PublicKey}, nil
+}
+
+// NewPublicKey creates v4.public verification key
+func NewPublicKey(kid string, publicKey ed25519.PublicKey) (*Key, error) {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return nil, ErrInvalidKey
+	}
+
+	return &Key{ID: kid, Purpose: PurposePublic, PublicKey: publicKey}, nil
+}
+
+// KeyringOption is keyring option
+type KeyringOption struct {
+	// Implicit is default implicit assertion, authenticated but not stored in the token, e.g. tenant id
+	Implicit []byte
+}
+
+// Keyring creates and verifies v4 token using key picked by kid in the footer, so keys can be rotated.
+// Footer is json object, e.g. {"kid":"2021-09"}.
+type Keyring struct {
+	mu       sync.RWMutex
+	keys     map[string]*Key
+	current  string
+	implicit []byte
+}
+
+// NewKeyring creates empty keyring
+func NewKeyring(option KeyringOption) *Keyring {
+	return &Keyring{
+		keys:     make(map[string]*Key),
+		implicit: option.Implicit,
+	}
+}
+
+// Add adds key to verify token, e.g. previous key during rotation or public key of other service
+func (k *Keyring) Add(key *Key) error {
+	if key.ID == "" {
+		return ErrMissingKeyID
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.keys[key.ID] = key
+	return nil
+}
+
+// Rotate adds key and uses it to create new token. Previous keys still verify token until removed.
+func (k *Keyring) Rotate(key *Key) error {
+	if key.Purpose == PurposePublic && len(key.PrivateKey) != ed25519.PrivateKeySize {
+		return ErrInvalidKey
+	}
+
+	if err := k.Add(key); err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.current = key.ID
+	return nil
+}
+
+// Remove removes key, token with the kid is no longer valid
+func (k *Keyring) Remove(kid string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	delete(k.keys, kid)
+	if k.current == kid {
+		k.current = ""
+	}
+}
+
+func (k *Keyring) key(kid string) (*Key, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, kid)
+	}
+
+	return key, nil
+}
+
+// Encrypt creates v4 token using current key with the default implicit assertion. Footer must be empty or json object,
+// the kid is added to it.
+func (k *Keyring) Encrypt(token paseto.JSONToken, footer string) (string, error) {
+	return k.EncryptWithImplicit(token, footer, k.implicit)
+}
+
+// EncryptWithImplicit creates v4 token using current key with the implicit assertion
+func (k *Keyring) EncryptWithImplicit(token paseto.JSONToken, footer string, implicit []byte) (string, error) {
+	k.mu.RLock()
+	key, ok := k.keys[k.current]
+	k.mu.RUnlock()
+
+	if !ok {
+		return "", ErrNoCurrentKey
+	}
+
+	f, err := footerWithKeyID(footer, key.ID)
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+
+	if key.Purpose == PurposeLocal {
+		return v4Encrypt(key.Symmetric, payload, f, implicit)
+	}
+
+	return v4Sign(key.PrivateKey, payload, f, implicit), nil
+}
+
+// Decrypt decrypts v4.local or verifies v4.public token using key of the kid in the footer, with the default
+// implicit assertion. Footer is returned as is.
+func (k *Keyring) Decrypt(encToken string) (paseto.JSONToken, string, error) {
+	return k.DecryptWithImplicit(encToken, k.implicit)
+}
+
+// DecryptWithImplicit decrypts or verifies v4 token with the implicit assertion
+func (k *Keyring) DecryptWithImplicit(encToken string, implicit []byte) (paseto.JSONToken, string, error) {
+	kid, err := KeyID(encToken)
+	if err != nil {
+		return paseto.JSONToken{}, "", err
+	}
+
+	key, err := k.key(kid)
+	if err != nil {
+		return paseto.JSONToken{}, "", err
+	}
+
+	var payload, footer []byte
+	switch {
+	case strings.HasPrefix(encToken, v4LocalHeader) && key.Purpose == PurposeLocal:
+		payload, footer, err = v4Decrypt(key.Symmetric, encToken, implicit)
+	case strings.HasPrefix(encToken, v4PublicHeader) && key.Purpose == PurposePublic:
+		payload, footer, err = v4Verify(key.PublicKey, encToken, implicit)
+	default:
+		return paseto.JSONToken{}, "", ErrPurposeMismatch
+	}
+
+	if err != nil {
+		return paseto.JSONToken{}, "", err
+	}
+
+	return unmarshalToken(payload, footer)
+}
+
+// KeyID gets kid from footer of the token without verifying it
+func KeyID(encToken string) (string, error) {
+	parts := strings.Split(encToken, ".")
+	if len(parts) != 4 {
+		return "", ErrMissingKeyID
+	}
+
+	footer, err := b64.DecodeString(parts[3])
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+
+	var f struct {
+		KeyID string `json:"kid"`
+	}
+
+	if err = json.Unmarshal(footer, &f); err != nil || f.KeyID == "" {
+		return "", ErrMissingKeyID
+	}
+
+	return f.KeyID, nil
+}
+
+// footerWithKeyID adds kid to json object footer
+func footerWithKeyID(footer, kid string) ([]byte, error) {
+	f := map[string]interface{}{}
+	if footer != "" {
+		if err := json.Unmarshal([]byte(footer), &f); err != nil {
+			return nil, fmt.Errorf("paseto: footer must be json object: %w", err)
+		}
+	}
+
+	f["kid"] = kid
+	return json.Marshal(f)
+}
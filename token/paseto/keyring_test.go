@@ -0,0 +1,114 @@
+package paseto
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSeed(t *testing.T) []byte {
+	seed := make([]byte, ed25519.SeedSize)
+	_, err := rand.Read(seed)
+	require.NoError(t, err)
+
+	return seed
+}
+
+func TestKeyringRotation(t *testing.T) {
+	keyring := NewKeyring(KeyringOption{})
+
+	_, err := keyring.Encrypt(newTestToken(), "")
+	assert.Equal(t, ErrNoCurrentKey, err)
+
+	oldKey, err := NewPublicKeyFromSeed("2021-08", newTestSeed(t))
+	require.NoError(t, err)
+	require.NoError(t, keyring.Rotate(oldKey))
+
+	oldToken, err := keyring.Encrypt(newTestToken(), `{"app":"kitabisa"}`)
+	require.NoError(t, err)
+
+	kid, err := KeyID(oldToken)
+	require.NoError(t, err)
+	assert.Equal(t, "2021-08", kid)
+
+	newKey, err := NewPublicKeyFromSeed("2021-09", newTestSeed(t))
+	require.NoError(t, err)
+	require.NoError(t, keyring.Rotate(newKey))
+
+	newToken, err := keyring.Encrypt(newTestToken(), "")
+	require.NoError(t, err)
+	kid, _ = KeyID(newToken)
+	assert.Equal(t, "2021-09", kid)
+
+	// old token is still valid until the old key is removed
+	_, footer, err := keyring.Decrypt(oldToken)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"app":"kitabisa","kid":"2021-08"}`, footer)
+
+	keyring.Remove("2021-08")
+	_, _, err = keyring.Decrypt(oldToken)
+	assert.True(t, errors.Is(err, ErrKeyNotFound))
+
+	_, _, err = keyring.Decrypt(newToken)
+	assert.NoError(t, err)
+}
+
+func TestKeyringVerifyOnly(t *testing.T) {
+	signer, err := NewPublicKeyFromSeed("2021-09", newTestSeed(t))
+	require.NoError(t, err)
+
+	issuer := NewKeyring(KeyringOption{Implicit: []byte("kitabisa")})
+	require.NoError(t, issuer.Rotate(signer))
+
+	encToken, err := issuer.Encrypt(newTestToken(), "")
+	require.NoError(t, err)
+
+	publicKey, err := NewPublicKey("2021-09", signer.PublicKey)
+	require.NoError(t, err)
+
+	verifier := NewKeyring(KeyringOption{Implicit: []byte("kitabisa")})
+	require.NoError(t, verifier.Add(publicKey))
+	assert.Equal(t, ErrInvalidKey, verifier.Rotate(publicKey))
+
+	token, _, err := verifier.Decrypt(encToken)
+	require.NoError(t, err)
+	assert.Equal(t, "budi@kitabisa.com", token.Get("email"))
+
+	_, _, err = verifier.DecryptWithImplicit(encToken, []byte("other"))
+	assert.Equal(t, ErrInvalidToken, err)
+}
+
+func TestKeyringPurposeMismatch(t *testing.T) {
+	localKey, err := NewLocalKey("k1", make([]byte, 32))
+	require.NoError(t, err)
+
+	local := NewKeyring(KeyringOption{})
+	require.NoError(t, local.Rotate(localKey))
+
+	encToken, err := local.Encrypt(newTestToken(), "")
+	require.NoError(t, err)
+
+	_, _, err = local.Decrypt(encToken)
+	require.NoError(t, err)
+
+	// public key with the same kid can't verify local token
+	publicKey, _ := NewPublicKeyFromSeed("k1", newTestSeed(t))
+	public := NewKeyring(KeyringOption{})
+	require.NoError(t, public.Add(publicKey))
+
+	_, _, err = public.Decrypt(encToken)
+	assert.Equal(t, ErrPurposeMismatch, err)
+
+	// footer without kid
+	v4, _ := NewV4Local(make([]byte, 32))
+	encToken, _ = v4.Encrypt(newTestToken(), "Kitabisa.com")
+	_, _, err = local.Decrypt(encToken)
+	assert.Equal(t, ErrMissingKeyID, err)
+
+	_, err = local.Encrypt(newTestToken(), "Kitabisa.com")
+	assert.Error(t, err)
+}
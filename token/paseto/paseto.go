@@ -1,4 +1,4 @@
-// Package to generate and validate paseto token. Only support paseto token v2
+// Package to generate and validate paseto token. Support paseto token v2 and v4
 
 package paseto
 
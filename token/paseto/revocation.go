@@ -0,0 +1,16 @@
+package paseto
+
+import (
+	"github.com/kitabisa/perkakas/v2/token/revocation"
+	"github.com/o1egl/paseto"
+)
+
+// RevocationToken gets jti, subject, iat and exp of the token to check against revocation list
+func RevocationToken(token paseto.JSONToken) revocation.Token {
+	return revocation.Token{
+		ID:        token.Jti,
+		Subject:   token.Subject,
+		IssuedAt:  token.IssuedAt,
+		ExpiresAt: token.Expiration,
+	}
+}
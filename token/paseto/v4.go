@@ -0,0 +1,330 @@
+package paseto
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/o1egl/paseto"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/chacha20"
+)
+
+const (
+	v4LocalHeader  = "v4.local."
+	v4PublicHeader = "v4.public."
+	v4NonceSize    = 32
+	v4MacSize      = 32
+)
+
+var (
+	// ErrInvalidToken is returned when token is malformed, or its signature or authentication tag is invalid
+	ErrInvalidToken = errors.New("paseto: invalid token")
+	// ErrInvalidKey is returned when key size is invalid
+	ErrInvalidKey = errors.New("paseto: invalid key")
+)
+
+var b64 = base64.RawURLEncoding
+
+// PasetoV4Local is v4.local token, encrypted with 32 bytes symmetric key
+type PasetoV4Local struct {
+	Key []byte
+	// Implicit is implicit assertion, authenticated but not stored in the token, e.g. tenant id
+	Implicit []byte
+}
+
+// NewV4Local creates v4.local token from 32 bytes symmetric key
+func NewV4Local(key []byte) (*PasetoV4Local, error) {
+	if len(key) != 32 {
+		return nil, ErrInvalidKey
+	}
+
+	return &PasetoV4Local{
+		Key: key,
+	}, nil
+}
+
+func (p PasetoV4Local) Encrypt(token paseto.JSONToken, footer string) (string, error) {
+	payload, err := json.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+
+	return v4Encrypt(p.Key, payload, []byte(footer), p.Implicit)
+}
+
+func (p PasetoV4Local) Decrypt(encToken string) (paseto.JSONToken, string, error) {
+	payload, footer, err := v4Decrypt(p.Key, encToken, p.Implicit)
+	if err != nil {
+		return paseto.JSONToken{}, "", err
+	}
+
+	return unmarshalToken(payload, footer)
+}
+
+// PasetoV4Public is v4.public token, signed with Ed25519 key
+type PasetoV4Public struct {
+	PrivateKey ed25519.PrivateKey
+	PublicKey  ed25519.PublicKey
+	// Implicit is implicit assertion, authenticated but not stored in the token, e.g. tenant id
+	Implicit []byte
+}
+
+// NewV4PublicFromSeed creates v4.public token from 32 bytes Ed25519 seed, so only the seed needs to be stored
+func NewV4PublicFromSeed(seed []byte) (*PasetoV4Public, error) {
+	if len(seed) != ed25519.SeedSize {
+		return nil, ErrInvalidKey
+	}
+
+	privateKey := ed25519.NewKeyFromSeed(seed)
+	return &PasetoV4Public{
+		PrivateKey: privateKey,
+		PublicKey:  privateKey.Public().(ed25519.PublicKey),
+	}, nil
+}
+
+// NewV4Public creates v4.public token which can only verify token
+func NewV4Public(publicKey ed25519.PublicKey) (*PasetoV4Public, error) {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return nil, ErrInvalidKey
+	}
+
+	return &PasetoV4Public{
+		PublicKey: publicKey,
+	}, nil
+}
+
+func (p PasetoV4Public) Encrypt(token paseto.JSONToken, footer string) (string, error) {
+	if len(p.PrivateKey) != ed25519.PrivateKeySize {
+		return "", ErrInvalidKey
+	}
+
+	payload, err := json.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+
+	return v4Sign(p.PrivateKey, payload, []byte(footer), p.Implicit), nil
+}
+
+func (p PasetoV4Public) Decrypt(encToken string) (paseto.JSONToken, string, error) {
+	payload, footer, err := v4Verify(p.PublicKey, encToken, p.Implicit)
+	if err != nil {
+		return paseto.JSONToken{}, "", err
+	}
+
+	return unmarshalToken(payload, footer)
+}
+
+func unmarshalToken(payload, footer []byte) (paseto.JSONToken, string, error) {
+	var token paseto.JSONToken
+	if err := json.Unmarshal(payload, &token); err != nil {
+		return paseto.JSONToken{}, "", fmt.Errorf("paseto: invalid token payload: %w", err)
+	}
+
+	return token, string(footer), nil
+}
+
+// pae is pre-authentication encoding of the pieces
+func pae(pieces ...[]byte) []byte {
+	var buf bytes.Buffer
+	le64 := make([]byte, 8)
+
+	binary.LittleEndian.PutUint64(le64, uint64(len(pieces)))
+	buf.Write(le64)
+
+	for _, p := range pieces {
+		binary.LittleEndian.PutUint64(le64, uint64(len(p)))
+		buf.Write(le64)
+		buf.Write(p)
+	}
+
+	return buf.Bytes()
+}
+
+// splitToken splits token with the header into decoded body and footer
+func splitToken(token, header string) (body, footer []byte, err error) {
+	if !strings.HasPrefix(token, header) {
+		return nil, nil, ErrInvalidToken
+	}
+
+	parts := strings.Split(token[len(header):], ".")
+	if len(parts) > 2 {
+		return nil, nil, ErrInvalidToken
+	}
+
+	body, err = b64.DecodeString(parts[0])
+	if err != nil {
+		return nil, nil, ErrInvalidToken
+	}
+
+	if len(parts) == 2 {
+		footer, err = b64.DecodeString(parts[1])
+		if err != nil {
+			return nil, nil, ErrInvalidToken
+		}
+	}
+
+	return body, footer, nil
+}
+
+func joinToken(header string, body, footer []byte) string {
+	token := header + b64.EncodeToString(body)
+	if len(footer) > 0 {
+		token += "." + b64.EncodeToString(footer)
+	}
+
+	return token
+}
+
+func v4Encrypt(key, payload, footer, implicit []byte) (string, error) {
+	nonce := make([]byte, v4NonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	return v4EncryptWithNonce(key, nonce, payload, footer, implicit)
+}
+
+func v4EncryptWithNonce(key, nonce, payload, footer, implicit []byte) (string, error) {
+	encKey, counterNonce, authKey, err := v4SplitKey(key, nonce)
+	if err != nil {
+		return "", err
+	}
+
+	cipher, err := chacha20.NewUnauthenticatedCipher(encKey, counterNonce)
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext := make([]byte, len(payload))
+	cipher.XORKeyStream(ciphertext, payload)
+
+	mac, err := v4Mac(authKey, []byte(v4LocalHeader), nonce, ciphertext, footer, implicit)
+	if err != nil {
+		return "", err
+	}
+
+	body := make([]byte, 0, len(nonce)+len(ciphertext)+len(mac))
+	body = append(body, nonce...)
+	body = append(body, ciphertext...)
+	body = append(body, mac...)
+
+	return joinToken(v4LocalHeader, body, footer), nil
+}
+
+func v4Decrypt(key []byte, token string, implicit []byte) (payload, footer []byte, err error) {
+	body, footer, err := splitToken(token, v4LocalHeader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(body) < v4NonceSize+v4MacSize {
+		return nil, nil, ErrInvalidToken
+	}
+
+	nonce := body[:v4NonceSize]
+	ciphertext := body[v4NonceSize : len(body)-v4MacSize]
+	mac := body[len(body)-v4MacSize:]
+
+	encKey, counterNonce, authKey, err := v4SplitKey(key, nonce)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	expected, err := v4Mac(authKey, []byte(v4LocalHeader), nonce, ciphertext, footer, implicit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !hmac.Equal(mac, expected) {
+		return nil, nil, ErrInvalidToken
+	}
+
+	cipher, err := chacha20.NewUnauthenticatedCipher(encKey, counterNonce)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	payload = make([]byte, len(ciphertext))
+	cipher.XORKeyStream(payload, ciphertext)
+
+	return payload, footer, nil
+}
+
+// v4SplitKey derives encryption key, XChaCha20 nonce and authentication key from the key and random nonce
+func v4SplitKey(key, nonce []byte) (encKey, counterNonce, authKey []byte, err error) {
+	if len(key) != 32 {
+		return nil, nil, nil, ErrInvalidKey
+	}
+
+	h, err := blake2b.New(56, key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	h.Write([]byte("paseto-encryption-key"))
+	h.Write(nonce)
+	tmp := h.Sum(nil)
+
+	h, err = blake2b.New(32, key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	h.Write([]byte("paseto-auth-key-for-aead"))
+	h.Write(nonce)
+
+	return tmp[:32], tmp[32:], h.Sum(nil), nil
+}
+
+func v4Mac(authKey []byte, pieces ...[]byte) ([]byte, error) {
+	h, err := blake2b.New(v4MacSize, authKey)
+	if err != nil {
+		return nil, err
+	}
+
+	h.Write(pae(pieces...))
+	return h.Sum(nil), nil
+}
+
+func v4Sign(privateKey ed25519.PrivateKey, payload, footer, implicit []byte) string {
+	signature := ed25519.Sign(privateKey, pae([]byte(v4PublicHeader), payload, footer, implicit))
+
+	body := make([]byte, 0, len(payload)+len(signature))
+	body = append(body, payload...)
+	body = append(body, signature...)
+
+	return joinToken(v4PublicHeader, body, footer)
+}
+
+func v4Verify(publicKey ed25519.PublicKey, token string, implicit []byte) (payload, footer []byte, err error) {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return nil, nil, ErrInvalidKey
+	}
+
+	body, footer, err := splitToken(token, v4PublicHeader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(body) < ed25519.SignatureSize {
+		return nil, nil, ErrInvalidToken
+	}
+
+	payload = body[:len(body)-ed25519.SignatureSize]
+	signature := body[len(body)-ed25519.SignatureSize:]
+
+	if !ed25519.Verify(publicKey, pae([]byte(v4PublicHeader), payload, footer, implicit), signature) {
+		return nil, nil, ErrInvalidToken
+	}
+
+	return payload, footer, nil
+}
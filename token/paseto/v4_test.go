@@ -0,0 +1,232 @@
+package paseto
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/o1egl/paseto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestToken() paseto.JSONToken {
+	now := time.Now()
+	token := paseto.JSONToken{
+		Issuer:     "Kulonuwun",
+		Jti:        "706cbfce-c031-4a44-815e-030f963f7d4e",
+		Subject:    "cac2ee7e-70d0-4220-badd-7b5695f53ad8",
+		Expiration: now.Add(24 * time.Hour),
+		IssuedAt:   now,
+		NotBefore:  now,
+	}
+	token.Set("email", "budi@kitabisa.com")
+
+	return token
+}
+
+// test vector 4-S-1 of paseto specification
+func TestV4PublicVector(t *testing.T) {
+	seed, _ := hex.DecodeString("b4cbfb43df4ce210727d953e4a713307fa19bb7d9f85041438d9e11b942a3774")
+	p, err := NewV4PublicFromSeed(seed)
+	require.NoError(t, err)
+	assert.Equal(t, "1eb9dbbbbc047c03fd70604e0071f0987e16b28b757225c11f00415d0e20b1a2", hex.EncodeToString(p.PublicKey))
+
+	payload := []byte(`{"data":"this is a signed message","exp":"2022-01-01T00:00:00+00:00"}`)
+	expected := "v4.public.eyJkYXRhIjoidGhpcyBpcyBhIHNpZ25lZCBtZXNzYWdlIiwiZXhwIjoiMjAyMi0wMS0wMVQwMDowMDowMCswMDowMCJ9bg_XBBzds8lTZShVlwwKSgeKpLT3yukTw6JUz3W4h_ExsQV-P0V54zemZDcAxFaSeef1QlXEFtkqxT1ciiQEDA"
+	assert.Equal(t, expected, v4Sign(p.PrivateKey, payload, nil, nil))
+
+	verified, footer, err := v4Verify(p.PublicKey, expected, nil)
+	require.NoError(t, err)
+	assert.Equal(t, payload, verified)
+	assert.Empty(t, footer)
+}
+
+// test vectors 4-E-1 to 4-E-9 of paseto specification
+func TestV4LocalVectors(t *testing.T) {
+	key, _ := hex.DecodeString("707172737475767778797a7b7c7d7e7f808182838485868788898a8b8c8d8e8f")
+	zeroNonce := make([]byte, v4NonceSize)
+	nonce, _ := hex.DecodeString("df654812bac492663825520ba2f6e67cf5ca5bdc13d4e7507a98cc4c2fcc3ad8")
+
+	const (
+		secret = `{"data":"this is a secret message","exp":"2022-01-01T00:00:00+00:00"}`
+		hidden = `{"data":"this is a hidden message","exp":"2022-01-01T00:00:00+00:00"}`
+		kid    = `{"kid":"zVhMiPBP9fRf2snEcT7gFTioeA9COcNy9DfgL1W60haN"}`
+	)
+
+	tests := []struct {
+		name     string
+		nonce    []byte
+		payload  string
+		footer   string
+		implicit string
+		token    string
+	}{
+		{
+			name:    "4-E-1",
+			nonce:   zeroNonce,
+			payload: secret,
+			token:   "v4.local.AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAQAr68PS4AXe7If_ZgesdkUMvSwscFlAl1pk5HC0e8kApeaqMfGo_7OpBnwJOAbY9V7WU6abu74MmcUE8YWAiaArVI8XJ5hOb_4v9RmDkneN0S92dx0OW4pgy7omxgf3S8c3LlQg",
+		},
+		{
+			name:    "4-E-2",
+			nonce:   zeroNonce,
+			payload: hidden,
+			token:   "v4.local.AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAQAr68PS4AXe7If_ZgesdkUMvS2csCgglvpk5HC0e8kApeaqMfGo_7OpBnwJOAbY9V7WU6abu74MmcUE8YWAiaArVI8XIemu9chy3WVKvRBfg6t8wwYHK0ArLxxfZP73W_vfwt5A",
+		},
+		{
+			name:    "4-E-3",
+			nonce:   nonce,
+			payload: secret,
+			token:   "v4.local.32VIErrEkmY4JVILovbmfPXKW9wT1OdQepjMTC_MOtjA4kiqw7_tcaOM5GNEcnTxl60WkwMsYXw6FSNb_UdJPXjpzm0KW9ojM5f4O2mRvE2IcweP-PRdoHjd5-RHCiExR1IK6t6-tyebyWG6Ov7kKvBdkrrAJ837lKP3iDag2hzUPHuMKA",
+		},
+		{
+			name:    "4-E-4",
+			nonce:   nonce,
+			payload: hidden,
+			token:   "v4.local.32VIErrEkmY4JVILovbmfPXKW9wT1OdQepjMTC_MOtjA4kiqw7_tcaOM5GNEcnTxl60WiA8rd3wgFSNb_UdJPXjpzm0KW9ojM5f4O2mRvE2IcweP-PRdoHjd5-RHCiExR1IK6t4gt6TiLm55vIH8c_lGxxZpE3AWlH4WTR0v45nsWoU3gQ",
+		},
+		{
+			name:    "4-E-5",
+			nonce:   nonce,
+			payload: secret,
+			footer:  kid,
+			token:   "v4.local.32VIErrEkmY4JVILovbmfPXKW9wT1OdQepjMTC_MOtjA4kiqw7_tcaOM5GNEcnTxl60WkwMsYXw6FSNb_UdJPXjpzm0KW9ojM5f4O2mRvE2IcweP-PRdoHjd5-RHCiExR1IK6t4x-RMNXtQNbz7FvFZ_G-lFpk5RG3EOrwDL6CgDqcerSQ.eyJraWQiOiJ6VmhNaVBCUDlmUmYyc25FY1Q3Z0ZUaW9lQTlDT2NOeTlEZmdMMVc2MGhhTiJ9",
+		},
+		{
+			name:    "4-E-6",
+			nonce:   nonce,
+			payload: hidden,
+			footer:  kid,
+			token:   "v4.local.32VIErrEkmY4JVILovbmfPXKW9wT1OdQepjMTC_MOtjA4kiqw7_tcaOM5GNEcnTxl60WiA8rd3wgFSNb_UdJPXjpzm0KW9ojM5f4O2mRvE2IcweP-PRdoHjd5-RHCiExR1IK6t6pWSA5HX2wjb3P-xLQg5K5feUCX4P2fpVK3ZLWFbMSxQ.eyJraWQiOiJ6VmhNaVBCUDlmUmYyc25FY1Q3Z0ZUaW9lQTlDT2NOeTlEZmdMMVc2MGhhTiJ9",
+		},
+		{
+			name:     "4-E-7",
+			nonce:    nonce,
+			payload:  secret,
+			footer:   kid,
+			implicit: `{"test-vector":"4-E-7"}`,
+			token:    "v4.local.32VIErrEkmY4JVILovbmfPXKW9wT1OdQepjMTC_MOtjA4kiqw7_tcaOM5GNEcnTxl60WkwMsYXw6FSNb_UdJPXjpzm0KW9ojM5f4O2mRvE2IcweP-PRdoHjd5-RHCiExR1IK6t40KCCWLA7GYL9KFHzKlwY9_RnIfRrMQpueydLEAZGGcA.eyJraWQiOiJ6VmhNaVBCUDlmUmYyc25FY1Q3Z0ZUaW9lQTlDT2NOeTlEZmdMMVc2MGhhTiJ9",
+		},
+		{
+			name:     "4-E-8",
+			nonce:    nonce,
+			payload:  hidden,
+			footer:   kid,
+			implicit: `{"test-vector":"4-E-8"}`,
+			token:    "v4.local.32VIErrEkmY4JVILovbmfPXKW9wT1OdQepjMTC_MOtjA4kiqw7_tcaOM5GNEcnTxl60WiA8rd3wgFSNb_UdJPXjpzm0KW9ojM5f4O2mRvE2IcweP-PRdoHjd5-RHCiExR1IK6t5uvqQbMGlLLNYBc7A6_x7oqnpUK5WLvj24eE4DVPDZjw.eyJraWQiOiJ6VmhNaVBCUDlmUmYyc25FY1Q3Z0ZUaW9lQTlDT2NOeTlEZmdMMVc2MGhhTiJ9",
+		},
+		{
+			name:     "4-E-9",
+			nonce:    nonce,
+			payload:  hidden,
+			footer:   "arbitrary-string-that-isn't-json",
+			implicit: `{"test-vector":"4-E-9"}`,
+			token:    "v4.local.32VIErrEkmY4JVILovbmfPXKW9wT1OdQepjMTC_MOtjA4kiqw7_tcaOM5GNEcnTxl60WiA8rd3wgFSNb_UdJPXjpzm0KW9ojM5f4O2mRvE2IcweP-PRdoHjd5-RHCiExR1IK6t6tybdlmnMwcDMw0YxA_gFSE_IUWl78aMtOepFYSWYfQA.YXJiaXRyYXJ5LXN0cmluZy10aGF0LWlzbid0LWpzb24",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := v4EncryptWithNonce(key, tt.nonce, []byte(tt.payload), []byte(tt.footer), []byte(tt.implicit))
+			require.NoError(t, err)
+			assert.Equal(t, tt.token, token)
+
+			payload, footer, err := v4Decrypt(key, tt.token, []byte(tt.implicit))
+			require.NoError(t, err)
+			assert.Equal(t, tt.payload, string(payload))
+			assert.Equal(t, tt.footer, string(footer))
+
+			// implicit assertion is authenticated
+			_, _, err = v4Decrypt(key, tt.token, []byte(`{"test-vector":"other"}`))
+			assert.Equal(t, ErrInvalidToken, err)
+		})
+	}
+}
+
+func TestV4Local(t *testing.T) {
+	key, _ := hex.DecodeString("707172737475767778797a7b7c7d7e7f808182838485868788898a8b8c8d8e8f")
+	p, err := NewV4Local(key)
+	require.NoError(t, err)
+
+	encToken, err := p.Encrypt(newTestToken(), "Kitabisa.com")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encToken, "v4.local."))
+	assert.NotContains(t, encToken, b64.EncodeToString([]byte("budi")))
+
+	token, footer, err := p.Decrypt(encToken)
+	require.NoError(t, err)
+	assert.Equal(t, "Kitabisa.com", footer)
+	assert.Equal(t, "budi@kitabisa.com", token.Get("email"))
+	assert.NoError(t, token.Validate())
+
+	// other key
+	other, _ := NewV4Local(make([]byte, 32))
+	_, _, err = other.Decrypt(encToken)
+	assert.Equal(t, ErrInvalidToken, err)
+
+	// tampered footer
+	tampered := encToken[:strings.LastIndex(encToken, ".")+1] + b64.EncodeToString([]byte("Evil.com"))
+	_, _, err = p.Decrypt(tampered)
+	assert.Equal(t, ErrInvalidToken, err)
+
+	_, err = NewV4Local([]byte("short"))
+	assert.Equal(t, ErrInvalidKey, err)
+}
+
+func TestV4Public(t *testing.T) {
+	seed := make([]byte, ed25519.SeedSize)
+	signer, err := NewV4PublicFromSeed(seed)
+	require.NoError(t, err)
+
+	encToken, err := signer.Encrypt(newTestToken(), "")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encToken, "v4.public."))
+
+	verifier, err := NewV4Public(signer.PublicKey)
+	require.NoError(t, err)
+
+	token, footer, err := verifier.Decrypt(encToken)
+	require.NoError(t, err)
+	assert.Empty(t, footer)
+	assert.Equal(t, "cac2ee7e-70d0-4220-badd-7b5695f53ad8", token.Subject)
+
+	// verification only key can't sign
+	_, err = verifier.Encrypt(newTestToken(), "")
+	assert.Equal(t, ErrInvalidKey, err)
+
+	// v4.local token is not accepted
+	_, _, err = verifier.Decrypt(strings.Replace(encToken, "v4.public.", "v4.local.", 1))
+	assert.Equal(t, ErrInvalidToken, err)
+}
+
+func TestV4ImplicitAssertion(t *testing.T) {
+	local, _ := NewV4Local(make([]byte, 32))
+	local.Implicit = []byte("tenant-1")
+
+	public, _ := NewV4PublicFromSeed(make([]byte, ed25519.SeedSize))
+	public.Implicit = []byte("tenant-1")
+
+	for _, p := range []interface {
+		Encrypt(paseto.JSONToken, string) (string, error)
+		Decrypt(string) (paseto.JSONToken, string, error)
+	}{local, public} {
+		encToken, err := p.Encrypt(newTestToken(), "")
+		require.NoError(t, err)
+
+		_, _, err = p.Decrypt(encToken)
+		assert.NoError(t, err)
+	}
+
+	encToken, _ := local.Encrypt(newTestToken(), "")
+	local.Implicit = []byte("tenant-2")
+	_, _, err := local.Decrypt(encToken)
+	assert.Equal(t, ErrInvalidToken, err)
+
+	encToken, _ = public.Encrypt(newTestToken(), "")
+	public.Implicit = nil
+	_, _, err = public.Decrypt(encToken)
+	assert.Equal(t, ErrInvalidToken, err)
+}
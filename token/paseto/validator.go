@@ -0,0 +1,151 @@
+package paseto
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kitabisa/perkakas/v2/token/jwt"
+	"github.com/o1egl/paseto"
+)
+
+// ValidationOption is paseto token claim validation option. Zero value only checks iat, nbf and exp.
+type ValidationOption struct {
+	// Issuers is allowed iss claim. Empty means iss is not checked.
+	Issuers []string
+	// Audience is required aud claim. Empty means aud is not checked.
+	Audience string
+	// Subject is required sub claim. Empty means sub is not checked.
+	Subject string
+	// Leeway is allowed clock skew when checking iat, nbf and exp claim
+	Leeway time.Duration
+	// RequiredClaims is custom claims which must be present, e.g. "user_id"
+	RequiredClaims []string
+	// Validators is additional validators
+	Validators []paseto.Validator
+}
+
+// validators returns validators of the option
+func (o ValidationOption) validators() []paseto.Validator {
+	validators := []paseto.Validator{ValidAtWithLeeway(time.Now(), o.Leeway)}
+
+	if len(o.Issuers) > 0 {
+		validators = append(validators, IssuedByAny(o.Issuers...))
+	}
+
+	if o.Audience != "" {
+		validators = append(validators, paseto.ForAudience(o.Audience))
+	}
+
+	if o.Subject != "" {
+		validators = append(validators, paseto.Subject(o.Subject))
+	}
+
+	if len(o.RequiredClaims) > 0 {
+		validators = append(validators, RequireClaims(o.RequiredClaims...))
+	}
+
+	return append(validators, o.Validators...)
+}
+
+// Validate validates claims of the token
+func Validate(token paseto.JSONToken, option ValidationOption) error {
+	return token.Validate(option.validators()...)
+}
+
+// ValidAtWithLeeway validates iat, nbf and exp claim at the time, allowing clock skew of the leeway
+func ValidAtWithLeeway(t time.Time, leeway time.Duration) paseto.Validator {
+	return func(token *paseto.JSONToken) error {
+		if !token.IssuedAt.IsZero() && t.Add(leeway).Before(token.IssuedAt) {
+			return fmt.Errorf("%w: token was issued in the future", paseto.ErrTokenValidationError)
+		}
+
+		if !token.NotBefore.IsZero() && t.Add(leeway).Before(token.NotBefore) {
+			return fmt.Errorf("%w: token cannot be used yet", paseto.ErrTokenValidationError)
+		}
+
+		if !token.Expiration.IsZero() && t.Add(-leeway).After(token.Expiration) {
+			return fmt.Errorf("%w: token has expired", paseto.ErrTokenValidationError)
+		}
+
+		return nil
+	}
+}
+
+// IssuedByAny validates iss claim is one of the issuers
+func IssuedByAny(issuers ...string) paseto.Validator {
+	return func(token *paseto.JSONToken) error {
+		for _, iss := range issuers {
+			if token.Issuer == iss {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("%w: token was not issued by %v", paseto.ErrTokenValidationError, issuers)
+	}
+}
+
+// RequireClaims validates the custom claims are present
+func RequireClaims(keys ...string) paseto.Validator {
+	return func(token *paseto.JSONToken) error {
+		for _, key := range keys {
+			if token.Get(key) == "" {
+				return fmt.Errorf("%w: token has no %s claim", paseto.ErrTokenValidationError, key)
+			}
+		}
+
+		return nil
+	}
+}
+
+// Scopes gets scopes from "scopes" claim, json array or space separated, or "scope" claim as in OAuth2
+func Scopes(token paseto.JSONToken) []string {
+	value := token.Get("scopes")
+	if value == "" {
+		value = token.Get("scope")
+	}
+
+	var scopes []string
+	if strings.HasPrefix(value, "[") && json.Unmarshal([]byte(value), &scopes) == nil {
+		return scopes
+	}
+
+	return strings.Fields(strings.Replace(value, ",", " ", -1))
+}
+
+// ToUserClaim maps paseto token into jwt.UserClaim, so jwt and paseto user is treated the same way.
+// UserID is "user_id" claim, or numeric sub claim. SecondaryID and ClientID is "secondary_id" and "client_id" claim.
+func ToUserClaim(token paseto.JSONToken) *jwt.UserClaim {
+	claim := &jwt.UserClaim{
+		SecondaryID: token.Get("secondary_id"),
+		ClientID:    token.Get("client_id"),
+		Scopes:      Scopes(token),
+	}
+
+	userID, err := strconv.ParseInt(token.Get("user_id"), 10, 64)
+	if err != nil {
+		userID, _ = strconv.ParseInt(token.Subject, 10, 64)
+	}
+
+	claim.UserID = userID
+	claim.Audience = token.Audience
+	claim.Id = token.Jti
+	claim.Issuer = token.Issuer
+	claim.Subject = token.Subject
+
+	if !token.Expiration.IsZero() {
+		claim.ExpiresAt = token.Expiration.Unix()
+	}
+
+	if !token.IssuedAt.IsZero() {
+		claim.IssuedAt = token.IssuedAt.Unix()
+	}
+
+	if !token.NotBefore.IsZero() {
+		claim.NotBefore = token.NotBefore.Unix()
+	}
+
+	return claim
+}
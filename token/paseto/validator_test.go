@@ -0,0 +1,76 @@
+package paseto
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/o1egl/paseto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	token := newTestToken()
+	token.Audience = "Kitabisa services"
+
+	assert.NoError(t, Validate(token, ValidationOption{}))
+	assert.NoError(t, Validate(token, ValidationOption{
+		Issuers:        []string{"Kulonuwun", "Kulonuwun staging"},
+		Audience:       "Kitabisa services",
+		Subject:        "cac2ee7e-70d0-4220-badd-7b5695f53ad8",
+		RequiredClaims: []string{"email"},
+	}))
+
+	for name, option := range map[string]ValidationOption{
+		"issuer":   {Issuers: []string{"Evil"}},
+		"audience": {Audience: "Payment services"},
+		"subject":  {Subject: "other"},
+		"claims":   {RequiredClaims: []string{"email", "user_id"}},
+		"custom": {Validators: []paseto.Validator{func(token *paseto.JSONToken) error {
+			return errors.New("custom")
+		}}},
+	} {
+		assert.Error(t, Validate(token, option), name)
+	}
+}
+
+func TestValidateLeeway(t *testing.T) {
+	now := time.Now()
+
+	expired := newTestToken()
+	expired.Expiration = now.Add(-10 * time.Second)
+	err := Validate(expired, ValidationOption{})
+	assert.True(t, errors.Is(err, paseto.ErrTokenValidationError))
+	assert.NoError(t, Validate(expired, ValidationOption{Leeway: 30 * time.Second}))
+
+	notYet := newTestToken()
+	notYet.IssuedAt = now.Add(10 * time.Second)
+	notYet.NotBefore = now.Add(10 * time.Second)
+	assert.Error(t, Validate(notYet, ValidationOption{}))
+	assert.NoError(t, Validate(notYet, ValidationOption{Leeway: 30 * time.Second}))
+}
+
+func TestToUserClaim(t *testing.T) {
+	token := newTestToken()
+	token.Audience = "Kitabisa services"
+	token.Set("user_id", "12345")
+	token.Set("client_id", "kitabisa-web")
+	token.Set("scopes", `["campaign:read","donation:write"]`)
+
+	claim := ToUserClaim(token)
+	assert.Equal(t, int64(12345), claim.UserID)
+	assert.Equal(t, "kitabisa-web", claim.ClientID)
+	assert.Equal(t, []string{"campaign:read", "donation:write"}, claim.Scopes)
+	assert.Equal(t, "706cbfce-c031-4a44-815e-030f963f7d4e", claim.Id)
+	assert.Equal(t, "Kulonuwun", claim.Issuer)
+	assert.Equal(t, "Kitabisa services", claim.Audience)
+	assert.Equal(t, token.Expiration.Unix(), claim.ExpiresAt)
+
+	// numeric subject
+	token = paseto.JSONToken{Subject: "678"}
+	token.Set("scope", "campaign:read donation:write")
+	claim = ToUserClaim(token)
+	assert.Equal(t, int64(678), claim.UserID)
+	assert.Equal(t, []string{"campaign:read", "donation:write"}, claim.Scopes)
+	assert.Zero(t, claim.ExpiresAt)
+}
@@ -0,0 +1,108 @@
+package refresh
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	libjwt "github.com/golang-jwt/jwt"
+	libpaseto "github.com/o1egl/paseto"
+)
+
+// AccessTokenIssuer issues access token of the session, implemented by JWTIssuer and PasetoIssuer.
+// The id must be used as jti of the access token, so it can be revoked on refresh token reuse.
+type AccessTokenIssuer interface {
+	IssueAccessToken(session Session, id string, expiresAt time.Time) (string, error)
+}
+
+// JWTCreator creates jwt token, implemented by jwt.JWT, jwt.JWTRSA and jwt.JWTSigner
+type JWTCreator interface {
+	Create(claims libjwt.Claims) (string, error)
+}
+
+// JWTIssuer issues jwt access token
+type JWTIssuer struct {
+	Creator JWTCreator
+	// Claims creates access token claims. Default is jwt.MapClaims of session claims with sub, jti, iat and exp,
+	// and user_id when the subject is numeric, so it can be parsed into jwt.UserClaim. The id is the jti.
+	Claims func(session Session, id string, expiresAt time.Time) libjwt.Claims
+}
+
+func (i JWTIssuer) IssueAccessToken(session Session, id string, expiresAt time.Time) (string, error) {
+	claimsFunc := i.Claims
+	if claimsFunc == nil {
+		claimsFunc = defaultJWTClaims
+	}
+
+	return i.Creator.Create(claimsFunc(session, id, expiresAt))
+}
+
+func defaultJWTClaims(session Session, id string, expiresAt time.Time) libjwt.Claims {
+	claims := libjwt.MapClaims{}
+	for k, v := range session.Claims {
+		claims[k] = v
+	}
+
+	if userID, err := strconv.ParseInt(session.Subject, 10, 64); err == nil {
+		claims["user_id"] = userID
+	}
+
+	claims["sub"] = session.Subject
+	claims["jti"] = id
+	claims["iat"] = time.Now().Unix()
+	claims["exp"] = expiresAt.Unix()
+
+	return claims
+}
+
+// PasetoEncrypter creates paseto token, implemented by paseto.PasetoSymmetric, paseto.PasetoAsymmetric,
+// paseto.PasetoV4Local, paseto.PasetoV4Public and paseto.Keyring
+type PasetoEncrypter interface {
+	Encrypt(token libpaseto.JSONToken, footer string) (string, error)
+}
+
+// PasetoIssuer issues paseto access token
+type PasetoIssuer struct {
+	Encrypter PasetoEncrypter
+	Footer    string
+	// Token creates access token. Default is token of session claims with sub, jti, iat, nbf and exp.
+	// Non string claim is json encoded. The id is the jti.
+	Token func(session Session, id string, expiresAt time.Time) libpaseto.JSONToken
+}
+
+func (i PasetoIssuer) IssueAccessToken(session Session, id string, expiresAt time.Time) (string, error) {
+	tokenFunc := i.Token
+	if tokenFunc == nil {
+		tokenFunc = defaultPasetoToken
+	}
+
+	return i.Encrypter.Encrypt(tokenFunc(session, id, expiresAt), i.Footer)
+}
+
+func defaultPasetoToken(session Session, id string, expiresAt time.Time) libpaseto.JSONToken {
+	now := time.Now()
+	token := libpaseto.JSONToken{
+		Jti:        id,
+		Subject:    session.Subject,
+		IssuedAt:   now,
+		NotBefore:  now,
+		Expiration: expiresAt,
+	}
+
+	for k, v := range session.Claims {
+		if str, ok := v.(string); ok {
+			token.Set(k, str)
+			continue
+		}
+
+		// paseto claim is string, e.g. scopes is stored as json array
+		b, err := json.Marshal(v)
+		if err != nil {
+			continue
+		}
+
+		token.Set(k, string(b))
+	}
+
+	return token
+}
@@ -0,0 +1,248 @@
+// Package refresh issues access and refresh token pair, and rotates the refresh token on every use
+
+package refresh
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kitabisa/perkakas/v2/token/revocation"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	// ErrInvalidRefreshToken is returned when refresh token is unknown or its family is revoked
+	ErrInvalidRefreshToken = errors.New("refresh: invalid refresh token")
+	// ErrRefreshTokenExpired is returned when refresh token has expired
+	ErrRefreshTokenExpired = errors.New("refresh: refresh token is expired")
+	// ErrRefreshTokenReused is returned when already rotated refresh token is used again. The whole family is revoked,
+	// since either the legitimate client or an attacker holds a stolen token.
+	ErrRefreshTokenReused = errors.New("refresh: refresh token is reused")
+)
+
+// Session is data of the login session, copied into every access token of the family
+type Session struct {
+	Subject string                 `json:"subject"`
+	Claims  map[string]interface{} `json:"claims,omitempty"`
+}
+
+// TokenPair is issued access and refresh token, in OAuth2 token response format
+type TokenPair struct {
+	AccessToken           string    `json:"access_token"`
+	TokenType             string    `json:"token_type"`
+	ExpiresIn             int64     `json:"expires_in"`
+	RefreshToken          string    `json:"refresh_token"`
+	AccessTokenExpiresAt  time.Time `json:"-"`
+	RefreshTokenExpiresAt time.Time `json:"-"`
+}
+
+// Token is stored refresh token. Only hash of the refresh token is stored.
+type Token struct {
+	Hash      string    `json:"hash"`
+	FamilyID  string    `json:"family_id"`
+	Session   Session   `json:"session"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Option is refresh token manager option
+type Option struct {
+	// Store stores refresh token, e.g. NewRedisStore or NewMemoryStore
+	Store Store
+	// Issuer issues access token, e.g. JWTIssuer or PasetoIssuer
+	Issuer AccessTokenIssuer
+	// AccessTokenTTL is access token lifetime. Default 15 minutes.
+	AccessTokenTTL time.Duration
+	// RefreshTokenTTL is refresh token lifetime, renewed on every rotation. Default 30 days.
+	RefreshTokenTTL time.Duration
+	// RevocationList is optional. On refresh token reuse, unexpired access tokens of the family are revoked by jti too.
+	RevocationList revocation.List
+	// RevokeSubjectOnReuse revokes all access tokens of the subject issued before the reuse instead, logging out every
+	// session of the subject, not only the family. It requires RevocationList.
+	RevokeSubjectOnReuse bool
+}
+
+// Manager issues and rotates refresh token
+type Manager struct {
+	option Option
+}
+
+// NewManager creates refresh token manager
+func NewManager(option Option) *Manager {
+	if option.AccessTokenTTL <= 0 {
+		option.AccessTokenTTL = 15 * time.Minute
+	}
+
+	if option.RefreshTokenTTL <= 0 {
+		option.RefreshTokenTTL = 30 * 24 * time.Hour
+	}
+
+	return &Manager{
+		option: option,
+	}
+}
+
+// Issue issues token pair of new session, e.g. after login
+func (m *Manager) Issue(session Session) (*TokenPair, error) {
+	return m.issue(uuid.New().String(), session)
+}
+
+// Refresh rotates the refresh token, and issues new token pair of its session. The refresh token can only be used once,
+// reusing it revokes the whole family and returns ErrRefreshTokenReused.
+func (m *Manager) Refresh(refreshToken string) (*TokenPair, error) {
+	token, err := m.lookup(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	first, err := m.option.Store.MarkUsed(token.Hash, time.Until(token.ExpiresAt))
+	if err != nil {
+		return nil, err
+	}
+
+	if !first {
+		log.Warn().Str("family_id", token.FamilyID).Str("subject", token.Session.Subject).Msg("refresh token reused, revoking token family")
+		if err = m.revoke(token); err != nil {
+			return nil, err
+		}
+
+		return nil, ErrRefreshTokenReused
+	}
+
+	return m.issue(token.FamilyID, token.Session)
+}
+
+// Revoke revokes the whole family of the refresh token, e.g. on logout
+func (m *Manager) Revoke(refreshToken string) error {
+	token, err := m.lookup(refreshToken)
+	if err != nil {
+		return err
+	}
+
+	return m.option.Store.RevokeFamily(token.FamilyID, m.option.RefreshTokenTTL)
+}
+
+// lookup gets valid refresh token
+func (m *Manager) lookup(refreshToken string) (*Token, error) {
+	token, err := m.option.Store.Get(hashToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+
+	if !time.Now().Before(token.ExpiresAt) {
+		return nil, ErrRefreshTokenExpired
+	}
+
+	revoked, err := m.option.Store.IsFamilyRevoked(token.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+
+	if revoked {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return token, nil
+}
+
+func (m *Manager) revoke(token *Token) error {
+	if err := m.option.Store.RevokeFamily(token.FamilyID, m.option.RefreshTokenTTL); err != nil {
+		return err
+	}
+
+	if m.option.RevocationList == nil {
+		return nil
+	}
+
+	if m.option.RevokeSubjectOnReuse {
+		if token.Session.Subject == "" {
+			return nil
+		}
+
+		return m.option.RevocationList.RevokeSubject(token.Session.Subject, time.Now())
+	}
+
+	accessTokens, err := m.option.Store.AccessTokens(token.FamilyID)
+	if err != nil {
+		return err
+	}
+
+	for _, accessToken := range accessTokens {
+		if err = m.option.RevocationList.Revoke(accessToken); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *Manager) issue(familyID string, session Session) (*TokenPair, error) {
+	now := time.Now()
+	accessExpiresAt := now.Add(m.option.AccessTokenTTL)
+	refreshExpiresAt := now.Add(m.option.RefreshTokenTTL)
+
+	accessTokenID := uuid.New().String()
+	accessToken, err := m.option.Issuer.IssueAccessToken(session, accessTokenID, accessExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("refresh: failed to issue access token: %w", err)
+	}
+
+	// access token of the family is only needed to revoke it on reuse
+	if m.option.RevocationList != nil && !m.option.RevokeSubjectOnReuse {
+		err = m.option.Store.AddAccessToken(familyID, revocation.Token{
+			ID:        accessTokenID,
+			Subject:   session.Subject,
+			IssuedAt:  now,
+			ExpiresAt: accessExpiresAt,
+		}, m.option.RefreshTokenTTL)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	refreshToken, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.option.Store.Save(Token{
+		Hash:      hashToken(refreshToken),
+		FamilyID:  familyID,
+		Session:   session,
+		IssuedAt:  now,
+		ExpiresAt: refreshExpiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:           accessToken,
+		TokenType:             "Bearer",
+		ExpiresIn:             int64(m.option.AccessTokenTTL / time.Second),
+		RefreshToken:          refreshToken,
+		AccessTokenExpiresAt:  accessExpiresAt,
+		RefreshTokenExpiresAt: refreshExpiresAt,
+	}, nil
+}
+
+// newRefreshToken creates opaque random refresh token
+func newRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("refresh: failed to generate refresh token: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(refreshToken string) string {
+	sum := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(sum[:])
+}
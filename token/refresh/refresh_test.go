@@ -0,0 +1,206 @@
+package refresh
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kitabisa/perkakas/v2/token/jwt"
+	"github.com/kitabisa/perkakas/v2/token/paseto"
+	"github.com/kitabisa/perkakas/v2/token/revocation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestManager(store Store) *Manager {
+	return NewManager(Option{
+		Store:  store,
+		Issuer: JWTIssuer{Creator: jwt.NewJWT([]byte("abcde"))},
+	})
+}
+
+func TestIssueAndRefresh(t *testing.T) {
+	m := newTestManager(NewMemoryStore())
+
+	pair, err := m.Issue(Session{Subject: "12345", Claims: map[string]interface{}{"scopes": []string{"campaign:read"}}})
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer", pair.TokenType)
+	assert.Equal(t, int64(900), pair.ExpiresIn)
+
+	parsed, err := jwt.NewJWT([]byte("abcde")).Parse(pair.AccessToken)
+	require.NoError(t, err)
+	claims := parsed.Claims.(*jwt.UserClaim)
+	assert.Equal(t, int64(12345), claims.UserID)
+	assert.Equal(t, "12345", claims.Subject)
+	assert.Equal(t, []string{"campaign:read"}, claims.Scopes)
+	assert.NotEmpty(t, claims.Id)
+
+	rotated, err := m.Refresh(pair.RefreshToken)
+	require.NoError(t, err)
+	assert.NotEqual(t, pair.RefreshToken, rotated.RefreshToken)
+	assert.NotEqual(t, pair.AccessToken, rotated.AccessToken)
+
+	_, err = m.Refresh("unknown")
+	assert.Equal(t, ErrInvalidRefreshToken, err)
+}
+
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	list := &recordingRevocationList{}
+	m := NewManager(Option{
+		Store:          NewMemoryStore(),
+		Issuer:         JWTIssuer{Creator: jwt.NewJWT([]byte("abcde"))},
+		RevocationList: list,
+	})
+
+	pair, err := m.Issue(Session{Subject: "12345"})
+	require.NoError(t, err)
+
+	rotated, err := m.Refresh(pair.RefreshToken)
+	require.NoError(t, err)
+
+	other, err := m.Issue(Session{Subject: "12345"})
+	require.NoError(t, err)
+
+	// stolen old token is used again
+	_, err = m.Refresh(pair.RefreshToken)
+	assert.Equal(t, ErrRefreshTokenReused, err)
+
+	// only access tokens of the family are revoked, not other session of the subject
+	assert.Empty(t, list.subject)
+	assert.ElementsMatch(t, []string{accessTokenID(t, pair), accessTokenID(t, rotated)}, list.ids)
+	assert.NotContains(t, list.ids, accessTokenID(t, other))
+
+	// the latest token of the family is revoked too
+	_, err = m.Refresh(rotated.RefreshToken)
+	assert.Equal(t, ErrInvalidRefreshToken, err)
+
+	// other family is not affected
+	_, err = m.Refresh(other.RefreshToken)
+	assert.NoError(t, err)
+}
+
+func TestRefreshReuseRevokesSubject(t *testing.T) {
+	list := &recordingRevocationList{}
+	m := NewManager(Option{
+		Store:                NewMemoryStore(),
+		Issuer:               JWTIssuer{Creator: jwt.NewJWT([]byte("abcde"))},
+		RevocationList:       list,
+		RevokeSubjectOnReuse: true,
+	})
+
+	pair, err := m.Issue(Session{Subject: "12345"})
+	require.NoError(t, err)
+
+	_, err = m.Refresh(pair.RefreshToken)
+	require.NoError(t, err)
+
+	_, err = m.Refresh(pair.RefreshToken)
+	assert.Equal(t, ErrRefreshTokenReused, err)
+	assert.Equal(t, "12345", list.subject)
+	assert.Empty(t, list.ids)
+}
+
+func TestRefreshConcurrentUse(t *testing.T) {
+	m := newTestManager(NewMemoryStore())
+
+	pair, err := m.Issue(Session{Subject: "12345"})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := m.Refresh(pair.RefreshToken)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		if err == nil {
+			succeeded++
+		}
+	}
+	assert.Equal(t, 1, succeeded)
+}
+
+func TestRevoke(t *testing.T) {
+	m := newTestManager(NewMemoryStore())
+
+	pair, err := m.Issue(Session{Subject: "12345"})
+	require.NoError(t, err)
+
+	require.NoError(t, m.Revoke(pair.RefreshToken))
+	_, err = m.Refresh(pair.RefreshToken)
+	assert.Equal(t, ErrInvalidRefreshToken, err)
+}
+
+func TestRefreshExpired(t *testing.T) {
+	store := NewMemoryStore()
+	m := NewManager(Option{
+		Store:           store,
+		Issuer:          JWTIssuer{Creator: jwt.NewJWT([]byte("abcde"))},
+		RefreshTokenTTL: 10 * time.Millisecond,
+	})
+
+	pair, err := m.Issue(Session{Subject: "12345"})
+	require.NoError(t, err)
+
+	time.Sleep(20 * time.Millisecond)
+	_, err = m.Refresh(pair.RefreshToken)
+	assert.Error(t, err)
+}
+
+func TestPasetoIssuer(t *testing.T) {
+	symmetric, err := paseto.NewSymmetric("PrU5AbXJawKJIUOJFmd4f6ZwmifLvvoF")
+	require.NoError(t, err)
+
+	m := NewManager(Option{
+		Store:  NewMemoryStore(),
+		Issuer: PasetoIssuer{Encrypter: symmetric, Footer: "Kitabisa.com"},
+	})
+
+	pair, err := m.Issue(Session{Subject: "cac2ee7e", Claims: map[string]interface{}{
+		"email":  "budi@kitabisa.com",
+		"scopes": []string{"campaign:read"},
+	}})
+	require.NoError(t, err)
+
+	pair, err = m.Refresh(pair.RefreshToken)
+	require.NoError(t, err)
+
+	token, footer, err := symmetric.Decrypt(pair.AccessToken)
+	require.NoError(t, err)
+	require.NoError(t, token.Validate())
+	assert.Equal(t, "Kitabisa.com", footer)
+	assert.Equal(t, "cac2ee7e", token.Subject)
+	assert.Equal(t, "budi@kitabisa.com", token.Get("email"))
+	assert.Equal(t, `["campaign:read"]`, token.Get("scopes"))
+}
+
+// recordingRevocationList records revoked jti and subject
+type recordingRevocationList struct {
+	revocation.List
+	ids     []string
+	subject string
+}
+
+func (l *recordingRevocationList) Revoke(token revocation.Token) error {
+	l.ids = append(l.ids, token.ID)
+	return nil
+}
+
+func (l *recordingRevocationList) RevokeSubject(subject string, before time.Time) error {
+	l.subject = subject
+	return nil
+}
+
+func accessTokenID(t *testing.T, pair *TokenPair) string {
+	parsed, err := jwt.NewJWT([]byte("abcde")).Parse(pair.AccessToken)
+	require.NoError(t, err)
+	return parsed.Claims.(*jwt.UserClaim).Id
+}
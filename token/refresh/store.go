@@ -0,0 +1,285 @@
+package refresh
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/kitabisa/perkakas/v2/token/revocation"
+)
+
+// Store stores refresh token and token family state
+type Store interface {
+	// Save saves refresh token until it expires
+	Save(token Token) error
+	// Get gets refresh token by hash. Unknown token returns ErrInvalidRefreshToken.
+	Get(hash string) (*Token, error)
+	// MarkUsed atomically marks refresh token as used. It returns false when the token is already used.
+	MarkUsed(hash string, ttl time.Duration) (first bool, err error)
+	// RevokeFamily revokes all refresh tokens of the family
+	RevokeFamily(familyID string, ttl time.Duration) error
+	// IsFamilyRevoked checks whether the family is revoked
+	IsFamilyRevoked(familyID string) (bool, error)
+	// AddAccessToken records access token issued to the family. The record expires after ttl since the last token.
+	AddAccessToken(familyID string, token revocation.Token, ttl time.Duration) error
+	// AccessTokens gets unexpired access tokens of the family. Only ID and ExpiresAt are guaranteed to be set.
+	AccessTokens(familyID string) ([]revocation.Token, error)
+}
+
+// MemoryStore is in-memory store, for single instance service and test
+type MemoryStore struct {
+	mu           sync.Mutex
+	tokens       map[string]Token
+	used         map[string]time.Time
+	families     map[string]time.Time
+	accessTokens map[string]familyAccessTokens
+}
+
+type familyAccessTokens struct {
+	tokens    []revocation.Token
+	expiresAt time.Time
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore creates in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		tokens:       make(map[string]Token),
+		used:         make(map[string]time.Time),
+		families:     make(map[string]time.Time),
+		accessTokens: make(map[string]familyAccessTokens),
+	}
+}
+
+func (s *MemoryStore) Save(token Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.evict()
+	s.tokens[token.Hash] = token
+	return nil
+}
+
+func (s *MemoryStore) Get(hash string) (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.tokens[hash]
+	if !ok || !time.Now().Before(token.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return &token, nil
+}
+
+func (s *MemoryStore) MarkUsed(hash string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if expiresAt, ok := s.used[hash]; ok && time.Now().Before(expiresAt) {
+		return false, nil
+	}
+
+	s.used[hash] = time.Now().Add(ttl)
+	return true, nil
+}
+
+func (s *MemoryStore) RevokeFamily(familyID string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.families[familyID] = time.Now().Add(ttl)
+	return nil
+}
+
+func (s *MemoryStore) IsFamilyRevoked(familyID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiresAt, ok := s.families[familyID]
+	return ok && time.Now().Before(expiresAt), nil
+}
+
+func (s *MemoryStore) AddAccessToken(familyID string, token revocation.Token, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	family := s.accessTokens[familyID]
+	if !now.Before(family.expiresAt) {
+		family.tokens = nil
+	}
+
+	tokens := family.tokens[:0]
+	for _, t := range family.tokens {
+		if now.Before(t.ExpiresAt) {
+			tokens = append(tokens, t)
+		}
+	}
+
+	s.accessTokens[familyID] = familyAccessTokens{
+		tokens:    append(tokens, token),
+		expiresAt: now.Add(ttl),
+	}
+	return nil
+}
+
+func (s *MemoryStore) AccessTokens(familyID string) ([]revocation.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	family, ok := s.accessTokens[familyID]
+	if !ok || !now.Before(family.expiresAt) {
+		return nil, nil
+	}
+
+	var tokens []revocation.Token
+	for _, t := range family.tokens {
+		if now.Before(t.ExpiresAt) {
+			tokens = append(tokens, t)
+		}
+	}
+
+	return tokens, nil
+}
+
+// evict deletes expired entries
+func (s *MemoryStore) evict() {
+	now := time.Now()
+	for hash, token := range s.tokens {
+		if !now.Before(token.ExpiresAt) {
+			delete(s.tokens, hash)
+		}
+	}
+
+	for hash, expiresAt := range s.used {
+		if !now.Before(expiresAt) {
+			delete(s.used, hash)
+		}
+	}
+
+	for id, expiresAt := range s.families {
+		if !now.Before(expiresAt) {
+			delete(s.families, id)
+		}
+	}
+
+	for id, family := range s.accessTokens {
+		if !now.Before(family.expiresAt) {
+			delete(s.accessTokens, id)
+		}
+	}
+}
+
+// RedisStore is redis store, so refresh token can be rotated by any instance.
+// Every key expires with the token, so the store doesn't grow forever.
+type RedisStore struct {
+	client redis.Cmdable
+	prefix string
+}
+
+var _ Store = (*RedisStore)(nil)
+
+// NewRedisStore creates redis store. Prefix is prepended to every key, e.g. "auth-service:refresh:".
+func NewRedisStore(client redis.Cmdable, prefix string) *RedisStore {
+	return &RedisStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+func (s *RedisStore) Save(token Token) error {
+	b, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+
+	return s.client.Set(s.prefix+"token:"+token.Hash, b, time.Until(token.ExpiresAt)).Err()
+}
+
+func (s *RedisStore) Get(hash string) (*Token, error) {
+	b, err := s.client.Get(s.prefix + "token:" + hash).Bytes()
+	if err == redis.Nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var token Token
+	if err = json.Unmarshal(b, &token); err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// MarkUsed uses SETNX, so only one of concurrent refresh using the same token wins
+func (s *RedisStore) MarkUsed(hash string, ttl time.Duration) (bool, error) {
+	return s.client.SetNX(s.prefix+"used:"+hash, "1", ttl).Result()
+}
+
+func (s *RedisStore) RevokeFamily(familyID string, ttl time.Duration) error {
+	return s.client.Set(s.prefix+"family:"+familyID, "1", ttl).Err()
+}
+
+func (s *RedisStore) IsFamilyRevoked(familyID string) (bool, error) {
+	err := s.client.Get(s.prefix + "family:" + familyID).Err()
+	if err == redis.Nil {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// AddAccessToken stores jti of the family in sorted set scored by its expiry, so expired jti is trimmed on every add
+func (s *RedisStore) AddAccessToken(familyID string, token revocation.Token, ttl time.Duration) error {
+	key := s.prefix + "access:" + familyID
+	now := time.Now()
+
+	err := s.client.ZRemRangeByScore(key, "-inf", strconv.FormatInt(now.UnixNano()/int64(time.Millisecond), 10)).Err()
+	if err != nil {
+		return err
+	}
+
+	err = s.client.ZAdd(key, redis.Z{
+		Score:  float64(token.ExpiresAt.UnixNano() / int64(time.Millisecond)),
+		Member: token.ID,
+	}).Err()
+	if err != nil {
+		return err
+	}
+
+	return s.client.Expire(key, ttl).Err()
+}
+
+func (s *RedisStore) AccessTokens(familyID string) ([]revocation.Token, error) {
+	now := time.Now()
+	members, err := s.client.ZRangeByScoreWithScores(s.prefix+"access:"+familyID, redis.ZRangeBy{
+		Min: "(" + strconv.FormatInt(now.UnixNano()/int64(time.Millisecond), 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make([]revocation.Token, 0, len(members))
+	for _, member := range members {
+		id, _ := member.Member.(string)
+		tokens = append(tokens, revocation.Token{
+			ID:        id,
+			ExpiresAt: time.Unix(0, int64(member.Score)*int64(time.Millisecond)),
+		})
+	}
+
+	return tokens, nil
+}
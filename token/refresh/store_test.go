@@ -0,0 +1,93 @@
+package refresh
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kitabisa/perkakas/v2/internal/redistest"
+	"github.com/kitabisa/perkakas/v2/token/revocation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisStore(t *testing.T) {
+	client := redistest.New()
+	store := NewRedisStore(client, "auth:refresh:")
+
+	token := Token{
+		Hash:      "hash-1",
+		FamilyID:  "family-1",
+		Session:   Session{Subject: "12345", Claims: map[string]interface{}{"client_id": "web"}},
+		IssuedAt:  time.Now(),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	require.NoError(t, store.Save(token))
+	assert.InDelta(t, time.Hour.Seconds(), client.Expiration("auth:refresh:token:hash-1").Seconds(), 2)
+
+	saved, err := store.Get("hash-1")
+	require.NoError(t, err)
+	assert.Equal(t, "family-1", saved.FamilyID)
+	assert.Equal(t, "web", saved.Session.Claims["client_id"])
+
+	_, err = store.Get("hash-2")
+	assert.Equal(t, ErrInvalidRefreshToken, err)
+
+	first, err := store.MarkUsed("hash-1", time.Hour)
+	require.NoError(t, err)
+	assert.True(t, first)
+
+	first, err = store.MarkUsed("hash-1", time.Hour)
+	require.NoError(t, err)
+	assert.False(t, first)
+
+	revoked, err := store.IsFamilyRevoked("family-1")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	require.NoError(t, store.RevokeFamily("family-1", time.Hour))
+	revoked, err = store.IsFamilyRevoked("family-1")
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	expiresAt := time.Now().Add(15 * time.Minute)
+	require.NoError(t, store.AddAccessToken("family-1", revocation.Token{ID: "jti-1", ExpiresAt: expiresAt}, time.Hour))
+	require.NoError(t, store.AddAccessToken("family-1", revocation.Token{ID: "jti-2", ExpiresAt: time.Now().Add(-time.Second)}, time.Hour))
+	assert.Equal(t, time.Hour, client.Expiration("auth:refresh:access:family-1"))
+
+	tokens, err := store.AccessTokens("family-1")
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	assert.Equal(t, "jti-1", tokens[0].ID)
+	assert.WithinDuration(t, expiresAt, tokens[0].ExpiresAt, time.Millisecond)
+
+	tokens, err = store.AccessTokens("family-2")
+	require.NoError(t, err)
+	assert.Empty(t, tokens)
+}
+
+func TestMemoryStoreExpiry(t *testing.T) {
+	store := NewMemoryStore()
+	require.NoError(t, store.Save(Token{Hash: "hash-1", ExpiresAt: time.Now().Add(10 * time.Millisecond)}))
+
+	_, err := store.Get("hash-1")
+	require.NoError(t, err)
+
+	time.Sleep(20 * time.Millisecond)
+	_, err = store.Get("hash-1")
+	assert.Equal(t, ErrInvalidRefreshToken, err)
+
+	require.NoError(t, store.Save(Token{Hash: "hash-2", ExpiresAt: time.Now().Add(time.Hour)}))
+	assert.Len(t, store.tokens, 1)
+
+	require.NoError(t, store.AddAccessToken("family-1", revocation.Token{ID: "jti-1", ExpiresAt: time.Now().Add(10 * time.Millisecond)}, time.Hour))
+	require.NoError(t, store.AddAccessToken("family-1", revocation.Token{ID: "jti-2", ExpiresAt: time.Now().Add(time.Hour)}, time.Hour))
+	tokens, err := store.AccessTokens("family-1")
+	require.NoError(t, err)
+	assert.Len(t, tokens, 2)
+
+	time.Sleep(20 * time.Millisecond)
+	tokens, err = store.AccessTokens("family-1")
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	assert.Equal(t, "jti-2", tokens[0].ID)
+}
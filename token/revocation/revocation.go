@@ -0,0 +1,278 @@
+// Package revocation provides jti based token revocation list, shared by jwt and paseto token
+
+package revocation
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+var (
+	// ErrMissingTokenID is returned when revoking token without jti
+	ErrMissingTokenID = errors.New("revocation: token has no jti")
+	// ErrMissingSubject is returned when revoking tokens of empty subject
+	ErrMissingSubject = errors.New("revocation: empty subject")
+)
+
+// Token identifies token checked against revocation list
+type Token struct {
+	ID        string    // jti claim
+	Subject   string    // sub claim or user id
+	IssuedAt  time.Time // iat claim
+	ExpiresAt time.Time // exp claim
+}
+
+// List is token revocation list
+type List interface {
+	// Revoke revokes single token until it expires
+	Revoke(token Token) error
+	// RevokeSubject revokes all tokens of the subject issued before the time
+	RevokeSubject(subject string, before time.Time) error
+	// IsRevoked checks whether the token is revoked
+	IsRevoked(token Token) (bool, error)
+}
+
+// RedisListOption is redis revocation list option
+type RedisListOption struct {
+	// Prefix is prepended to every key. Default "revocation:".
+	Prefix string
+	// MaxTokenLifetime is the longest token lifetime, used as ttl of subject revocation and of token without exp.
+	// Default 24 hours.
+	MaxTokenLifetime time.Duration
+	// CacheTTL is how long IsRevoked result is cached locally. Revoked token is cached until it expires,
+	// so CacheTTL is the longest delay before revocation by other instance takes effect. Default 10 seconds,
+	// negative disables the cache.
+	CacheTTL time.Duration
+	// CacheSize is maximum entries of local cache. Default 10000.
+	CacheSize int
+}
+
+// RedisList is revocation list stored in redis, so revocation is shared between instances.
+// Entry ttl is the remaining token lifetime, so the list doesn't grow forever.
+type RedisList struct {
+	client redis.Cmdable
+	option RedisListOption
+	cache  *localCache
+}
+
+var _ List = (*RedisList)(nil)
+
+// NewRedisList creates redis revocation list
+func NewRedisList(client redis.Cmdable, option RedisListOption) *RedisList {
+	if option.Prefix == "" {
+		option.Prefix = "revocation:"
+	}
+
+	if option.MaxTokenLifetime <= 0 {
+		option.MaxTokenLifetime = 24 * time.Hour
+	}
+
+	if option.CacheTTL == 0 {
+		option.CacheTTL = 10 * time.Second
+	}
+
+	if option.CacheSize <= 0 {
+		option.CacheSize = 10000
+	}
+
+	return &RedisList{
+		client: client,
+		option: option,
+		cache:  newLocalCache(option.CacheSize),
+	}
+}
+
+func (l *RedisList) tokenKey(id string) string {
+	return l.option.Prefix + "jti:" + id
+}
+
+func (l *RedisList) subjectKey(subject string) string {
+	return l.option.Prefix + "sub:" + subject
+}
+
+func (l *RedisList) Revoke(token Token) error {
+	if token.ID == "" {
+		return ErrMissingTokenID
+	}
+
+	now := time.Now()
+	expiresAt := token.ExpiresAt
+	if expiresAt.IsZero() {
+		expiresAt = now.Add(l.option.MaxTokenLifetime)
+	}
+
+	ttl := expiresAt.Sub(now)
+	if ttl <= 0 {
+		return nil
+	}
+
+	if err := l.client.Set(l.tokenKey(token.ID), "1", ttl).Err(); err != nil {
+		return err
+	}
+
+	l.cache.set(l.tokenKey(token.ID), "1", expiresAt)
+	return nil
+}
+
+func (l *RedisList) RevokeSubject(subject string, before time.Time) error {
+	if subject == "" {
+		return ErrMissingSubject
+	}
+
+	now := time.Now()
+	expiresAt := before.Add(l.option.MaxTokenLifetime)
+	ttl := expiresAt.Sub(now)
+	if ttl <= 0 {
+		return nil
+	}
+
+	// millisecond cutoff, so token issued earlier in the same second is revoked too
+	value := strconv.FormatInt(unixMilli(before), 10)
+	if err := l.client.Set(l.subjectKey(subject), value, ttl).Err(); err != nil {
+		return err
+	}
+
+	l.cache.set(l.subjectKey(subject), value, now.Add(l.option.CacheTTL))
+	return nil
+}
+
+// IsRevoked checks the token jti and subject in single redis round trip, unless both are cached locally
+func (l *RedisList) IsRevoked(token Token) (bool, error) {
+	var keys []string
+	if token.ID != "" {
+		if value, ok := l.cache.get(l.tokenKey(token.ID)); ok && value != "" {
+			return true, nil
+		}
+
+		keys = append(keys, l.tokenKey(token.ID))
+	}
+
+	if token.Subject != "" {
+		keys = append(keys, l.subjectKey(token.Subject))
+	}
+
+	values, err := l.get(keys)
+	if err != nil {
+		return false, err
+	}
+
+	if token.ID != "" && values[l.tokenKey(token.ID)] != "" {
+		l.cache.set(l.tokenKey(token.ID), "1", token.ExpiresAt)
+		return true, nil
+	}
+
+	if token.Subject == "" {
+		return false, nil
+	}
+
+	before := values[l.subjectKey(token.Subject)]
+	if before == "" {
+		return false, nil
+	}
+
+	cutoff, err := strconv.ParseInt(before, 10, 64)
+	if err != nil {
+		return false, err
+	}
+
+	// token without iat can't prove it is issued after the revocation. iat of jwt has second resolution,
+	// so token issued later in the same second as the revocation is revoked too.
+	return token.IssuedAt.IsZero() || unixMilli(token.IssuedAt) < cutoff, nil
+}
+
+func unixMilli(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// get gets values of the keys from local cache, falling back to redis. Missing key has empty value.
+func (l *RedisList) get(keys []string) (map[string]string, error) {
+	values := make(map[string]string, len(keys))
+
+	var missed []string
+	for _, key := range keys {
+		value, ok := l.cache.get(key)
+		if !ok {
+			missed = append(missed, key)
+			continue
+		}
+
+		values[key] = value
+	}
+
+	if len(missed) == 0 {
+		return values, nil
+	}
+
+	result, err := l.client.MGet(missed...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(l.option.CacheTTL)
+	for i, key := range missed {
+		value, _ := result[i].(string)
+		values[key] = value
+		l.cache.set(key, value, expiresAt)
+	}
+
+	return values, nil
+}
+
+type cacheEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+// localCache is bounded in-memory cache. When full, expired entries are evicted, then the whole cache if still full.
+type localCache struct {
+	mu      sync.RWMutex
+	entries map[string]cacheEntry
+	size    int
+}
+
+func newLocalCache(size int) *localCache {
+	return &localCache{
+		entries: make(map[string]cacheEntry),
+		size:    size,
+	}
+}
+
+func (c *localCache) get(key string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[key]
+	if !ok || !time.Now().Before(entry.expiresAt) {
+		return "", false
+	}
+
+	return entry.value, true
+}
+
+func (c *localCache) set(key, value string, expiresAt time.Time) {
+	if !time.Now().Before(expiresAt) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.size {
+		now := time.Now()
+		for k, e := range c.entries {
+			if !now.Before(e.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+
+		if len(c.entries) >= c.size {
+			c.entries = make(map[string]cacheEntry)
+		}
+	}
+
+	c.entries[key] = cacheEntry{value: value, expiresAt: expiresAt}
+}
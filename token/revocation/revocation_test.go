@@ -0,0 +1,107 @@
+package revocation
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kitabisa/perkakas/v2/internal/redistest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevoke(t *testing.T) {
+	client := redistest.New()
+	list := NewRedisList(client, RedisListOption{})
+
+	token := Token{ID: "jti-1", Subject: "user-1", IssuedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}
+	other := Token{ID: "jti-2", Subject: "user-1", IssuedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}
+
+	require.NoError(t, list.Revoke(token))
+	value, _ := client.Value("revocation:jti:jti-1")
+	assert.Equal(t, "1", value)
+	assert.InDelta(t, time.Hour.Seconds(), client.Expiration("revocation:jti:jti-1").Seconds(), 2)
+
+	revoked, err := list.IsRevoked(token)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	revoked, err = list.IsRevoked(other)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	// expired token doesn't need entry
+	require.NoError(t, list.Revoke(Token{ID: "jti-3", ExpiresAt: time.Now().Add(-time.Minute)}))
+	_, ok := client.Value("revocation:jti:jti-3")
+	assert.False(t, ok)
+
+	assert.Equal(t, ErrMissingTokenID, list.Revoke(Token{Subject: "user-1"}))
+}
+
+func TestRevokeSubject(t *testing.T) {
+	client := redistest.New()
+	list := NewRedisList(client, RedisListOption{Prefix: "auth:", MaxTokenLifetime: time.Hour})
+
+	// middle of the second, so jwt iat of the same second is before the revocation
+	now := time.Now().Truncate(time.Second).Add(500 * time.Millisecond)
+	old := Token{ID: "jti-1", Subject: "user-1", IssuedAt: now.Add(-time.Minute), ExpiresAt: now.Add(time.Hour)}
+	fresh := Token{ID: "jti-2", Subject: "user-1", IssuedAt: now.Add(time.Minute), ExpiresAt: now.Add(time.Hour)}
+	noIat := Token{ID: "jti-3", Subject: "user-1", ExpiresAt: now.Add(time.Hour)}
+	otherUser := Token{ID: "jti-4", Subject: "user-2", IssuedAt: now.Add(-time.Minute), ExpiresAt: now.Add(time.Hour)}
+	sameSecond := Token{ID: "jti-5", Subject: "user-1", IssuedAt: now.Add(-time.Millisecond), ExpiresAt: now.Add(time.Hour)}
+	jwtSameSecond := Token{ID: "jti-6", Subject: "user-1", IssuedAt: time.Unix(now.Unix(), 0), ExpiresAt: now.Add(time.Hour)}
+	justAfter := Token{ID: "jti-7", Subject: "user-1", IssuedAt: now.Add(time.Millisecond), ExpiresAt: now.Add(time.Hour)}
+
+	require.NoError(t, list.RevokeSubject("user-1", now))
+	assert.InDelta(t, time.Hour.Seconds(), client.Expiration("auth:sub:user-1").Seconds(), 2)
+
+	for token, expected := range map[Token]bool{
+		old: true, fresh: false, noIat: true, otherUser: false, sameSecond: true, jwtSameSecond: true, justAfter: false,
+	} {
+		revoked, err := list.IsRevoked(token)
+		require.NoError(t, err)
+		assert.Equal(t, expected, revoked, token.ID)
+	}
+
+	assert.Equal(t, ErrMissingSubject, list.RevokeSubject("", now))
+}
+
+func TestIsRevokedLocalCache(t *testing.T) {
+	client := redistest.New()
+	list := NewRedisList(client, RedisListOption{CacheTTL: 50 * time.Millisecond})
+	token := Token{ID: "jti-1", Subject: "user-1", IssuedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}
+
+	for i := 0; i < 3; i++ {
+		revoked, err := list.IsRevoked(token)
+		require.NoError(t, err)
+		assert.False(t, revoked)
+	}
+	assert.Equal(t, 1, client.Calls("mget"))
+
+	// revoked by other instance, visible after cache ttl
+	other := NewRedisList(client, RedisListOption{})
+	require.NoError(t, other.Revoke(token))
+
+	revoked, _ := list.IsRevoked(token)
+	assert.False(t, revoked)
+
+	time.Sleep(60 * time.Millisecond)
+	revoked, _ = list.IsRevoked(token)
+	assert.True(t, revoked)
+
+	// revoked token is cached until it expires
+	mgets := client.Calls("mget")
+	time.Sleep(60 * time.Millisecond)
+	revoked, _ = list.IsRevoked(token)
+	assert.True(t, revoked)
+	assert.Equal(t, mgets, client.Calls("mget"))
+}
+
+func TestIsRevokedRedisError(t *testing.T) {
+	client := redistest.New()
+	client.SetError(errors.New("connection refused"))
+	list := NewRedisList(client, RedisListOption{CacheTTL: -1})
+
+	_, err := list.IsRevoked(Token{ID: "jti-1"})
+	assert.Error(t, err)
+}